@@ -3,22 +3,34 @@ package agent
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/paulhalleux/workflow-engine-go/agent/internal/connector"
 	"github.com/paulhalleux/workflow-engine-go/agent/internal/executor"
 	"github.com/paulhalleux/workflow-engine-go/agent/internal/grpcserver"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/httpserver"
 	"github.com/paulhalleux/workflow-engine-go/agent/internal/models"
 	"github.com/paulhalleux/workflow-engine-go/agent/internal/registry"
 	"github.com/paulhalleux/workflow-engine-go/agent/internal/ticker"
 	"github.com/paulhalleux/workflow-engine-go/proto"
 	"github.com/swaggest/jsonschema-go"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 type TaskDefinition = models.TaskDefinition
 type TaskExecutionRequest = models.TaskExecutionRequest
 type TaskExecutionResult = models.TaskExecutionResult
+type TaskHandler = models.TaskHandler
+type ContextTaskHandler = models.ContextTaskHandler
+type TaskInfo = models.TaskInfo
+type PauseSignal = models.PauseSignal
+type ProgressUpdate = models.ProgressUpdate
+type ProgressReporter = models.ProgressReporter
+
+// TaskInfoFromContext returns the TaskInfo carried by the context of a task
+// handler.
+func TaskInfoFromContext(ctx context.Context) (TaskInfo, bool) {
+	return models.TaskInfoFromContext(ctx)
+}
 
 type Agent struct {
 	cfg       *Config
@@ -29,7 +41,11 @@ type Agent struct {
 
 func NewAgent(ctx context.Context, cfg *Config) (*Agent, error) {
 	reg := registry.NewTaskDefinitionRegistry()
-	engineConnector, err := connector.NewEngineConnector(proto.AGENT_PROTOCOL_GRPC, cfg.EngineGrpcUrl)
+	engineUrl := cfg.EngineGrpcUrl
+	if cfg.AgentProtocol() == proto.AGENT_PROTOCOL_HTTP {
+		engineUrl = cfg.EngineHttpUrl
+	}
+	engineConnector, err := connector.NewEngineConnector(cfg.AgentProtocol(), engineUrl)
 
 	if err != nil {
 		return nil, err
@@ -43,32 +59,42 @@ func NewAgent(ctx context.Context, cfg *Config) (*Agent, error) {
 	}, nil
 }
 
+// agentServer serves the agent service with the protocol of the agent.
+type agentServer interface {
+	Start()
+	Stop() error
+}
+
 func (a *Agent) Start() error {
-	engineGrpcConnection, err := grpc.NewClient(a.cfg.EngineGrpcUrl, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		log.Fatalf("Failed to connect to engine gRPC server: %v", err)
+	address, port := a.cfg.GrpcAddress, a.cfg.GrpcPort
+	if a.cfg.AgentProtocol() == proto.AGENT_PROTOCOL_HTTP {
+		address, port = a.cfg.HttpAddress, a.cfg.HttpPort
 	}
 
 	tick := ticker.NewEngineTicker(30, &connector.AgentInfo{
 		Name:        a.cfg.Name,
 		Version:     a.cfg.Version,
-		Address:     a.cfg.GrpcAddress,
-		Port:        a.cfg.GrpcPort,
+		Address:     address,
+		Port:        port,
+		Weight:      int32(a.cfg.Weight),
 		Definitions: a.registry.ToProto(),
 	}, a.registry, a.connector)
 
 	taskExecutor := executor.NewTaskExecutor(&executor.TaskExecutorConfig{
-		MaxQueueSize:     100,
-		MaxParallelTasks: 5,
-	}, a.registry, engineGrpcConnection)
+		MaxQueueSize:     a.cfg.MaxQueueSize,
+		MaxParallelTasks: a.cfg.MaxParallelTasks,
+		ProgressInterval: time.Duration(a.cfg.ProgressIntervalMs) * time.Millisecond,
+	}, a.registry, a.connector)
 
-	grpcSrv := grpcserver.NewGrpcServer(
-		a.cfg.GrpcAddress,
-		a.cfg.GrpcPort,
-		grpcserver.NewAgentService(),
-	)
+	var srv agentServer
+	agentService := grpcserver.NewAgentService(taskExecutor)
+	if a.cfg.AgentProtocol() == proto.AGENT_PROTOCOL_HTTP {
+		srv = httpserver.NewHttpServer(address, port, agentService)
+	} else {
+		srv = grpcserver.NewGrpcServer(address, port, agentService)
+	}
 
-	go grpcSrv.Start()
+	go srv.Start()
 	go taskExecutor.Start(a.ctx)
 	go tick.Start()
 
@@ -76,8 +102,8 @@ func (a *Agent) Start() error {
 	log.Println("[agent] shutting down...")
 	tick.Stop()
 
-	if err := grpcSrv.Stop(); err != nil {
-		log.Printf("grpc shutdown error: %v", err)
+	if err := srv.Stop(); err != nil {
+		log.Printf("server shutdown error: %v", err)
 	}
 
 	if err := a.connector.Close(); err != nil {
@@ -3,27 +3,61 @@ package agent
 import (
 	"fmt"
 	"os"
+	"strconv"
+
+	"github.com/paulhalleux/workflow-engine-go/proto"
 )
 
 type Config struct {
 	Name          string
 	Version       string
 	EngineGrpcUrl string
+	EngineHttpUrl string
+
+	// Protocol is the protocol the agent serves and reaches the engine
+	// with, either grpc or http. It defaults to grpc.
+	Protocol string
 
 	MaxQueueSize     int
 	MaxParallelTasks int
+	// ProgressIntervalMs is the minimum delay between two progress updates
+	// sent for a task. It defaults to one second.
+	ProgressIntervalMs int
+	// Weight is the share of tasks the agent receives relative to other
+	// agents supporting the same tasks, when the engine balances by weight.
+	Weight int
 
 	GrpcAddress string
 	GrpcPort    string
+	HttpAddress string
+	HttpPort    string
 }
 
 func LoadConfigFromEnv() (*Config, error) {
 	cfg := &Config{
 		GrpcAddress:   getEnvDefault("GRPC_ADDRESS", ""),
 		GrpcPort:      getEnvDefault("GRPC_PORT", "50051"),
+		HttpAddress:   getEnvDefault("HTTP_ADDRESS", ""),
+		HttpPort:      getEnvDefault("HTTP_PORT", "50052"),
 		Name:          getEnvDefault("AGENT_NAME", "workflow-agent"),
 		Version:       getEnvDefault("AGENT_VERSION", "v1.0.0"),
 		EngineGrpcUrl: getEnvDefault("ENGINE_GRPC_URL", "localhost:60051"),
+		EngineHttpUrl: getEnvDefault("ENGINE_HTTP_URL", "http://localhost:8080"),
+		Protocol:      getEnvDefault("AGENT_PROTOCOL", "grpc"),
+	}
+
+	var err error
+	if cfg.MaxQueueSize, err = getEnvIntDefault("MAX_QUEUE_SIZE", 100); err != nil {
+		return nil, err
+	}
+	if cfg.MaxParallelTasks, err = getEnvIntDefault("MAX_PARALLEL_TASKS", 5); err != nil {
+		return nil, err
+	}
+	if cfg.ProgressIntervalMs, err = getEnvIntDefault("PROGRESS_INTERVAL_MS", 1000); err != nil {
+		return nil, err
+	}
+	if cfg.Weight, err = getEnvIntDefault("AGENT_WEIGHT", 1); err != nil {
+		return nil, err
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -35,23 +69,64 @@ func LoadConfigFromEnv() (*Config, error) {
 
 func (c *Config) validate() error {
 	missing := make([]string, 0)
-	if c.GrpcPort == "" {
-		missing = append(missing, "GRPC_PORT")
-	}
-	if c.EngineGrpcUrl == "" {
-		missing = append(missing, "ENGINE_GRPC_URL")
+	switch c.AgentProtocol() {
+	case proto.AGENT_PROTOCOL_GRPC:
+		if c.GrpcPort == "" {
+			missing = append(missing, "GRPC_PORT")
+		}
+		if c.EngineGrpcUrl == "" {
+			missing = append(missing, "ENGINE_GRPC_URL")
+		}
+	case proto.AGENT_PROTOCOL_HTTP:
+		if c.HttpPort == "" {
+			missing = append(missing, "HTTP_PORT")
+		}
+		if c.EngineHttpUrl == "" {
+			missing = append(missing, "ENGINE_HTTP_URL")
+		}
+	default:
+		return fmt.Errorf("AGENT_PROTOCOL must be grpc or http, got %s", c.Protocol)
 	}
 
 	if len(missing) > 0 {
 		return fmt.Errorf("missing required env vars: %v", missing)
 	}
 
+	if c.Weight <= 0 {
+		return fmt.Errorf("AGENT_WEIGHT must be positive")
+	}
+
 	return nil
 }
 
+// AgentProtocol returns the protocol the agent serves and reaches the engine
+// with, or AGENT_PROTOCOL_UNKNOWN if Protocol is neither grpc nor http.
+func (c *Config) AgentProtocol() proto.AgentProtocol {
+	switch c.Protocol {
+	case "grpc":
+		return proto.AGENT_PROTOCOL_GRPC
+	case "http":
+		return proto.AGENT_PROTOCOL_HTTP
+	default:
+		return proto.AGENT_PROTOCOL_UNKNOWN
+	}
+}
+
 func getEnvDefault(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return def
 }
+
+func getEnvIntDefault(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return i, nil
+}
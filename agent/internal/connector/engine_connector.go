@@ -1,6 +1,7 @@
 package connector
 
 import (
+	"context"
 	"errors"
 
 	"github.com/paulhalleux/workflow-engine-go/proto"
@@ -11,6 +12,7 @@ type AgentInfo struct {
 	Version     string
 	Address     string
 	Port        string
+	Weight      int32
 	Definitions []*proto.TaskDefinition
 }
 
@@ -18,13 +20,80 @@ type EngineConnector interface {
 	Close() error
 	Ping(name string) (bool, error)
 	RegisterAgent(agent *AgentInfo) (bool, error)
+	NotifyTaskStatus(req *proto.NotifyTaskStatusRequest) error
+	NotifyTaskProgress(req *proto.NotifyTaskProgressRequest) error
 }
 
 func NewEngineConnector(protocol proto.AgentProtocol, address string) (EngineConnector, error) {
 	switch protocol {
 	case proto.AGENT_PROTOCOL_GRPC:
 		return NewGrpcEngineConnector(address)
+	case proto.AGENT_PROTOCOL_HTTP:
+		return NewHttpEngineConnector(address), nil
 	default:
 		return nil, errors.New("unsupported protocol")
 	}
 }
+
+// engineClients implements the calls of an EngineConnector with the engine
+// service clients, whatever the transport they use. The agent registers with
+// the protocol it uses to reach the engine, since it serves the same one.
+type engineClients struct {
+	protocol     proto.AgentProtocol
+	engineClient proto.EngineServiceClient
+	taskClient   proto.TaskServiceClient
+}
+
+func (e *engineClients) Ping(name string) (bool, error) {
+	result, err := e.engineClient.Ping(context.Background(), &proto.EnginePingRequest{
+		Name: name,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	if result == nil {
+		return false, errors.New("nil response from engine ping")
+	}
+
+	return result.KnowAgent, nil
+}
+
+func (e *engineClients) RegisterAgent(
+	agent *AgentInfo,
+) (bool, error) {
+	res, err := e.engineClient.RegisterAgent(context.Background(), &proto.RegisterAgentRequest{
+		Name:           agent.Name,
+		Version:        agent.Version,
+		Address:        &agent.Address,
+		Port:           agent.Port,
+		Protocol:       e.protocol,
+		SupportedTasks: agent.Definitions,
+		Weight:         &agent.Weight,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	if res == nil {
+		return false, errors.New("nil response from engine register agent")
+	}
+
+	if res.Success == false {
+		return false, errors.New(*res.Message)
+	}
+
+	return true, nil
+}
+
+func (e *engineClients) NotifyTaskStatus(req *proto.NotifyTaskStatusRequest) error {
+	_, err := e.taskClient.NotifyTaskStatus(context.Background(), req)
+	return err
+}
+
+func (e *engineClients) NotifyTaskProgress(req *proto.NotifyTaskProgressRequest) error {
+	_, err := e.taskClient.NotifyTaskProgress(context.Background(), req)
+	return err
+}
@@ -1,7 +1,6 @@
 package connector
 
 import (
-	"context"
 	"errors"
 
 	"github.com/paulhalleux/workflow-engine-go/proto"
@@ -10,6 +9,7 @@ import (
 )
 
 type GrpcEngineConnector struct {
+	engineClients
 	connection *grpc.ClientConn
 }
 
@@ -20,6 +20,11 @@ func NewGrpcEngineConnector(address string) (*GrpcEngineConnector, error) {
 	}
 
 	return &GrpcEngineConnector{
+		engineClients: engineClients{
+			protocol:     proto.AGENT_PROTOCOL_GRPC,
+			engineClient: proto.NewEngineServiceClient(connection),
+			taskClient:   proto.NewTaskServiceClient(connection),
+		},
 		connection: connection,
 	}, nil
 }
@@ -30,48 +35,3 @@ func (g *GrpcEngineConnector) Close() error {
 	}
 	return nil
 }
-
-func (g *GrpcEngineConnector) Ping(name string) (bool, error) {
-	client := proto.NewEngineServiceClient(g.connection)
-	result, err := client.Ping(context.Background(), &proto.EnginePingRequest{
-		Name: name,
-	})
-
-	if err != nil {
-		return false, err
-	}
-
-	if result == nil {
-		return false, errors.New("nil response from engine ping")
-	}
-
-	return result.KnowAgent, nil
-}
-
-func (g *GrpcEngineConnector) RegisterAgent(
-	agent *AgentInfo,
-) (bool, error) {
-	client := proto.NewEngineServiceClient(g.connection)
-	res, err := client.RegisterAgent(context.Background(), &proto.RegisterAgentRequest{
-		Name:           agent.Name,
-		Version:        agent.Version,
-		Address:        &agent.Address,
-		Port:           agent.Port,
-		Protocol:       proto.AGENT_PROTOCOL_GRPC,
-		SupportedTasks: agent.Definitions,
-	})
-
-	if err != nil {
-		return false, err
-	}
-
-	if res == nil {
-		return false, errors.New("nil response from engine register agent")
-	}
-
-	if res.Success == false {
-		return false, errors.New(*res.Message)
-	}
-
-	return true, nil
-}
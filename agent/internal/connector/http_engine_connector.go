@@ -0,0 +1,26 @@
+package connector
+
+import (
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+// HttpEngineConnector reaches the engine with the HTTP transport, whose JSON
+// payloads mirror the proto messages.
+type HttpEngineConnector struct {
+	engineClients
+}
+
+func NewHttpEngineConnector(baseURL string) *HttpEngineConnector {
+	connection := proto.NewHttpClientConn(baseURL)
+	return &HttpEngineConnector{
+		engineClients: engineClients{
+			protocol:     proto.AGENT_PROTOCOL_HTTP,
+			engineClient: proto.NewEngineServiceClient(connection),
+			taskClient:   proto.NewTaskServiceClient(connection),
+		},
+	}
+}
+
+func (h *HttpEngineConnector) Close() error {
+	return nil
+}
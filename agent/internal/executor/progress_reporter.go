@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/connector"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const defaultProgressInterval = time.Second
+
+// progressReporter sends the progress of a task to the engine at most once per
+// interval. Updates reported in between replace each other, and the latest one
+// is sent when the interval elapses.
+type progressReporter struct {
+	taskID          string
+	interval        time.Duration
+	engineConnector connector.EngineConnector
+
+	mu       sync.Mutex
+	pending  *models.ProgressUpdate
+	lastSent time.Time
+	timer    *time.Timer
+	closed   bool
+}
+
+func newProgressReporter(taskID string, interval time.Duration, engineConnector connector.EngineConnector) *progressReporter {
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	return &progressReporter{
+		taskID:          taskID,
+		interval:        interval,
+		engineConnector: engineConnector,
+	}
+}
+
+func (r *progressReporter) Report(update models.ProgressUpdate) {
+	update.Progress = min(max(update.Progress, 0), 100)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.pending = &update
+	if r.timer == nil {
+		delay := max(time.Until(r.lastSent.Add(r.interval)), 0)
+		r.timer = time.AfterFunc(delay, r.flush)
+	}
+}
+
+// close stops sending updates once the task finished. Pending updates are
+// dropped, since the task status supersedes them.
+func (r *progressReporter) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	r.pending = nil
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+}
+
+func (r *progressReporter) flush() {
+	r.mu.Lock()
+	update := r.pending
+	r.pending = nil
+	r.timer = nil
+	r.lastSent = time.Now()
+	r.mu.Unlock()
+
+	if update == nil {
+		return
+	}
+
+	req := &proto.NotifyTaskProgressRequest{
+		TaskId:   r.taskID,
+		Progress: update.Progress,
+	}
+	if update.Message != "" {
+		req.Message = &update.Message
+	}
+	if update.PartialOutput != nil {
+		output, err := structpb.NewStruct(update.PartialOutput)
+		if err != nil {
+			log.Printf("Failed to convert partial output of task %s: %s", r.taskID, err.Error())
+			return
+		}
+		req.PartialOutput = output
+	}
+
+	if err := r.engineConnector.NotifyTaskProgress(req); err != nil {
+		log.Printf("Failed to report progress of task %s: %s", r.taskID, err.Error())
+	}
+}
@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+func expectProgress(t *testing.T, engine *fakeEngineConnector, want float32) *proto.NotifyTaskProgressRequest {
+	t.Helper()
+	select {
+	case sent := <-engine.progress:
+		if sent.TaskId != "t1" || sent.Progress != want {
+			t.Fatalf("expected progress %v of task t1 to be sent, got %v", want, sent)
+		}
+		return sent
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected progress %v to be sent", want)
+		return nil
+	}
+}
+
+func expectNoProgress(t *testing.T, engine *fakeEngineConnector, wait time.Duration) {
+	t.Helper()
+	select {
+	case sent := <-engine.progress:
+		t.Fatalf("expected no progress to be sent, got %v", sent)
+	case <-time.After(wait):
+	}
+}
+
+func TestProgressReporterThrottles(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	engine := newFakeEngineConnector()
+	reporter := newProgressReporter("t1", interval, engine)
+
+	reporter.Report(models.ProgressUpdate{Progress: 10, Message: "starting"})
+	first := expectProgress(t, engine, 10)
+	if first.GetMessage() != "starting" {
+		t.Fatalf("expected the message to be sent, got %v", first)
+	}
+	firstSentAt := time.Now()
+
+	// Updates reported within the interval replace each other.
+	reporter.Report(models.ProgressUpdate{Progress: 20})
+	reporter.Report(models.ProgressUpdate{Progress: 30})
+	reporter.Report(models.ProgressUpdate{Progress: 150, PartialOutput: map[string]interface{}{"rows": 3}})
+	latest := expectProgress(t, engine, 100)
+	if elapsed := time.Since(firstSentAt); elapsed < interval/2 {
+		t.Fatalf("expected the latest update to wait for the interval, sent after %s", elapsed)
+	}
+	if latest.PartialOutput.AsMap()["rows"] != float64(3) {
+		t.Fatalf("expected the partial output to be sent, got %v", latest.PartialOutput)
+	}
+
+	// Updates pending when the task finishes are dropped.
+	reporter.Report(models.ProgressUpdate{Progress: 50})
+	reporter.Report(models.ProgressUpdate{Progress: 60})
+	reporter.close()
+	expectNoProgress(t, engine, 2*interval)
+	reporter.Report(models.ProgressUpdate{Progress: 70})
+	expectNoProgress(t, engine, 2*interval)
+}
+
+func TestHandlerReportsProgress(t *testing.T) {
+	te, engine := newTestExecutor(10, func(ctx context.Context, req *models.TaskExecutionRequest) models.TaskExecutionResult {
+		req.Progress.Report(models.ProgressUpdate{Progress: 40, Message: "halfway"})
+		<-ctx.Done()
+		err := ctx.Err()
+		return models.TaskExecutionResult{Error: &err}
+	})
+	startExecutor(t, te)
+
+	if err := te.EnqueueTask(&TaskExecution{TaskID: "t1", TaskDefName: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := expectProgress(t, engine, 40); sent.GetMessage() != "halfway" {
+		t.Fatalf("expected the message of the handler to be sent, got %v", sent)
+	}
+	if err := te.StopTask("t1"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,284 @@
+package executor
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// finishedTaskRetention is how long the status of a finished task can still be
+// queried by the engine.
+const finishedTaskRetention = 10 * time.Minute
+
+// StopTask stops a task. A queued task never starts, and the context of a
+// running task is cancelled and its result discarded.
+func (te *TaskExecutor) StopTask(taskID string) error {
+	te.mu.Lock()
+	exec, found := te.tasks[taskID]
+	if !found {
+		te.mu.Unlock()
+		return ErrTaskNotFound
+	}
+	if isFinished(exec.status) {
+		te.mu.Unlock()
+		return ErrTaskFinished
+	}
+	exec.status = proto.TaskStatus_STOPPED
+	exec.message = "task stopped"
+	exec.held = nil
+	cancel := exec.cancel
+	te.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+
+	log.Printf("Task %s stopped", taskID)
+	te.forgetTask(taskID)
+	te.notifyStatus(&proto.NotifyTaskStatusRequest{
+		TaskId:  taskID,
+		Status:  proto.TaskStatus_STOPPED,
+		Message: "task stopped",
+	})
+	return nil
+}
+
+// PauseTask pauses a task. A queued task does not start until it is resumed.
+// The handler of a running task is signalled through the PauseSignal of its
+// TaskInfo, and its result is held back until the task is resumed.
+func (te *TaskExecutor) PauseTask(taskID string) error {
+	te.mu.Lock()
+	exec, found := te.tasks[taskID]
+	if !found {
+		te.mu.Unlock()
+		return ErrTaskNotFound
+	}
+	if isFinished(exec.status) {
+		te.mu.Unlock()
+		return ErrTaskFinished
+	}
+	if exec.status == proto.TaskStatus_PAUSED {
+		te.mu.Unlock()
+		return ErrTaskNotRunning
+	}
+	exec.status = proto.TaskStatus_PAUSED
+	if exec.pause != nil {
+		exec.pause.pause()
+	}
+	te.mu.Unlock()
+
+	log.Printf("Task %s paused", taskID)
+	te.notifyStatus(&proto.NotifyTaskStatusRequest{
+		TaskId: taskID,
+		Status: proto.TaskStatus_PAUSED,
+	})
+	return nil
+}
+
+// ResumeTask resumes a paused task. A task that was paused before it started
+// is queued again, and the result of a task that finished while paused is
+// reported.
+func (te *TaskExecutor) ResumeTask(taskID string) error {
+	te.mu.Lock()
+	exec, found := te.tasks[taskID]
+	if !found {
+		te.mu.Unlock()
+		return ErrTaskNotFound
+	}
+	if exec.status != proto.TaskStatus_PAUSED {
+		te.mu.Unlock()
+		return ErrTaskNotPaused
+	}
+
+	if !exec.started {
+		if !exec.queued {
+			select {
+			case te.taskQueue <- exec:
+				exec.queued = true
+			default:
+				te.mu.Unlock()
+				return ErrQueueFull
+			}
+		}
+		exec.status = proto.TaskStatus_PENDING
+		te.mu.Unlock()
+		log.Printf("Task %s resumed", taskID)
+		return nil
+	}
+
+	held := exec.held
+	exec.held = nil
+	exec.status = proto.TaskStatus_RUNNING
+	te.mu.Unlock()
+
+	log.Printf("Task %s resumed", taskID)
+	te.notifyStatus(&proto.NotifyTaskStatusRequest{
+		TaskId: taskID,
+		Status: proto.TaskStatus_RUNNING,
+	})
+
+	// The handler is woken once the engine was told the task runs again, so
+	// the result it reports next is not overtaken by that notification.
+	te.mu.Lock()
+	if exec.status == proto.TaskStatus_RUNNING {
+		exec.pause.resume()
+	}
+	te.mu.Unlock()
+	if held != nil {
+		te.reportResult(exec, *held)
+	}
+	return nil
+}
+
+// GetTaskStatus returns the status of a task known to the executor.
+func (te *TaskExecutor) GetTaskStatus(taskID string) (*proto.GetTaskStatusResponse, error) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	exec, found := te.tasks[taskID]
+	if !found {
+		return nil, ErrTaskNotFound
+	}
+
+	res := &proto.GetTaskStatusResponse{
+		TaskId:  taskID,
+		Status:  exec.status,
+		Message: exec.message,
+	}
+	if exec.status == proto.TaskStatus_COMPLETED {
+		res.Progress = 100
+	}
+	if exec.output != nil {
+		output, err := structpb.NewStruct(exec.output)
+		if err != nil {
+			return nil, err
+		}
+		res.Output = output
+	}
+	return res, nil
+}
+
+// claimTask marks a dequeued task as started and returns the context its
+// handler runs with. Stopped tasks are dropped, and paused ones are left aside
+// until they are resumed.
+func (te *TaskExecutor) claimTask(ctx context.Context, exec *TaskExecution) (context.Context, context.CancelFunc, bool) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	exec.queued = false
+	if exec.status == proto.TaskStatus_STOPPED || exec.status == proto.TaskStatus_PAUSED {
+		return nil, nil, false
+	}
+
+	exec.pause = &pauseSignal{}
+	taskCtx := models.ContextWithTaskInfo(ctx, models.TaskInfo{
+		TaskID:             exec.TaskID,
+		Attempt:            exec.Attempt,
+		WorkflowInstanceID: exec.WorkflowInstanceID,
+		Pause:              exec.pause,
+	})
+	var cancel context.CancelFunc
+	if exec.TimeoutSeconds != nil && *exec.TimeoutSeconds > 0 {
+		taskCtx, cancel = context.WithTimeout(taskCtx, time.Duration(*exec.TimeoutSeconds)*time.Second)
+	} else {
+		taskCtx, cancel = context.WithCancel(taskCtx)
+	}
+
+	exec.started = true
+	exec.status = proto.TaskStatus_RUNNING
+	exec.cancel = cancel
+	return taskCtx, cancel, true
+}
+
+// releaseResult reports whether the result of a task must be sent to the
+// engine. The result of a stopped task is discarded, and the result of a
+// paused task is held until it is resumed.
+func (te *TaskExecutor) releaseResult(exec *TaskExecution, result *models.TaskExecutionResult) bool {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	switch exec.status {
+	case proto.TaskStatus_STOPPED:
+		log.Printf("Task %s finished after being stopped, discarding its result", exec.TaskID)
+		return false
+	case proto.TaskStatus_PAUSED:
+		exec.held = result
+		return false
+	default:
+		return true
+	}
+}
+
+func (te *TaskExecutor) finishTask(exec *TaskExecution, status proto.TaskStatus, message string, output map[string]interface{}) {
+	te.mu.Lock()
+	exec.status = status
+	exec.message = message
+	exec.output = output
+	te.mu.Unlock()
+
+	te.forgetTask(exec.TaskID)
+}
+
+func (te *TaskExecutor) forgetTask(taskID string) {
+	time.AfterFunc(finishedTaskRetention, func() {
+		te.mu.Lock()
+		delete(te.tasks, taskID)
+		te.mu.Unlock()
+	})
+}
+
+func isFinished(status proto.TaskStatus) bool {
+	return status == proto.TaskStatus_COMPLETED || status == proto.TaskStatus_FAILED || status == proto.TaskStatus_STOPPED
+}
+
+// pauseSignal is the models.PauseSignal of a started task.
+type pauseSignal struct {
+	mu sync.Mutex
+	// resumed is closed when the task is resumed. It is nil while the task
+	// is not paused.
+	resumed chan struct{}
+}
+
+func (p *pauseSignal) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resumed != nil
+}
+
+func (p *pauseSignal) Wait(ctx context.Context) error {
+	p.mu.Lock()
+	resumed := p.resumed
+	p.mu.Unlock()
+
+	if resumed == nil {
+		return nil
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *pauseSignal) pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed == nil {
+		p.resumed = make(chan struct{})
+	}
+}
+
+func (p *pauseSignal) resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed != nil {
+		close(p.resumed)
+		p.resumed = nil
+	}
+}
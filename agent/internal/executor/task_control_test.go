@@ -0,0 +1,185 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+// blockingHandler returns a handler that signals started once it runs, then
+// waits for release or for its context to be done. The context error it saw,
+// if any, is sent on done.
+func blockingHandler(started chan<- models.TaskInfo, release <-chan struct{}, done chan<- error) models.ContextTaskHandler {
+	return func(ctx context.Context, req *models.TaskExecutionRequest) models.TaskExecutionResult {
+		info, _ := models.TaskInfoFromContext(ctx)
+		started <- info
+		select {
+		case <-release:
+			done <- nil
+			return models.TaskExecutionResult{Output: &req.Input}
+		case <-ctx.Done():
+			err := ctx.Err()
+			done <- err
+			return models.TaskExecutionResult{Error: &err}
+		}
+	}
+}
+
+func TestStopRunningTask(t *testing.T) {
+	started, release, done := make(chan models.TaskInfo, 1), make(chan struct{}), make(chan error, 1)
+	te, engine := newTestExecutor(10, blockingHandler(started, release, done))
+	startExecutor(t, te)
+
+	if err := te.EnqueueTask(&TaskExecution{TaskID: "t1", TaskDefName: "test", Attempt: 2, WorkflowInstanceID: "w1"}); err != nil {
+		t.Fatal(err)
+	}
+	engine.expectStatus(t, "t1", proto.TaskStatus_RUNNING)
+	if info := <-started; info.TaskID != "t1" || info.Attempt != 2 || info.WorkflowInstanceID != "w1" {
+		t.Fatalf("expected the handler to get the task info, got %+v", info)
+	}
+
+	if err := te.StopTask("t1"); err != nil {
+		t.Fatal(err)
+	}
+	engine.expectStatus(t, "t1", proto.TaskStatus_STOPPED)
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the context of the handler to be cancelled, got %v", err)
+	}
+	engine.expectNoStatus(t)
+
+	if res, err := te.GetTaskStatus("t1"); err != nil || res.Status != proto.TaskStatus_STOPPED {
+		t.Fatalf("expected the task to be stopped, got %v, %v", res, err)
+	}
+	if err := te.StopTask("t1"); !errors.Is(err, ErrTaskFinished) {
+		t.Fatalf("expected stopping a stopped task to be refused, got %v", err)
+	}
+}
+
+func TestStopQueuedTask(t *testing.T) {
+	called := make(chan struct{}, 1)
+	te, engine := newTestExecutor(10, func(_ context.Context, req *models.TaskExecutionRequest) models.TaskExecutionResult {
+		called <- struct{}{}
+		return models.TaskExecutionResult{Output: &req.Input}
+	})
+
+	if err := te.EnqueueTask(&TaskExecution{TaskID: "t1", TaskDefName: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := te.StopTask("t1"); err != nil {
+		t.Fatal(err)
+	}
+	engine.expectStatus(t, "t1", proto.TaskStatus_STOPPED)
+
+	startExecutor(t, te)
+	engine.expectNoStatus(t)
+	select {
+	case <-called:
+		t.Fatal("expected a task stopped while queued never to start")
+	default:
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	tests := []struct {
+		name string
+		// waitWhilePaused handlers suspend their work while the task is
+		// paused, the others keep running and their result is held back.
+		waitWhilePaused bool
+	}{
+		{name: "handler waiting while paused", waitWhilePaused: true},
+		{name: "handler running while paused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started, release, paused := make(chan models.TaskInfo, 1), make(chan struct{}), make(chan bool, 1)
+			te, engine := newTestExecutor(10, func(ctx context.Context, req *models.TaskExecutionRequest) models.TaskExecutionResult {
+				info, _ := models.TaskInfoFromContext(ctx)
+				started <- info
+				<-release
+				paused <- info.Pause.Paused()
+				if tt.waitWhilePaused {
+					if err := info.Pause.Wait(ctx); err != nil {
+						return models.TaskExecutionResult{Error: &err}
+					}
+				}
+				return models.TaskExecutionResult{Output: &req.Input}
+			})
+			startExecutor(t, te)
+
+			if err := te.EnqueueTask(&TaskExecution{TaskID: "t1", TaskDefName: "test"}); err != nil {
+				t.Fatal(err)
+			}
+			engine.expectStatus(t, "t1", proto.TaskStatus_RUNNING)
+			<-started
+
+			if err := te.PauseTask("t1"); err != nil {
+				t.Fatal(err)
+			}
+			engine.expectStatus(t, "t1", proto.TaskStatus_PAUSED)
+			if err := te.PauseTask("t1"); !errors.Is(err, ErrTaskNotRunning) {
+				t.Fatalf("expected pausing a paused task to be refused, got %v", err)
+			}
+
+			close(release)
+			if !<-paused {
+				t.Fatal("expected the handler to see the task paused")
+			}
+			engine.expectNoStatus(t)
+
+			if err := te.ResumeTask("t1"); err != nil {
+				t.Fatal(err)
+			}
+			engine.expectStatus(t, "t1", proto.TaskStatus_RUNNING)
+			engine.expectStatus(t, "t1", proto.TaskStatus_COMPLETED)
+			if err := te.ResumeTask("t1"); !errors.Is(err, ErrTaskNotPaused) {
+				t.Fatalf("expected resuming a completed task to be refused, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPauseQueuedTask(t *testing.T) {
+	te, engine := newTestExecutor(10, echoHandler)
+
+	if err := te.EnqueueTask(&TaskExecution{TaskID: "t1", TaskDefName: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := te.PauseTask("t1"); err != nil {
+		t.Fatal(err)
+	}
+	engine.expectStatus(t, "t1", proto.TaskStatus_PAUSED)
+
+	startExecutor(t, te)
+	engine.expectNoStatus(t)
+
+	if err := te.ResumeTask("t1"); err != nil {
+		t.Fatal(err)
+	}
+	engine.expectStatus(t, "t1", proto.TaskStatus_RUNNING)
+	engine.expectStatus(t, "t1", proto.TaskStatus_COMPLETED)
+}
+
+func TestTaskTimeout(t *testing.T) {
+	started, release, done := make(chan models.TaskInfo, 1), make(chan struct{}), make(chan error, 1)
+	te, engine := newTestExecutor(10, blockingHandler(started, release, done))
+	startExecutor(t, te)
+
+	timeout := int32(1)
+	if err := te.EnqueueTask(&TaskExecution{TaskID: "t1", TaskDefName: "test", TimeoutSeconds: &timeout}); err != nil {
+		t.Fatal(err)
+	}
+	engine.expectStatus(t, "t1", proto.TaskStatus_RUNNING)
+	<-started
+
+	failed := engine.expectStatus(t, "t1", proto.TaskStatus_FAILED)
+	if failed.GetErrorCode() != models.TaskErrorCodeTimedOut {
+		t.Fatalf("expected the task to fail as timed out, got %v", failed)
+	}
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context of the handler to time out, got %v", err)
+	}
+}
@@ -4,45 +4,85 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/kaptinlin/jsonschema"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/connector"
 	"github.com/paulhalleux/workflow-engine-go/agent/internal/models"
 	"github.com/paulhalleux/workflow-engine-go/agent/internal/registry"
 	"github.com/paulhalleux/workflow-engine-go/proto"
 	tjs "github.com/swaggest/jsonschema-go"
-	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
+var (
+	ErrQueueFull              = errors.New("task queue is full")
+	ErrTaskDefinitionNotFound = errors.New("task definition not found")
+	ErrTaskNotFound           = errors.New("task not found")
+	ErrTaskFinished           = errors.New("task already finished")
+	ErrTaskNotRunning         = errors.New("task is not running")
+	ErrTaskNotPaused          = errors.New("task is not paused")
+)
+
 type TaskExecution struct {
-	TaskID      string
-	TaskDefName string
-	Input       map[string]interface{}
+	TaskID             string
+	TaskDefName        string
+	Input              map[string]interface{}
+	TimeoutSeconds     *int32
+	Attempt            int
+	WorkflowInstanceID string
+
+	// The fields below are guarded by the executor mutex.
+	status  proto.TaskStatus
+	message string
+	output  map[string]interface{}
+	// queued is true while the task waits in the queue, and started once
+	// its handler was called.
+	queued  bool
+	started bool
+	// held is the result of a task that finished while paused. It is
+	// reported once the task is resumed.
+	held *models.TaskExecutionResult
+	// cancel cancels the context of a started task.
+	cancel context.CancelFunc
+	// pause signals the handler of a started task that it is paused.
+	pause *pauseSignal
 }
 
 type TaskExecutor struct {
-	engineConnection       *grpc.ClientConn
+	engineConnector        connector.EngineConnector
 	taskDefinitionRegistry *registry.TaskDefinitionRegistry
 	taskQueue              chan *TaskExecution
 	sem                    chan struct{}
+	progressInterval       time.Duration
+
+	mu    sync.Mutex
+	tasks map[string]*TaskExecution
 }
 
 type TaskExecutorConfig struct {
 	MaxQueueSize     int
 	MaxParallelTasks int
+	// ProgressInterval is the minimum delay between two progress updates
+	// sent for a task.
+	ProgressInterval time.Duration
 }
 
 func NewTaskExecutor(
 	config *TaskExecutorConfig,
 	taskDefinitionRegistry *registry.TaskDefinitionRegistry,
-	engineConnection *grpc.ClientConn,
+	engineConnector connector.EngineConnector,
 ) *TaskExecutor {
 	return &TaskExecutor{
-		engineConnection:       engineConnection,
+		engineConnector:        engineConnector,
 		taskDefinitionRegistry: taskDefinitionRegistry,
 		taskQueue:              make(chan *TaskExecution, config.MaxQueueSize),
 		sem:                    make(chan struct{}, config.MaxParallelTasks),
+		progressInterval:       config.ProgressInterval,
+		tasks:                  make(map[string]*TaskExecution),
 	}
 }
 
@@ -53,39 +93,64 @@ func (te *TaskExecutor) Start(ctx context.Context) {
 			return
 		case exec := <-te.taskQueue:
 			te.sem <- struct{}{}
+			taskCtx, cancel, claimed := te.claimTask(ctx, exec)
+			if !claimed {
+				<-te.sem
+				continue
+			}
 			te.startTask(exec)
 
 			var err error
 			taskDef, found := te.taskDefinitionRegistry.Get(exec.TaskDefName)
 			if !found {
-				err = errors.New("task definition not found")
+				err = models.NewTaskError(models.TaskErrorCodeTaskDefinitionNotFound, ErrTaskDefinitionNotFound)
 				println("Task definition not found for task:", exec.TaskID)
-			}
-
-			err = te.validateParameters(exec.Input, taskDef.InputParameters)
-			if err != nil {
-				println("Invalid input parameters for task:", exec.TaskID, "Error:", err.Error())
+			} else {
+				err = te.validateParameters(exec.Input, taskDef.InputParameters)
+				if err != nil {
+					println("Invalid input parameters for task:", exec.TaskID, "Error:", err.Error())
+					err = models.NewTaskError(models.TaskErrorCodeInvalidInput, err)
+				}
 			}
 
 			if err == nil {
 				go func(exec *TaskExecution) {
 					defer func() { <-te.sem }()
-					te.handle(exec, taskDef)
+					defer cancel()
+					te.handle(taskCtx, exec, taskDef)
 				}(exec)
 			} else {
 				te.failTask(exec, err)
+				cancel()
 				<-te.sem
 			}
 		}
 	}
 }
 
-func (te *TaskExecutor) handle(execCtx *TaskExecution, taskDef models.TaskDefinition) {
+func (te *TaskExecutor) handle(ctx context.Context, execCtx *TaskExecution, taskDef models.TaskDefinition) {
+	progress := newProgressReporter(execCtx.TaskID, te.progressInterval, te.engineConnector)
 	req := &models.TaskExecutionRequest{
-		Input: execCtx.Input,
+		Input:    execCtx.Input,
+		Progress: progress,
+	}
+
+	result := taskDef.Handler()(ctx, req)
+	progress.close()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		var err error = models.NewTaskError(
+			models.TaskErrorCodeTimedOut,
+			fmt.Errorf("task timed out after %d seconds", *execCtx.TimeoutSeconds),
+		)
+		result = models.TaskExecutionResult{Error: &err}
+	}
+	if !te.releaseResult(execCtx, &result) {
+		return
 	}
+	te.reportResult(execCtx, result)
+}
 
-	result := taskDef.Handle(req)
+func (te *TaskExecutor) reportResult(execCtx *TaskExecution, result models.TaskExecutionResult) {
 	if result.Error != nil {
 		println("Error executing task:", execCtx.TaskID, "Error:", (*result.Error).Error())
 		te.failTask(execCtx, *result.Error)
@@ -117,27 +182,27 @@ func (te *TaskExecutor) validateParameters(params map[string]interface{}, schema
 
 func (te *TaskExecutor) startTask(exec *TaskExecution) {
 	log.Printf("Task %s started", exec.TaskID)
-	client := proto.NewTaskServiceClient(te.engineConnection)
-	_, _ = client.NotifyTaskStatus(
-		context.Background(),
-		&proto.NotifyTaskStatusRequest{
-			TaskId: exec.TaskID,
-			Status: proto.TaskStatus_RUNNING,
-		},
-	)
+	te.notifyStatus(&proto.NotifyTaskStatusRequest{
+		TaskId: exec.TaskID,
+		Status: proto.TaskStatus_RUNNING,
+	})
 }
 
 func (te *TaskExecutor) failTask(exec *TaskExecution, err error) {
 	log.Printf("Task %s failed with error: %s", exec.TaskID, err.Error())
-	client := proto.NewTaskServiceClient(te.engineConnection)
-	_, _ = client.NotifyTaskStatus(
-		context.Background(),
-		&proto.NotifyTaskStatusRequest{
-			TaskId:  exec.TaskID,
-			Status:  proto.TaskStatus_FAILED,
-			Message: err.Error(),
-		},
-	)
+	req := &proto.NotifyTaskStatusRequest{
+		TaskId:  exec.TaskID,
+		Status:  proto.TaskStatus_FAILED,
+		Message: err.Error(),
+	}
+
+	var taskErr *models.TaskError
+	if errors.As(err, &taskErr) {
+		req.ErrorCode = &taskErr.Code
+	}
+
+	te.finishTask(exec, proto.TaskStatus_FAILED, err.Error(), nil)
+	te.notifyStatus(req)
 }
 
 func (te *TaskExecutor) completeTask(exec *TaskExecution, output map[string]interface{}) {
@@ -150,18 +215,39 @@ func (te *TaskExecutor) completeTask(exec *TaskExecution, output map[string]inte
 		return
 	}
 
-	client := proto.NewTaskServiceClient(te.engineConnection)
-	_, _ = client.NotifyTaskStatus(
-		context.Background(),
-		&proto.NotifyTaskStatusRequest{
-			TaskId:           exec.TaskID,
-			Status:           proto.TaskStatus_COMPLETED,
-			OutputParameters: outputStruct,
-		},
-	)
+	te.finishTask(exec, proto.TaskStatus_COMPLETED, "", output)
+	te.notifyStatus(&proto.NotifyTaskStatusRequest{
+		TaskId:           exec.TaskID,
+		Status:           proto.TaskStatus_COMPLETED,
+		OutputParameters: outputStruct,
+	})
+}
+
+func (te *TaskExecutor) notifyStatus(req *proto.NotifyTaskStatusRequest) {
+	_ = te.engineConnector.NotifyTaskStatus(req)
 }
 
-func (te *TaskExecutor) EnqueueTask(exec *TaskExecution) {
-	log.Printf("Enqueuing task %s of type %s", exec.TaskID, exec.TaskDefName)
-	te.taskQueue <- exec
+// EnqueueTask queues a task for execution. It never blocks: when the queue
+// already holds MaxQueueSize tasks, the task is rejected with ErrQueueFull.
+func (te *TaskExecutor) EnqueueTask(exec *TaskExecution) error {
+	if _, found := te.taskDefinitionRegistry.Get(exec.TaskDefName); !found {
+		return ErrTaskDefinitionNotFound
+	}
+
+	te.mu.Lock()
+	exec.status = proto.TaskStatus_PENDING
+	exec.queued = true
+	te.tasks[exec.TaskID] = exec
+	te.mu.Unlock()
+
+	select {
+	case te.taskQueue <- exec:
+		log.Printf("Enqueuing task %s of type %s", exec.TaskID, exec.TaskDefName)
+		return nil
+	default:
+		te.mu.Lock()
+		delete(te.tasks, exec.TaskID)
+		te.mu.Unlock()
+		return ErrQueueFull
+	}
 }
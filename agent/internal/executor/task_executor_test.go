@@ -0,0 +1,126 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/connector"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/registry"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+// fakeEngineConnector records the task statuses and progress updates sent to
+// the engine.
+type fakeEngineConnector struct {
+	statuses chan *proto.NotifyTaskStatusRequest
+	progress chan *proto.NotifyTaskProgressRequest
+}
+
+func newFakeEngineConnector() *fakeEngineConnector {
+	return &fakeEngineConnector{
+		statuses: make(chan *proto.NotifyTaskStatusRequest, 16),
+		progress: make(chan *proto.NotifyTaskProgressRequest, 16),
+	}
+}
+
+func (e *fakeEngineConnector) Close() error              { return nil }
+func (e *fakeEngineConnector) Ping(string) (bool, error) { return true, nil }
+func (e *fakeEngineConnector) RegisterAgent(*connector.AgentInfo) (bool, error) {
+	return true, nil
+}
+
+func (e *fakeEngineConnector) NotifyTaskStatus(req *proto.NotifyTaskStatusRequest) error {
+	e.statuses <- req
+	return nil
+}
+
+func (e *fakeEngineConnector) NotifyTaskProgress(req *proto.NotifyTaskProgressRequest) error {
+	e.progress <- req
+	return nil
+}
+
+// expectStatus waits for the next status notified for a task.
+func (e *fakeEngineConnector) expectStatus(t *testing.T, taskID string, want proto.TaskStatus) *proto.NotifyTaskStatusRequest {
+	t.Helper()
+	select {
+	case notified := <-e.statuses:
+		if notified.TaskId != taskID || notified.Status != want {
+			t.Fatalf("expected task %s to be notified %s, got %v", taskID, want, notified)
+		}
+		return notified
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected task %s to be notified %s", taskID, want)
+		return nil
+	}
+}
+
+// expectNoStatus checks that no status is notified for a while.
+func (e *fakeEngineConnector) expectNoStatus(t *testing.T) {
+	t.Helper()
+	select {
+	case notified := <-e.statuses:
+		t.Fatalf("expected no status to be notified, got %v", notified)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func newTestExecutor(maxQueueSize int, handler models.ContextTaskHandler) (*TaskExecutor, *fakeEngineConnector) {
+	reg := registry.NewTaskDefinitionRegistry()
+	reg.Register(models.TaskDefinition{ID: "test", Name: "Test", HandleWithContext: handler})
+
+	engine := newFakeEngineConnector()
+	return NewTaskExecutor(&TaskExecutorConfig{
+		MaxQueueSize:     maxQueueSize,
+		MaxParallelTasks: 1,
+		ProgressInterval: 50 * time.Millisecond,
+	}, reg, engine), engine
+}
+
+func startExecutor(t *testing.T, te *TaskExecutor) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go te.Start(ctx)
+}
+
+func echoHandler(_ context.Context, req *models.TaskExecutionRequest) models.TaskExecutionResult {
+	return models.TaskExecutionResult{Output: &req.Input}
+}
+
+func TestEnqueueTask(t *testing.T) {
+	te, engine := newTestExecutor(10, echoHandler)
+	startExecutor(t, te)
+
+	if err := te.EnqueueTask(&TaskExecution{TaskID: "t1", TaskDefName: "test", Input: map[string]interface{}{"message": "hello"}}); err != nil {
+		t.Fatal(err)
+	}
+	engine.expectStatus(t, "t1", proto.TaskStatus_RUNNING)
+	completed := engine.expectStatus(t, "t1", proto.TaskStatus_COMPLETED)
+	if completed.OutputParameters.AsMap()["message"] != "hello" {
+		t.Fatalf("expected the output to be notified, got %v", completed.OutputParameters)
+	}
+
+	if err := te.EnqueueTask(&TaskExecution{TaskID: "t2", TaskDefName: "missing"}); !errors.Is(err, ErrTaskDefinitionNotFound) {
+		t.Fatalf("expected an unknown task definition to be refused, got %v", err)
+	}
+}
+
+func TestEnqueueTaskQueueFull(t *testing.T) {
+	// The executor is not started, so queued tasks stay in the queue.
+	te, _ := newTestExecutor(1, echoHandler)
+
+	if err := te.EnqueueTask(&TaskExecution{TaskID: "t1", TaskDefName: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := te.EnqueueTask(&TaskExecution{TaskID: "t2", TaskDefName: "test"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected the task to be refused when the queue is full, got %v", err)
+	}
+	if _, err := te.GetTaskStatus("t2"); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("expected a refused task to be forgotten, got %v", err)
+	}
+	if res, err := te.GetTaskStatus("t1"); err != nil || res.Status != proto.TaskStatus_PENDING {
+		t.Fatalf("expected the queued task to be pending, got %v, %v", res, err)
+	}
+}
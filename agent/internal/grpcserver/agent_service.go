@@ -2,7 +2,10 @@ package grpcserver
 
 import (
 	"context"
+	"errors"
 
+	"github.com/google/uuid"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/executor"
 	"github.com/paulhalleux/workflow-engine-go/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -11,27 +14,92 @@ import (
 
 type AgentService struct {
 	proto.UnimplementedAgentServiceServer
+
+	taskExecutor *executor.TaskExecutor
 }
 
-func NewAgentService() *AgentService {
-	return &AgentService{}
+func NewAgentService(
+	taskExecutor *executor.TaskExecutor,
+) *AgentService {
+	return &AgentService{
+		taskExecutor: taskExecutor,
+	}
 }
 
-func (s *AgentService) StartTask(context.Context, *proto.StartTaskRequest) (*proto.TaskActionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method StartTask not implemented")
+func (s *AgentService) StartTask(_ context.Context, req *proto.StartTaskRequest) (*proto.TaskActionResponse, error) {
+	// The engine names the task, so it knows the task before it starts.
+	taskID := req.GetTaskId()
+	if taskID == "" {
+		taskID = uuid.NewString()
+	}
+	err := s.taskExecutor.EnqueueTask(&executor.TaskExecution{
+		TaskID:             taskID,
+		TaskDefName:        req.TaskName,
+		Input:              req.InputParameters.AsMap(),
+		TimeoutSeconds:     req.TimeoutSeconds,
+		Attempt:            int(req.GetAttempt()),
+		WorkflowInstanceID: req.GetWorkflowInstanceId(),
+	})
+
+	if errors.Is(err, executor.ErrTaskDefinitionNotFound) {
+		return nil, status.Errorf(codes.NotFound, "task definition %s not found", req.TaskName)
+	}
+	if errors.Is(err, executor.ErrQueueFull) {
+		return nil, status.Errorf(codes.ResourceExhausted, "cannot accept task %s: %v", req.TaskName, err)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to enqueue task %s: %v", req.TaskName, err)
+	}
+
+	return &proto.TaskActionResponse{
+		TaskId:  taskID,
+		Success: true,
+	}, nil
 }
-func (s *AgentService) GetTaskStatus(context.Context, *proto.TaskActionRequest) (*proto.GetTaskStatusResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetTaskStatus not implemented")
+
+func (s *AgentService) GetTaskStatus(_ context.Context, req *proto.TaskActionRequest) (*proto.GetTaskStatusResponse, error) {
+	res, err := s.taskExecutor.GetTaskStatus(req.TaskId)
+	if errors.Is(err, executor.ErrTaskNotFound) {
+		return nil, status.Errorf(codes.NotFound, "task %s not found", req.TaskId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get status of task %s: %v", req.TaskId, err)
+	}
+	return res, nil
 }
-func (s *AgentService) StopTask(context.Context, *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method StopTask not implemented")
+
+func (s *AgentService) StopTask(_ context.Context, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	return taskActionResponse(req.TaskId, s.taskExecutor.StopTask(req.TaskId))
 }
-func (s *AgentService) PauseTask(context.Context, *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method PauseTask not implemented")
+
+func (s *AgentService) PauseTask(_ context.Context, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	return taskActionResponse(req.TaskId, s.taskExecutor.PauseTask(req.TaskId))
 }
-func (s *AgentService) ResumeTask(context.Context, *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ResumeTask not implemented")
+
+func (s *AgentService) ResumeTask(_ context.Context, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	return taskActionResponse(req.TaskId, s.taskExecutor.ResumeTask(req.TaskId))
 }
+
 func (s *AgentService) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return &emptypb.Empty{}, nil
 }
+
+// taskActionResponse reports a task action refused because of the task state
+// as an unsuccessful response, and other failures as gRPC errors.
+func taskActionResponse(taskID string, err error) (*proto.TaskActionResponse, error) {
+	if errors.Is(err, executor.ErrTaskNotFound) {
+		return nil, status.Errorf(codes.NotFound, "task %s not found", taskID)
+	}
+	if err != nil {
+		message := err.Error()
+		return &proto.TaskActionResponse{
+			TaskId:  taskID,
+			Success: false,
+			Message: &message,
+		}, nil
+	}
+	return &proto.TaskActionResponse{
+		TaskId:  taskID,
+		Success: true,
+	}, nil
+}
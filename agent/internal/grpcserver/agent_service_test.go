@@ -0,0 +1,62 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/executor"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/registry"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStartTaskQueueFull(t *testing.T) {
+	reg := registry.NewTaskDefinitionRegistry()
+	reg.Register(models.TaskDefinition{
+		ID: "echo",
+		Handle: func(req *models.TaskExecutionRequest) models.TaskExecutionResult {
+			return models.TaskExecutionResult{Output: &req.Input}
+		},
+	})
+	// The executor is not started, so the first task fills the queue.
+	taskExecutor := executor.NewTaskExecutor(&executor.TaskExecutorConfig{MaxQueueSize: 1, MaxParallelTasks: 1}, reg, nil)
+	service := NewAgentService(taskExecutor)
+
+	res, err := service.StartTask(context.Background(), &proto.StartTaskRequest{TaskName: "echo"})
+	if err != nil || !res.Success {
+		t.Fatalf("expected the first task to be queued, got %v, %v", res, err)
+	}
+
+	_, err = service.StartTask(context.Background(), &proto.StartTaskRequest{TaskName: "echo"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the task to be refused as resource exhausted, got %v", err)
+	}
+}
+
+func TestStartTaskID(t *testing.T) {
+	reg := registry.NewTaskDefinitionRegistry()
+	reg.Register(models.TaskDefinition{
+		ID: "echo",
+		Handle: func(req *models.TaskExecutionRequest) models.TaskExecutionResult {
+			return models.TaskExecutionResult{Output: &req.Input}
+		},
+	})
+	taskExecutor := executor.NewTaskExecutor(&executor.TaskExecutorConfig{MaxQueueSize: 2, MaxParallelTasks: 1}, reg, nil)
+	service := NewAgentService(taskExecutor)
+
+	taskID := "engine-task"
+	res, err := service.StartTask(context.Background(), &proto.StartTaskRequest{TaskName: "echo", TaskId: &taskID})
+	if err != nil || res.TaskId != taskID {
+		t.Fatalf("expected the task to keep the ID given by the engine, got %v, %v", res, err)
+	}
+	if taskStatus, err := service.GetTaskStatus(context.Background(), &proto.TaskActionRequest{TaskId: taskID}); err != nil || taskStatus.Status != proto.TaskStatus_PENDING {
+		t.Fatalf("expected the task to be queued under its ID, got %v, %v", taskStatus, err)
+	}
+
+	res, err = service.StartTask(context.Background(), &proto.StartTaskRequest{TaskName: "echo"})
+	if err != nil || res.TaskId == "" || res.TaskId == taskID {
+		t.Fatalf("expected a task without ID to get one, got %v, %v", res, err)
+	}
+}
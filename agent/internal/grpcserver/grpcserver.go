@@ -52,6 +52,10 @@ func (g *GrpcServer) Stop() error {
 	return g.listener.Close()
 }
 
+func (g *GrpcServer) Addr() net.Addr {
+	return g.listener.Addr()
+}
+
 func (g *GrpcServer) GetServer() *grpc.Server {
 	return g.server
 }
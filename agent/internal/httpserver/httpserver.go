@@ -0,0 +1,58 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+// HttpServer serves the agent service with the HTTP transport, for engines
+// reaching the agent with AGENT_PROTOCOL_HTTP. It answers the same way as the
+// gRPC server, since both call the same AgentService.
+type HttpServer struct {
+	address  string
+	port     string
+	server   *http.Server
+	listener net.Listener
+}
+
+func NewHttpServer(
+	address,
+	port string,
+	agentService proto.AgentServiceServer,
+) *HttpServer {
+	mux := http.NewServeMux()
+	proto.RegisterHttpService(mux, &proto.AgentService_ServiceDesc, agentService)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%s", address, port))
+	if err != nil {
+		panic(fmt.Sprintf("failed to listen on %s:%s: %v", address, port, err))
+	}
+
+	return &HttpServer{
+		address:  address,
+		port:     port,
+		server:   &http.Server{Handler: mux},
+		listener: listener,
+	}
+}
+
+func (h *HttpServer) Start() {
+	log.Printf("[agent] HTTP server listening on %s", h.listener.Addr().String())
+	if err := h.server.Serve(h.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		panic(fmt.Sprintf("failed to start HTTP server: %v", err))
+	}
+}
+
+func (h *HttpServer) Stop() error {
+	return h.server.Shutdown(context.Background())
+}
+
+func (h *HttpServer) Addr() net.Addr {
+	return h.listener.Addr()
+}
@@ -1,13 +1,55 @@
 package models
 
-import "github.com/swaggest/jsonschema-go"
+import (
+	"context"
 
+	"github.com/swaggest/jsonschema-go"
+)
+
+// TaskHandler runs a task without a way to interrupt it. Prefer
+// ContextTaskHandler for tasks that can be stopped or time out.
 type TaskHandler func(req *TaskExecutionRequest) TaskExecutionResult
+
+// ContextTaskHandler runs a task until it completes or its context is done.
+// The context is cancelled when the task is stopped, when its timeout expires
+// and when the agent shuts down, and carries the TaskInfo of the task.
+type ContextTaskHandler func(ctx context.Context, req *TaskExecutionRequest) TaskExecutionResult
+
 type TaskDefinition struct {
-	ID               string
-	Name             string
-	Description      string
-	InputParameters  *jsonschema.Schema
-	OutputParameters *jsonschema.Schema
-	Handle           TaskHandler
+	ID                string
+	Name              string
+	Description       string
+	InputParameters   *jsonschema.Schema
+	OutputParameters  *jsonschema.Schema
+	Handle            TaskHandler
+	HandleWithContext ContextTaskHandler
+}
+
+// Handler returns the context-aware handler of the task, adapting Handle when
+// HandleWithContext is not set.
+func (d TaskDefinition) Handler() ContextTaskHandler {
+	if d.HandleWithContext != nil {
+		return d.HandleWithContext
+	}
+	return AdaptTaskHandler(d.Handle)
+}
+
+// AdaptTaskHandler turns a TaskHandler into a ContextTaskHandler. The adapted
+// handler returns the context error as soon as its context is done, while the
+// wrapped handler keeps running in the background until it returns.
+func AdaptTaskHandler(handler TaskHandler) ContextTaskHandler {
+	return func(ctx context.Context, req *TaskExecutionRequest) TaskExecutionResult {
+		done := make(chan TaskExecutionResult, 1)
+		go func() {
+			done <- handler(req)
+		}()
+
+		select {
+		case result := <-done:
+			return result
+		case <-ctx.Done():
+			err := ctx.Err()
+			return TaskExecutionResult{Error: &err}
+		}
+	}
 }
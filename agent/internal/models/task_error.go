@@ -0,0 +1,29 @@
+package models
+
+const (
+	TaskErrorCodeInvalidInput           = "INVALID_INPUT"
+	TaskErrorCodeTaskDefinitionNotFound = "TASK_DEFINITION_NOT_FOUND"
+	TaskErrorCodeTimedOut               = "TIMED_OUT"
+)
+
+// TaskError is an error returned by a task handler with a code the engine can
+// match against the non-retryable error codes of a step retry policy.
+type TaskError struct {
+	Code string
+	Err  error
+}
+
+func NewTaskError(code string, err error) *TaskError {
+	return &TaskError{
+		Code: code,
+		Err:  err,
+	}
+}
+
+func (e *TaskError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
@@ -7,4 +7,21 @@ type TaskExecutionResult struct {
 
 type TaskExecutionRequest struct {
 	Input map[string]interface{}
+	// Progress reports the progress of the task to the engine.
+	Progress ProgressReporter
+}
+
+// ProgressUpdate is the progress of a running task, as a percentage between 0
+// and 100, with an optional message and the output produced so far.
+type ProgressUpdate struct {
+	Progress      float32
+	Message       string
+	PartialOutput map[string]interface{}
+}
+
+// ProgressReporter sends progress updates to the engine. Updates are
+// throttled, so handlers can report as often as they like: only the latest
+// update of each interval is sent.
+type ProgressReporter interface {
+	Report(update ProgressUpdate)
 }
@@ -0,0 +1,39 @@
+package models
+
+import "context"
+
+// TaskInfo identifies the task a handler runs, so handlers can log and
+// deduplicate their work. Attempt and WorkflowInstanceID are zero when the
+// engine did not provide them.
+type TaskInfo struct {
+	TaskID             string
+	Attempt            int
+	WorkflowInstanceID string
+	// Pause tells the handler whether the task is paused.
+	Pause PauseSignal
+}
+
+// PauseSignal tells a handler whether its task is paused. Pausing a task never
+// interrupts its handler: handlers that can suspend their work call Wait
+// between units of work, and the result of those that keep running is held
+// back until the task is resumed.
+type PauseSignal interface {
+	// Paused reports whether the task is paused.
+	Paused() bool
+	// Wait blocks while the task is paused. It returns the error of the
+	// context if it is done first.
+	Wait(ctx context.Context) error
+}
+
+type taskInfoKey struct{}
+
+func ContextWithTaskInfo(ctx context.Context, info TaskInfo) context.Context {
+	return context.WithValue(ctx, taskInfoKey{}, info)
+}
+
+// TaskInfoFromContext returns the TaskInfo carried by the context of a task
+// handler.
+func TaskInfoFromContext(ctx context.Context) (TaskInfo, bool) {
+	info, ok := ctx.Value(taskInfoKey{}).(TaskInfo)
+	return info, ok
+}
@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/connector"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/executor"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/grpcserver"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/httpserver"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/agent/internal/registry"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeEngine records the agents registering with it and the task statuses
+// they notify.
+type fakeEngine struct {
+	proto.UnimplementedEngineServiceServer
+	proto.UnimplementedTaskServiceServer
+
+	mu       sync.Mutex
+	agents   map[string]*proto.RegisterAgentRequest
+	statuses chan *proto.NotifyTaskStatusRequest
+}
+
+func newFakeEngine() *fakeEngine {
+	return &fakeEngine{
+		agents:   make(map[string]*proto.RegisterAgentRequest),
+		statuses: make(chan *proto.NotifyTaskStatusRequest, 16),
+	}
+}
+
+func (e *fakeEngine) RegisterAgent(_ context.Context, req *proto.RegisterAgentRequest) (*proto.RegisterAgentResponse, error) {
+	if req.Name == "" {
+		message := "agent name is required"
+		return &proto.RegisterAgentResponse{Success: false, Message: &message}, nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.agents[req.Name] = req
+	return &proto.RegisterAgentResponse{Success: true}, nil
+}
+
+func (e *fakeEngine) agent(name string) *proto.RegisterAgentRequest {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.agents[name]
+}
+
+func (e *fakeEngine) Ping(_ context.Context, req *proto.EnginePingRequest) (*proto.EnginePingResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, known := e.agents[req.Name]
+	return &proto.EnginePingResponse{KnowAgent: known}, nil
+}
+
+func (e *fakeEngine) NotifyTaskStatus(_ context.Context, req *proto.NotifyTaskStatusRequest) (*emptypb.Empty, error) {
+	e.statuses <- req
+	return &emptypb.Empty{}, nil
+}
+
+func (e *fakeEngine) NotifyTaskProgress(context.Context, *proto.NotifyTaskProgressRequest) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+// transport serves the fake engine and the agent service with one protocol.
+// serveEngine returns the connector of the agent to the engine, and serveAgent
+// a client of the agent service.
+type transport struct {
+	protocol    proto.AgentProtocol
+	serveEngine func(t *testing.T, engine *fakeEngine) connector.EngineConnector
+	serveAgent  func(t *testing.T, agentService proto.AgentServiceServer) proto.AgentServiceClient
+}
+
+var transports = []transport{
+	{
+		protocol: proto.AGENT_PROTOCOL_GRPC,
+		serveEngine: func(t *testing.T, engine *fakeEngine) connector.EngineConnector {
+			engineSrv := grpc.NewServer()
+			proto.RegisterEngineServiceServer(engineSrv, engine)
+			proto.RegisterTaskServiceServer(engineSrv, engine)
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go engineSrv.Serve(listener)
+			t.Cleanup(engineSrv.Stop)
+
+			engineConnector, err := connector.NewEngineConnector(proto.AGENT_PROTOCOL_GRPC, listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = engineConnector.Close() })
+			return engineConnector
+		},
+		serveAgent: func(t *testing.T, agentService proto.AgentServiceServer) proto.AgentServiceClient {
+			agentSrv := grpcserver.NewGrpcServer("127.0.0.1", "0", agentService)
+			go agentSrv.Start()
+			t.Cleanup(func() { _ = agentSrv.Stop() })
+
+			conn, err := grpc.NewClient(agentSrv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+			return proto.NewAgentServiceClient(conn)
+		},
+	},
+	{
+		protocol: proto.AGENT_PROTOCOL_HTTP,
+		serveEngine: func(t *testing.T, engine *fakeEngine) connector.EngineConnector {
+			mux := http.NewServeMux()
+			proto.RegisterHttpService(mux, &proto.EngineService_ServiceDesc, engine)
+			proto.RegisterHttpService(mux, &proto.TaskService_ServiceDesc, engine)
+			engineSrv := httptest.NewServer(mux)
+			t.Cleanup(engineSrv.Close)
+
+			engineConnector, err := connector.NewEngineConnector(proto.AGENT_PROTOCOL_HTTP, engineSrv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = engineConnector.Close() })
+			return engineConnector
+		},
+		serveAgent: func(t *testing.T, agentService proto.AgentServiceServer) proto.AgentServiceClient {
+			agentSrv := httpserver.NewHttpServer("127.0.0.1", "0", agentService)
+			go agentSrv.Start()
+			t.Cleanup(func() { _ = agentSrv.Stop() })
+
+			return proto.NewAgentServiceClient(proto.NewHttpClientConn("http://" + agentSrv.Addr().String()))
+		},
+	},
+}
+
+// TestTransportContract checks that agents behave the same whatever the
+// protocol they talk with the engine.
+func TestTransportContract(t *testing.T) {
+	for _, tr := range transports {
+		t.Run(tr.protocol.String(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			reg := registry.NewTaskDefinitionRegistry()
+			reg.Register(models.TaskDefinition{
+				ID:   "echo",
+				Name: "Echo",
+				Handle: func(req *models.TaskExecutionRequest) models.TaskExecutionResult {
+					return models.TaskExecutionResult{Output: &req.Input}
+				},
+			})
+
+			engine := newFakeEngine()
+			engineConnector := tr.serveEngine(t, engine)
+			taskExecutor := executor.NewTaskExecutor(&executor.TaskExecutorConfig{
+				MaxQueueSize:     10,
+				MaxParallelTasks: 1,
+			}, reg, engineConnector)
+			go taskExecutor.Start(ctx)
+			client := tr.serveAgent(t, grpcserver.NewAgentService(taskExecutor))
+
+			testRegisterAgent(t, tr.protocol, engine, engineConnector)
+			testRunTask(t, engine, client)
+			testUnknownTask(t, client)
+		})
+	}
+}
+
+func testRegisterAgent(t *testing.T, protocol proto.AgentProtocol, engine *fakeEngine, engineConnector connector.EngineConnector) {
+	if known, err := engineConnector.Ping("echo-agent"); err != nil || known {
+		t.Fatalf("expected the engine not to know the agent yet, got %v, %v", known, err)
+	}
+
+	registered, err := engineConnector.RegisterAgent(&connector.AgentInfo{Name: "echo-agent", Version: "v1.0.0", Port: "50051", Weight: 1})
+	if err != nil || !registered {
+		t.Fatalf("expected the agent to register, got %v, %v", registered, err)
+	}
+	if got := engine.agent("echo-agent").GetProtocol(); got != protocol {
+		t.Fatalf("expected the agent to register with %s, got %s", protocol, got)
+	}
+	if known, err := engineConnector.Ping("echo-agent"); err != nil || !known {
+		t.Fatalf("expected the engine to know the agent, got %v, %v", known, err)
+	}
+
+	if _, err := engineConnector.RegisterAgent(&connector.AgentInfo{}); err == nil || err.Error() != "agent name is required" {
+		t.Fatalf("expected the refusal of the engine to be returned, got %v", err)
+	}
+}
+
+func testRunTask(t *testing.T, engine *fakeEngine, client proto.AgentServiceClient) {
+	input, _ := structpb.NewStruct(map[string]interface{}{"message": "hello"})
+	res, err := client.StartTask(context.Background(), &proto.StartTaskRequest{TaskName: "echo", InputParameters: input})
+	if err != nil || !res.Success || res.TaskId == "" {
+		t.Fatalf("expected the task to start, got %v, %v", res, err)
+	}
+
+	for _, want := range []proto.TaskStatus{proto.TaskStatus_RUNNING, proto.TaskStatus_COMPLETED} {
+		select {
+		case notified := <-engine.statuses:
+			if notified.TaskId != res.TaskId || notified.Status != want {
+				t.Fatalf("expected task %s to be notified %s, got %v", res.TaskId, want, notified)
+			}
+			if want == proto.TaskStatus_COMPLETED && notified.OutputParameters.AsMap()["message"] != "hello" {
+				t.Fatalf("expected the output to be notified, got %v", notified.OutputParameters)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected task %s to be notified %s", res.TaskId, want)
+		}
+	}
+
+	taskStatus, err := client.GetTaskStatus(context.Background(), &proto.TaskActionRequest{TaskId: res.TaskId})
+	if err != nil || taskStatus.Status != proto.TaskStatus_COMPLETED || taskStatus.Progress != 100 {
+		t.Fatalf("expected the task to be completed, got %v, %v", taskStatus, err)
+	}
+	if taskStatus.Output.AsMap()["message"] != "hello" {
+		t.Fatalf("expected the output of the task, got %v", taskStatus.Output)
+	}
+
+	stopped, err := client.StopTask(context.Background(), &proto.TaskActionRequest{TaskId: res.TaskId})
+	if err != nil || stopped.Success || stopped.GetMessage() != executor.ErrTaskFinished.Error() {
+		t.Fatalf("expected stopping a finished task to be refused, got %v, %v", stopped, err)
+	}
+}
+
+func testUnknownTask(t *testing.T, client proto.AgentServiceClient) {
+	_, err := client.StartTask(context.Background(), &proto.StartTaskRequest{TaskName: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected starting an unknown task definition to be not found, got %v", err)
+	}
+
+	_, err = client.GetTaskStatus(context.Background(), &proto.TaskActionRequest{TaskId: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected the status of an unknown task to be not found, got %v", err)
+	}
+
+	_, err = client.PauseTask(context.Background(), &proto.TaskActionRequest{TaskId: "missing"})
+	if status.Code(err) != codes.NotFound || status.Convert(err).Message() != "task missing not found" {
+		t.Fatalf("expected pausing an unknown task to be not found, got %v", err)
+	}
+}
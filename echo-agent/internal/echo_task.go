@@ -1,6 +1,9 @@
 package internal
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/paulhalleux/workflow-engine-go/agent"
@@ -21,8 +24,29 @@ func NewEchoTaskDefinition() agent.TaskDefinition {
 		Description:      "A task that echoes a message",
 		InputParameters:  agent.ReflectJsonSchema(inputParameters{}),
 		OutputParameters: agent.ReflectJsonSchema(outputParameters{}),
-		Handle: func(req *agent.TaskExecutionRequest) agent.TaskExecutionResult {
-			time.Sleep(5 * time.Second) // Simulate some processing time
+		HandleWithContext: func(ctx context.Context, req *agent.TaskExecutionRequest) agent.TaskExecutionResult {
+			info, _ := agent.TaskInfoFromContext(ctx)
+
+			// Simulate some processing time, suspended while the task is paused
+			for i := 1; i <= 5; i++ {
+				if info.Pause != nil {
+					if err := info.Pause.Wait(ctx); err != nil {
+						return agent.TaskExecutionResult{Error: &err}
+					}
+				}
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					err := ctx.Err()
+					return agent.TaskExecutionResult{Error: &err}
+				}
+				req.Progress.Report(agent.ProgressUpdate{
+					Progress: float32(i * 20),
+					Message:  fmt.Sprintf("step %d of 5", i),
+				})
+			}
+
+			log.Printf("Echoing message of task %s (attempt %d)", info.TaskID, info.Attempt)
 			return agent.TaskExecutionResult{
 				Output: &map[string]interface{}{
 					"message": req.Input["message"],
@@ -3,6 +3,7 @@ package app
 import (
 	"fmt"
 	"os"
+	"strconv"
 )
 
 type Config struct {
@@ -18,6 +19,17 @@ type Config struct {
 	DbPassword string
 	DbName     string
 	DbSSLMode  string
+
+	SchedulerWorkers   int
+	SchedulerQueueSize int
+
+	TimerPollIntervalMs int
+	TimerBatchSize      int
+	TimerClaimTimeoutMs int
+
+	AgentPingIntervalMs int
+	AgentOfflineAfter   int
+	AgentBalancer       string
 }
 
 func LoadConfigFromEnv() (*Config, error) {
@@ -34,6 +46,33 @@ func LoadConfigFromEnv() (*Config, error) {
 		DbPassword: os.Getenv("DB_PASSWORD"),
 		DbName:     os.Getenv("DB_NAME"),
 		DbSSLMode:  getEnvDefault("DB_SSLMODE", "disable"),
+
+		AgentBalancer: getEnvDefault("AGENT_BALANCER", "round-robin"),
+	}
+
+	var err error
+	if cfg.SchedulerWorkers, err = getEnvIntDefault("SCHEDULER_WORKERS", 4); err != nil {
+		return nil, err
+	}
+	if cfg.SchedulerQueueSize, err = getEnvIntDefault("SCHEDULER_QUEUE_SIZE", 1000); err != nil {
+		return nil, err
+	}
+
+	if cfg.TimerPollIntervalMs, err = getEnvIntDefault("TIMER_POLL_INTERVAL_MS", 1000); err != nil {
+		return nil, err
+	}
+	if cfg.TimerBatchSize, err = getEnvIntDefault("TIMER_BATCH_SIZE", 100); err != nil {
+		return nil, err
+	}
+	if cfg.TimerClaimTimeoutMs, err = getEnvIntDefault("TIMER_CLAIM_TIMEOUT_MS", 60000); err != nil {
+		return nil, err
+	}
+
+	if cfg.AgentPingIntervalMs, err = getEnvIntDefault("AGENT_PING_INTERVAL_MS", 10000); err != nil {
+		return nil, err
+	}
+	if cfg.AgentOfflineAfter, err = getEnvIntDefault("AGENT_OFFLINE_AFTER", 3); err != nil {
+		return nil, err
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -68,6 +107,19 @@ func (c *Config) validate() error {
 		return fmt.Errorf("missing required env vars: %v", missing)
 	}
 
+	if c.TimerPollIntervalMs <= 0 {
+		return fmt.Errorf("TIMER_POLL_INTERVAL_MS must be positive")
+	}
+	if c.TimerClaimTimeoutMs <= 0 {
+		return fmt.Errorf("TIMER_CLAIM_TIMEOUT_MS must be positive")
+	}
+	if c.AgentPingIntervalMs <= 0 {
+		return fmt.Errorf("AGENT_PING_INTERVAL_MS must be positive")
+	}
+	if c.AgentOfflineAfter <= 0 {
+		return fmt.Errorf("AGENT_OFFLINE_AFTER must be positive")
+	}
+
 	return nil
 }
 
@@ -77,3 +129,15 @@ func getEnvDefault(key, def string) string {
 	}
 	return def
 }
+
+func getEnvIntDefault(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return i, nil
+}
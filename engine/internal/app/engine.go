@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/grpcserver"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/httpserver"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/registry"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/scheduler"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/ws"
 	"github.com/paulhalleux/workflow-engine-go/proto"
 	"gorm.io/gorm"
@@ -34,35 +37,91 @@ func NewEngine(ctx context.Context, cfg *Config) (*Engine, error) {
 }
 
 func (e *Engine) Start() error {
-	agentRegistry := registry.NewAgentRegistry()
+	balancer, err := registry.NewBalancer(e.cfg.AgentBalancer)
+	if err != nil {
+		return fmt.Errorf("create agent balancer: %w", err)
+	}
+	agentRepo := persistance.NewAgentRepository(e.db)
+	agentRegistry := registry.NewAgentRegistry(balancer, agentRepo)
+	if err := agentRegistry.Restore(); err != nil {
+		log.Printf("[engine] failed to restore agents: %v", err)
+	}
+
+	wfDefRepo := persistance.NewWorkflowDefinitionRepository(e.db)
+	wfInstanceRepo := persistance.NewWorkflowInstanceRepository(e.db)
+	stepInstanceRepo := persistance.NewStepInstanceRepository(e.db)
+	timerRepo := persistance.NewTimerRepository(e.db)
+	stepAttemptRepo := persistance.NewStepAttemptRepository(e.db)
+
+	sched := scheduler.NewScheduler(&scheduler.Config{
+		Workers:           e.cfg.SchedulerWorkers,
+		QueueSize:         e.cfg.SchedulerQueueSize,
+		TimerPollInterval: time.Duration(e.cfg.TimerPollIntervalMs) * time.Millisecond,
+		TimerBatchSize:    e.cfg.TimerBatchSize,
+		TimerClaimTimeout: time.Duration(e.cfg.TimerClaimTimeoutMs) * time.Millisecond,
+	}, wfDefRepo, wfInstanceRepo, stepInstanceRepo, timerRepo, stepAttemptRepo)
+	sched.Registry.RegisterHandler(models.StepTypeFork, scheduler.NewForkStepHandler())
+	sched.Registry.RegisterHandler(models.StepTypeJoin, scheduler.NewJoinStepHandler(sched))
+	sched.Registry.RegisterHandler(models.StepTypeDecision, scheduler.NewDecisionStepHandler())
+	sched.Registry.RegisterHandler(models.StepTypeWait, scheduler.NewWaitStepHandler(sched))
+	sched.Registry.RegisterHandler(models.StepTypeTask, scheduler.NewTaskStepHandler(agentRegistry, stepInstanceRepo))
+	sched.Registry.RegisterHandler(models.StepTypeWorkflow, scheduler.NewWorkflowStepHandler(sched))
+
+	heartbeat := registry.NewHeartbeat(agentRegistry, &registry.HeartbeatConfig{
+		Interval:     time.Duration(e.cfg.AgentPingIntervalMs) * time.Millisecond,
+		OfflineAfter: e.cfg.AgentOfflineAfter,
+	})
+	schedAgentEvents, unsubscribeSched := agentRegistry.Subscribe()
+	defer unsubscribeSched()
 
 	httpSrv := httpserver.NewHttpServer(
 		e.cfg.HttpAddress,
 		e.cfg.HttpPort,
 	)
 
+	wsSrv := ws.NewServer()
+	wsSrv.Registry.RegisterCommand(proto.WEBSOCKET_COMMAND_TYPE_SUBSCRIBE, ws.NewSubscribeCommandHandler())
+	wsAgentEvents, unsubscribeWs := agentRegistry.Subscribe()
+	defer unsubscribeWs()
+
+	engineService := grpcserver.NewEngineService(
+		agentRegistry,
+		wfDefRepo,
+		sched,
+	)
+	taskService := grpcserver.NewTaskService(
+		stepInstanceRepo,
+		stepAttemptRepo,
+		sched,
+		agentRegistry,
+		wsSrv,
+	)
 	grpcSrv := grpcserver.NewGrpcServer(
 		e.cfg.GrpcAddress,
 		e.cfg.GrpcPort,
-		grpcserver.NewEngineService(
-			agentRegistry,
-		),
-		grpcserver.NewTaskService(),
+		engineService,
+		taskService,
 	)
 
-	wsSrv := ws.NewServer()
-	wsSrv.Registry.RegisterCommand(proto.WEBSOCKET_COMMAND_TYPE_SUBSCRIBE, ws.NewSubscribeCommandHandler())
-
-	wfDefRepo := persistance.NewWorkflowDefinitionRepository(e.db)
-	wfDefHandlers := httpserver.NewWorkflowDefinitionsHandlers(wfDefRepo)
-	wfAgentsHandlers := httpserver.NewAgentsHandlers(agentRegistry)
+	wfDefHandlers := httpserver.NewWorkflowDefinitionsHandlers(wfDefRepo, wfInstanceRepo, agentRegistry)
+	wfInstanceHandlers := httpserver.NewWorkflowInstancesHandlers(wfInstanceRepo, stepInstanceRepo, stepAttemptRepo, sched)
+	wfAgentsHandlers := httpserver.NewAgentsHandlers(agentRegistry, agentRepo)
+	timersHandlers := httpserver.NewTimersHandlers(timerRepo, sched)
+	agentProtocolHandlers := httpserver.NewAgentProtocolHandlers(engineService, taskService)
 
 	httpSrv.RegisterApiHandler(wfDefHandlers)
+	httpSrv.RegisterApiHandler(wfInstanceHandlers)
 	httpSrv.RegisterApiHandler(wfAgentsHandlers)
+	httpSrv.RegisterApiHandler(timersHandlers)
+	httpSrv.RegisterHandler(agentProtocolHandlers)
 
 	// Lancer les serveurs en goroutines.
 	go httpSrv.Start(wsSrv)
 	go grpcSrv.Start()
+	go sched.Start(e.ctx)
+	go heartbeat.Start(e.ctx)
+	go sched.WatchAgents(e.ctx, schedAgentEvents)
+	go wsSrv.BroadcastAgentEvents(e.ctx, wsAgentEvents)
 
 	// Attendre la fin du contexte.
 	<-e.ctx.Done()
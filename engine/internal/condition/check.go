@@ -0,0 +1,143 @@
+package condition
+
+import (
+	"fmt"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+type valueType string
+
+const (
+	typeAny     valueType = "any"
+	typeNull    valueType = "null"
+	typeBoolean valueType = "boolean"
+	typeNumber  valueType = "number"
+	typeString  valueType = "string"
+	typeObject  valueType = "object"
+	typeArray   valueType = "array"
+)
+
+func check(n node, definition *models.WorkflowDefinition) (valueType, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		return literalType(n.value), nil
+	case *referenceNode:
+		return checkReference(n, definition)
+	case *unaryNode:
+		if err := checkBoolean(n.operand, definition); err != nil {
+			return "", err
+		}
+		return typeBoolean, nil
+	case *binaryNode:
+		return checkBinary(n, definition)
+	default:
+		return "", fmt.Errorf("%w: unknown node %T", errors.ErrInvalidCondition, n)
+	}
+}
+
+func checkBoolean(n node, definition *models.WorkflowDefinition) error {
+	t, err := check(n, definition)
+	if err != nil {
+		return err
+	}
+	if t != typeBoolean && t != typeAny {
+		return fmt.Errorf("%w: expected a boolean operand, got %s", errors.ErrInvalidCondition, t)
+	}
+	return nil
+}
+
+func checkBinary(n *binaryNode, definition *models.WorkflowDefinition) (valueType, error) {
+	if n.op == "&&" || n.op == "||" {
+		if err := checkBoolean(n.left, definition); err != nil {
+			return "", err
+		}
+		if err := checkBoolean(n.right, definition); err != nil {
+			return "", err
+		}
+		return typeBoolean, nil
+	}
+
+	left, err := check(n.left, definition)
+	if err != nil {
+		return "", err
+	}
+	right, err := check(n.right, definition)
+	if err != nil {
+		return "", err
+	}
+	known := left != typeAny && right != typeAny
+
+	switch n.op {
+	case "==", "!=":
+		if known && left != right && left != typeNull && right != typeNull {
+			return "", fmt.Errorf("%w: cannot compare %s %s %s", errors.ErrInvalidCondition, left, n.op, right)
+		}
+	default:
+		for _, t := range []valueType{left, right} {
+			if t != typeAny && t != typeNumber && t != typeString {
+				return "", fmt.Errorf("%w: operator %s does not apply to %s", errors.ErrInvalidCondition, n.op, t)
+			}
+		}
+		if known && left != right {
+			return "", fmt.Errorf("%w: cannot compare %s %s %s", errors.ErrInvalidCondition, left, n.op, right)
+		}
+	}
+	return typeBoolean, nil
+}
+
+func checkReference(ref *referenceNode, definition *models.WorkflowDefinition) (valueType, error) {
+	if ref.root == rootSteps {
+		if _, exists := definition.GetStepByID(ref.stepID); !exists {
+			return "", fmt.Errorf("%w: %s references unknown step %s", errors.ErrInvalidCondition, ref, ref.stepID)
+		}
+		return typeAny, nil
+	}
+
+	name := ref.path[0].Key
+	if definition.InputParameters != nil {
+		for _, param := range *definition.InputParameters {
+			if param.Name != name {
+				continue
+			}
+			if len(ref.path) > 1 {
+				return typeAny, nil
+			}
+			return parameterType(param.Type), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s references unknown input parameter %s", errors.ErrInvalidCondition, ref, name)
+}
+
+func literalType(value interface{}) valueType {
+	switch value.(type) {
+	case nil:
+		return typeNull
+	case bool:
+		return typeBoolean
+	case float64:
+		return typeNumber
+	case string:
+		return typeString
+	default:
+		return typeAny
+	}
+}
+
+func parameterType(paramType string) valueType {
+	switch paramType {
+	case "string":
+		return typeString
+	case "number", "integer":
+		return typeNumber
+	case "boolean":
+		return typeBoolean
+	case "object":
+		return typeObject
+	case "array":
+		return typeArray
+	default:
+		return typeAny
+	}
+}
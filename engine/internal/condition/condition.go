@@ -0,0 +1,194 @@
+// Package condition implements the expression language used by decision step
+// cases, e.g. `input.amount > 100 && steps.check-stock.available == true`.
+//
+// Operands are literals (numbers, 'strings' or "strings", true, false, null)
+// or references to the workflow input (`input.<path>`) and to the output of a
+// completed step (`steps.<stepId>.<path>`). Supported operators are ==, !=,
+// <, <=, >, >=, &&, || and !, with parentheses for grouping.
+package condition
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/resolver"
+)
+
+type Expression struct {
+	source string
+	root   node
+}
+
+// Parse parses a condition without checking it against a workflow definition.
+func Parse(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate evaluates the condition against the workflow input and the output
+// of the completed steps. References to missing values evaluate to null.
+func (e *Expression) Evaluate(ctx *resolver.Context) (bool, error) {
+	value, err := evaluate(e.root, ctx)
+	if err != nil {
+		return false, fmt.Errorf("condition %q: %w", e.source, err)
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: condition %q evaluated to %v, not a boolean", errors.ErrConditionEvaluation, e.source, value)
+	}
+	return result, nil
+}
+
+// Check type-checks the condition against a workflow definition: referenced
+// input parameters and steps must exist, and operands must have compatible
+// types wherever they are known statically.
+func (e *Expression) Check(definition *models.WorkflowDefinition) error {
+	t, err := check(e.root, definition)
+	if err != nil {
+		return fmt.Errorf("condition %q: %w", e.source, err)
+	}
+	if t != typeBoolean && t != typeAny {
+		return fmt.Errorf("%w: condition %q is of type %s, not boolean", errors.ErrInvalidCondition, e.source, t)
+	}
+	return nil
+}
+
+func evaluate(n node, ctx *resolver.Context) (interface{}, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		return n.value, nil
+	case *referenceNode:
+		return lookup(n, ctx), nil
+	case *unaryNode:
+		operand, err := evaluateBoolean(n.operand, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return !operand, nil
+	case *binaryNode:
+		return evaluateBinary(n, ctx)
+	default:
+		return nil, fmt.Errorf("%w: unknown node %T", errors.ErrConditionEvaluation, n)
+	}
+}
+
+func evaluateBoolean(n node, ctx *resolver.Context) (bool, error) {
+	value, err := evaluate(n, ctx)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: expected a boolean, got %v", errors.ErrConditionEvaluation, value)
+	}
+	return result, nil
+}
+
+func evaluateBinary(n *binaryNode, ctx *resolver.Context) (interface{}, error) {
+	switch n.op {
+	case "&&", "||":
+		left, err := evaluateBoolean(n.left, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if (n.op == "&&" && !left) || (n.op == "||" && left) {
+			return left, nil
+		}
+		return evaluateBoolean(n.right, ctx)
+	}
+
+	left, err := evaluate(n.left, ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evaluate(n.right, ctx)
+	if err != nil {
+		return nil, err
+	}
+	left, right = normalize(left), normalize(right)
+
+	switch n.op {
+	case "==":
+		return reflect.DeepEqual(left, right), nil
+	case "!=":
+		return !reflect.DeepEqual(left, right), nil
+	}
+
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			return compare(n.op, l < r, l == r), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return compare(n.op, l < r, l == r), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: cannot compare %v %s %v", errors.ErrConditionEvaluation, left, n.op, right)
+}
+
+func compare(op string, less bool, equal bool) bool {
+	switch op {
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	default:
+		return !less
+	}
+}
+
+func lookup(ref *referenceNode, ctx *resolver.Context) interface{} {
+	var source interface{} = ctx.WorkflowInput
+	if ref.root == rootSteps {
+		output, exists := ctx.StepOutputs[ref.stepID]
+		if !exists {
+			return nil
+		}
+		source = output
+	}
+
+	value, err := ref.path.Lookup(source)
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+// normalize converts the numeric types that can appear in step outputs to
+// float64 so they compare equal to JSON numbers.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	default:
+		return value
+	}
+}
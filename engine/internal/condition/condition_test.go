@@ -0,0 +1,86 @@
+package condition
+
+import (
+	"testing"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/resolver"
+)
+
+func testDefinition() *models.WorkflowDefinition {
+	return &models.WorkflowDefinition{
+		InputParameters: &models.WorkflowParameterDefinitionList{
+			{Name: "amount", Type: "number"},
+			{Name: "country", Type: "string"},
+		},
+		Steps: &models.WorkflowStepDefinitionList{
+			{StepDefinitionID: "check-stock", Type: models.StepTypeTask},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := &resolver.Context{
+		WorkflowInput: map[string]interface{}{"amount": 150.0, "country": "BE"},
+		StepOutputs: map[string]map[string]interface{}{
+			"check-stock": {"available": true, "items": []interface{}{"a", "b"}},
+		},
+	}
+
+	cases := map[string]bool{
+		"input.amount > 100":                                         true,
+		"input.amount >= 150 && input.country == 'BE'":               true,
+		"!(input.country != \"BE\") || false":                        true,
+		"steps.check-stock.available == true":                        true,
+		"steps.check-stock.items[1] == 'b'":                          true,
+		"steps.check-stock.missing == null":                          true,
+		"input.amount < 100 || steps.check-stock.available == false": false,
+	}
+	for source, expected := range cases {
+		expression, err := Parse(source)
+		if err != nil {
+			t.Fatalf("parse %q: %v", source, err)
+		}
+		result, err := expression.Evaluate(ctx)
+		if err != nil {
+			t.Fatalf("evaluate %q: %v", source, err)
+		}
+		if result != expected {
+			t.Fatalf("expected %q to be %v", source, expected)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, source := range []string{"", "input.amount >", "amount > 1", "(input.amount > 1", "input.amount > 'x"} {
+		if _, err := Parse(source); err == nil {
+			t.Fatalf("expected %q to be rejected", source)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	definition := testDefinition()
+
+	valid := []string{"input.amount > 100", "steps.check-stock.available", "input.country == null"}
+	for _, source := range valid {
+		expression, err := Parse(source)
+		if err != nil {
+			t.Fatalf("parse %q: %v", source, err)
+		}
+		if err := expression.Check(definition); err != nil {
+			t.Fatalf("expected %q to check, got %v", source, err)
+		}
+	}
+
+	invalid := []string{"input.amount > 'x'", "input.unknown == 1", "steps.unknown.value == 1", "input.amount", "1 && true"}
+	for _, source := range invalid {
+		expression, err := Parse(source)
+		if err != nil {
+			t.Fatalf("parse %q: %v", source, err)
+		}
+		if err := expression.Check(definition); err == nil {
+			t.Fatalf("expected %q to fail type checking", source)
+		}
+	}
+}
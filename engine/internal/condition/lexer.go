@@ -0,0 +1,133 @@
+package condition
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenDot
+	tokenLBracket
+	tokenRBracket
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!"}
+
+func tokenize(source string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '.':
+			tokens = append(tokens, token{kind: tokenDot, text: ".", pos: i})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenLBracket, text: "[", pos: i})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenRBracket, text: "]", pos: i})
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == '\'' || c == '"':
+			value, end, err := readString(source, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: source[i:end], value: value, pos: i})
+			i = end
+		case isDigit(c) || (c == '-' && i+1 < len(source) && isDigit(source[i+1])):
+			end := i + 1
+			for end < len(source) && (isDigit(source[end]) || source[end] == '.') {
+				end++
+			}
+			value, err := strconv.ParseFloat(source[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid number %q at position %d", errors.ErrInvalidCondition, source[i:end], i)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[i:end], value: value, pos: i})
+			i = end
+		case isIdentStart(c):
+			end := i + 1
+			for end < len(source) && isIdentPart(source[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[i:end], pos: i})
+			i = end
+		default:
+			op := matchOperator(source[i:])
+			if op == "" {
+				return nil, fmt.Errorf("%w: unexpected character %q at position %d", errors.ErrInvalidCondition, c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+func readString(source string, start int) (string, int, error) {
+	quote := source[start]
+	var sb strings.Builder
+	for i := start + 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			if i+1 >= len(source) {
+				break
+			}
+			i++
+			sb.WriteByte(source[i])
+		case quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(source[i])
+		}
+	}
+	return "", 0, fmt.Errorf("%w: unterminated string at position %d", errors.ErrInvalidCondition, start)
+}
+
+func matchOperator(source string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(source, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '-'
+}
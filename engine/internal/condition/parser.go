@@ -0,0 +1,225 @@
+package condition
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/resolver"
+)
+
+const (
+	rootInput = "input"
+	rootSteps = "steps"
+)
+
+type node interface{}
+
+type literalNode struct {
+	value interface{}
+}
+
+// referenceNode reads a value from the workflow input ("input.<path>") or
+// from the output of a completed step ("steps.<stepId>.<path>").
+type referenceNode struct {
+	root   string
+	stepID string
+	path   resolver.Path
+}
+
+func (r *referenceNode) String() string {
+	text := r.root
+	if r.stepID != "" {
+		text += "." + r.stepID
+	}
+	return text + strings.TrimPrefix(r.path.String(), "$")
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at position %d", errors.ErrInvalidCondition, fmt.Sprintf(format, args...), t.pos)
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		op := p.next().text
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		op := p.next().text
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isOperator("!") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "!", operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOperator("==", "!=", "<", "<=", ">", ">=") {
+		op := p.next().text
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected )")
+		}
+		return inner, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		case rootInput, rootSteps:
+			return p.parseReference(t)
+		default:
+			return nil, p.errorf(t, "unknown identifier %q, references must start with %q or %q", t.text, rootInput, rootSteps)
+		}
+	case tokenEOF:
+		return nil, p.errorf(t, "unexpected end of condition")
+	default:
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+}
+
+func (p *parser) parseReference(root token) (node, error) {
+	ref := &referenceNode{root: root.text, path: make(resolver.Path, 0)}
+
+	if root.text == rootSteps {
+		if t := p.next(); t.kind != tokenDot {
+			return nil, p.errorf(t, "expected . after %q", rootSteps)
+		}
+		id := p.next()
+		if id.kind != tokenIdent {
+			return nil, p.errorf(id, "expected step ID")
+		}
+		ref.stepID = id.text
+	}
+
+	for {
+		switch p.peek().kind {
+		case tokenDot:
+			p.next()
+			key := p.next()
+			if key.kind != tokenIdent {
+				return nil, p.errorf(key, "expected key after .")
+			}
+			ref.path = append(ref.path, resolver.PathSegment{Key: key.text})
+		case tokenLBracket:
+			p.next()
+			segment := p.next()
+			switch segment.kind {
+			case tokenString:
+				ref.path = append(ref.path, resolver.PathSegment{Key: segment.value.(string)})
+			case tokenNumber:
+				index := segment.value.(float64)
+				if index < 0 || index != float64(int(index)) {
+					return nil, p.errorf(segment, "invalid index %s", segment.text)
+				}
+				ref.path = append(ref.path, resolver.PathSegment{Index: int(index), IsIndex: true})
+			default:
+				return nil, p.errorf(segment, "expected index or quoted key")
+			}
+			if closing := p.next(); closing.kind != tokenRBracket {
+				return nil, p.errorf(closing, "expected ]")
+			}
+		default:
+			if ref.root == rootInput && len(ref.path) == 0 {
+				return nil, p.errorf(p.peek(), "expected input parameter name")
+			}
+			return ref, nil
+		}
+	}
+}
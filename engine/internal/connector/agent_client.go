@@ -0,0 +1,67 @@
+package connector
+
+import (
+	"context"
+	"time"
+
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// pingTimeout bounds how long a ping waits for an agent, so an unresponsive
+// agent is reported as such instead of blocking its caller.
+const pingTimeout = 5 * time.Second
+
+// taskCallTimeout bounds how long a task call waits for an agent. Steps call
+// agents while they are cancelled, paused or timed out, which must not hang
+// on an agent that stopped answering.
+var taskCallTimeout = 10 * time.Second
+
+// agentClient implements the calls of an AgentConnector with the agent
+// service client, whatever the transport it uses.
+type agentClient struct {
+	client proto.AgentServiceClient
+}
+
+func (a *agentClient) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	_, err := a.client.Ping(ctx, &emptypb.Empty{})
+	return err
+}
+
+func (a *agentClient) StartTask(req *proto.StartTaskRequest) (*proto.TaskActionResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), taskCallTimeout)
+	defer cancel()
+
+	return a.client.StartTask(ctx, req)
+}
+
+func (a *agentClient) StopTask(req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), taskCallTimeout)
+	defer cancel()
+
+	return a.client.StopTask(ctx, req)
+}
+
+func (a *agentClient) PauseTask(req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), taskCallTimeout)
+	defer cancel()
+
+	return a.client.PauseTask(ctx, req)
+}
+
+func (a *agentClient) ResumeTask(req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), taskCallTimeout)
+	defer cancel()
+
+	return a.client.ResumeTask(ctx, req)
+}
+
+func (a *agentClient) GetTaskStatus(req *proto.TaskActionRequest) (*proto.GetTaskStatusResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), taskCallTimeout)
+	defer cancel()
+
+	return a.client.GetTaskStatus(ctx, req)
+}
@@ -10,12 +10,18 @@ type AgentConnector interface {
 	Close() error
 	Ping() error
 	StartTask(req *proto.StartTaskRequest) (*proto.TaskActionResponse, error)
+	StopTask(req *proto.TaskActionRequest) (*proto.TaskActionResponse, error)
+	PauseTask(req *proto.TaskActionRequest) (*proto.TaskActionResponse, error)
+	ResumeTask(req *proto.TaskActionRequest) (*proto.TaskActionResponse, error)
+	GetTaskStatus(req *proto.TaskActionRequest) (*proto.GetTaskStatusResponse, error)
 }
 
 func NewAgentConnector(protocol proto.AgentProtocol, address *string, port string) (AgentConnector, error) {
 	switch protocol {
 	case proto.AGENT_PROTOCOL_GRPC:
 		return NewGrpcAgentConnector(address, port)
+	case proto.AGENT_PROTOCOL_HTTP:
+		return NewHttpAgentConnector(address, port), nil
 	default:
 		return nil, errors.New("unsupported protocol")
 	}
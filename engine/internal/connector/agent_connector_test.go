@@ -0,0 +1,186 @@
+package connector
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeAgent answers the task calls of the engine, recording the actions it
+// receives. Calls on the task "hang" block until the call is cancelled.
+type fakeAgent struct {
+	proto.UnimplementedAgentServiceServer
+
+	mu      sync.Mutex
+	actions []string
+}
+
+func (a *fakeAgent) record(action string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.actions = append(a.actions, action)
+}
+
+func (a *fakeAgent) recorded() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.actions...)
+}
+
+func (a *fakeAgent) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+func (a *fakeAgent) StartTask(_ context.Context, req *proto.StartTaskRequest) (*proto.TaskActionResponse, error) {
+	if req.TaskName != "echo" {
+		return nil, status.Errorf(codes.NotFound, "task definition %s not found", req.TaskName)
+	}
+	a.record("start " + req.TaskName + " " + req.InputParameters.AsMap()["message"].(string))
+	return &proto.TaskActionResponse{TaskId: "task-1", Success: true}, nil
+}
+
+func (a *fakeAgent) GetTaskStatus(ctx context.Context, req *proto.TaskActionRequest) (*proto.GetTaskStatusResponse, error) {
+	if err := a.find(ctx, req.TaskId); err != nil {
+		return nil, err
+	}
+	output, _ := structpb.NewStruct(map[string]interface{}{"message": "hello"})
+	return &proto.GetTaskStatusResponse{TaskId: req.TaskId, Status: proto.TaskStatus_COMPLETED, Progress: 100, Output: output}, nil
+}
+
+func (a *fakeAgent) StopTask(ctx context.Context, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	return a.act(ctx, "stop", req)
+}
+
+func (a *fakeAgent) PauseTask(ctx context.Context, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	return a.act(ctx, "pause", req)
+}
+
+func (a *fakeAgent) ResumeTask(ctx context.Context, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	return a.act(ctx, "resume", req)
+}
+
+func (a *fakeAgent) act(ctx context.Context, action string, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	if err := a.find(ctx, req.TaskId); err != nil {
+		return nil, err
+	}
+	if action == "resume" {
+		message := "task is not paused"
+		return &proto.TaskActionResponse{TaskId: req.TaskId, Success: false, Message: &message}, nil
+	}
+	a.record(action + " " + req.TaskId)
+	return &proto.TaskActionResponse{TaskId: req.TaskId, Success: true}, nil
+}
+
+func (a *fakeAgent) find(ctx context.Context, taskID string) error {
+	switch taskID {
+	case "task-1":
+		return nil
+	case "hang":
+		<-ctx.Done()
+		return ctx.Err()
+	default:
+		return status.Errorf(codes.NotFound, "task %s not found", taskID)
+	}
+}
+
+// agentTransports serve the fake agent with each protocol and return the
+// connector of the engine to it.
+var agentTransports = map[proto.AgentProtocol]func(t *testing.T, agent *fakeAgent) AgentConnector{
+	proto.AGENT_PROTOCOL_GRPC: func(t *testing.T, agent *fakeAgent) AgentConnector {
+		srv := grpc.NewServer()
+		proto.RegisterAgentServiceServer(srv, agent)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go srv.Serve(listener)
+		t.Cleanup(srv.Stop)
+
+		host, port, _ := net.SplitHostPort(listener.Addr().String())
+		return newTestConnector(t, proto.AGENT_PROTOCOL_GRPC, host, port)
+	},
+	proto.AGENT_PROTOCOL_HTTP: func(t *testing.T, agent *fakeAgent) AgentConnector {
+		mux := http.NewServeMux()
+		proto.RegisterHttpService(mux, &proto.AgentService_ServiceDesc, agent)
+		srv := httptest.NewServer(mux)
+		t.Cleanup(srv.Close)
+
+		address, _ := url.Parse(srv.URL)
+		return newTestConnector(t, proto.AGENT_PROTOCOL_HTTP, address.Hostname(), address.Port())
+	},
+}
+
+func newTestConnector(t *testing.T, protocol proto.AgentProtocol, host, port string) AgentConnector {
+	agentConnector, err := NewAgentConnector(protocol, &host, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = agentConnector.Close() })
+	return agentConnector
+}
+
+// TestAgentConnectorContract checks that the engine reaches agents the same
+// way whatever the protocol they registered with.
+func TestAgentConnectorContract(t *testing.T) {
+	timeout := taskCallTimeout
+	taskCallTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { taskCallTimeout = timeout })
+
+	for protocol, serve := range agentTransports {
+		t.Run(protocol.String(), func(t *testing.T) {
+			agent := &fakeAgent{}
+			agentConnector := serve(t, agent)
+
+			if err := agentConnector.Ping(); err != nil {
+				t.Fatalf("expected the agent to answer the ping, got %v", err)
+			}
+
+			input, _ := structpb.NewStruct(map[string]interface{}{"message": "hello"})
+			started, err := agentConnector.StartTask(&proto.StartTaskRequest{TaskName: "echo", InputParameters: input})
+			if err != nil || !started.Success || started.TaskId != "task-1" {
+				t.Fatalf("expected the task to start, got %v, %v", started, err)
+			}
+
+			taskStatus, err := agentConnector.GetTaskStatus(&proto.TaskActionRequest{TaskId: "task-1"})
+			if err != nil || taskStatus.Status != proto.TaskStatus_COMPLETED || taskStatus.Output.AsMap()["message"] != "hello" {
+				t.Fatalf("expected the status of the task, got %v, %v", taskStatus, err)
+			}
+
+			for _, action := range []func(*proto.TaskActionRequest) (*proto.TaskActionResponse, error){agentConnector.PauseTask, agentConnector.StopTask} {
+				if res, err := action(&proto.TaskActionRequest{TaskId: "task-1"}); err != nil || !res.Success {
+					t.Fatalf("expected the action to be applied, got %v, %v", res, err)
+				}
+			}
+			if res, err := agentConnector.ResumeTask(&proto.TaskActionRequest{TaskId: "task-1"}); err != nil || res.Success || res.GetMessage() != "task is not paused" {
+				t.Fatalf("expected the refusal of the agent to be returned, got %v, %v", res, err)
+			}
+			if got := agent.recorded(); len(got) != 3 || got[0] != "start echo hello" || got[1] != "pause task-1" || got[2] != "stop task-1" {
+				t.Fatalf("expected the agent to receive the calls in order, got %v", got)
+			}
+
+			if _, err := agentConnector.StartTask(&proto.StartTaskRequest{TaskName: "missing"}); status.Code(err) != codes.NotFound || status.Convert(err).Message() != "task definition missing not found" {
+				t.Fatalf("expected the error of the agent to be returned, got %v", err)
+			}
+
+			begin := time.Now()
+			if _, err := agentConnector.StopTask(&proto.TaskActionRequest{TaskId: "hang"}); status.Code(err) != codes.DeadlineExceeded {
+				t.Fatalf("expected a call to an unresponsive agent to time out, got %v", err)
+			}
+			if elapsed := time.Since(begin); elapsed > 5*taskCallTimeout {
+				t.Fatalf("expected the call to give up after %s, took %s", taskCallTimeout, elapsed)
+			}
+		})
+	}
+}
@@ -1,17 +1,17 @@
 package connector
 
 import (
-	"context"
 	"errors"
 	"net"
 
 	"github.com/paulhalleux/workflow-engine-go/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 type GrpcAgentConnector struct {
+	agentClient
+
 	address *string
 	port    string
 
@@ -25,9 +25,10 @@ func NewGrpcAgentConnector(address *string, port string) (*GrpcAgentConnector, e
 	}
 
 	return &GrpcAgentConnector{
-		address:    address,
-		port:       port,
-		connection: connection,
+		agentClient: agentClient{client: proto.NewAgentServiceClient(connection)},
+		address:     address,
+		port:        port,
+		connection:  connection,
 	}, nil
 }
 
@@ -38,18 +39,6 @@ func (g *GrpcAgentConnector) Close() error {
 	return nil
 }
 
-func (g *GrpcAgentConnector) Ping() error {
-	client := proto.NewAgentServiceClient(g.connection)
-	_, err := client.Ping(context.Background(), &emptypb.Empty{})
-	return err
-}
-
-func (g *GrpcAgentConnector) StartTask(req *proto.StartTaskRequest) (*proto.TaskActionResponse, error) {
-	client := proto.NewAgentServiceClient(g.connection)
-	res, err := client.StartTask(context.Background(), req)
-	return res, err
-}
-
 func joinHostPort(host *string, port string) string {
 	if host != nil {
 		return net.JoinHostPort(*host, port)
@@ -0,0 +1,27 @@
+package connector
+
+import (
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+// HttpAgentConnector reaches an agent with the HTTP transport, whose JSON
+// payloads mirror the proto messages.
+type HttpAgentConnector struct {
+	agentClient
+
+	address *string
+	port    string
+}
+
+func NewHttpAgentConnector(address *string, port string) *HttpAgentConnector {
+	connection := proto.NewHttpClientConn("http://" + joinHostPort(address, port))
+	return &HttpAgentConnector{
+		agentClient: agentClient{client: proto.NewAgentServiceClient(connection)},
+		address:     address,
+		port:        port,
+	}
+}
+
+func (h *HttpAgentConnector) Close() error {
+	return nil
+}
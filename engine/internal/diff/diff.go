@@ -0,0 +1,397 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/utils/semver"
+)
+
+// Bump is the semver increment a change warrants.
+type Bump string // @name DiffBump
+
+const (
+	BumpNone  Bump = "none"
+	BumpPatch Bump = "patch"
+	BumpMinor Bump = "minor"
+	BumpMajor Bump = "major"
+)
+
+var bumpRanks = map[Bump]int{BumpNone: 0, BumpPatch: 1, BumpMinor: 2, BumpMajor: 3}
+
+// Change is a difference between two workflow definitions. StepID names the
+// changed step, and Field the JSON path of the changed field within the step,
+// or within the definition when StepID is empty. From and To hold the values
+// before and after the change, and are omitted when absent.
+type Change struct {
+	StepID   string      `json:"stepId,omitempty"`
+	Field    string      `json:"field,omitempty"`
+	From     interface{} `json:"from,omitempty"`
+	To       interface{} `json:"to,omitempty"`
+	Bump     Bump        `json:"bump"`
+	Breaking bool        `json:"breaking"`
+	Message  string      `json:"message"`
+} // @name WorkflowDefinitionChange
+
+// DefinitionDiff lists the changes between two workflow definitions, grouped
+// by kind, with the version bump they warrant together.
+type DefinitionDiff struct {
+	FromVersion             string   `json:"fromVersion"`
+	ToVersion               string   `json:"toVersion"`
+	AddedSteps              []Change `json:"addedSteps"`
+	RemovedSteps            []Change `json:"removedSteps"`
+	ChangedSteps            []Change `json:"changedSteps"`
+	RewiredEdges            []Change `json:"rewiredEdges"`
+	ChangedParameters       []Change `json:"changedParameters"`
+	ChangedInputParameters  []Change `json:"changedInputParameters"`
+	ChangedOutputParameters []Change `json:"changedOutputParameters"`
+	Breaking                bool     `json:"breaking"`
+	SuggestedBump           Bump     `json:"suggestedBump"`
+	// SuggestedVersion is the version of the first definition bumped by
+	// SuggestedBump.
+	SuggestedVersion string `json:"suggestedVersion"`
+} // @name WorkflowDefinitionDiff
+
+// Compare returns the changes turning one workflow definition into another.
+//
+// Changes are classified from the point of view of the callers of the
+// workflow and of the instances it runs: removing steps or inputs and outputs,
+// requiring new inputs, changing their types or the work a step performs are
+// breaking and warrant a major bump. Adding steps and optional inputs or
+// outputs, and changing the wiring, parameters and branching of the steps
+// warrant a minor bump. Other changes, such as names, descriptions, timeouts
+// and retry policies, warrant a patch bump.
+func Compare(from, to *models.WorkflowDefinition) *DefinitionDiff {
+	d := &DefinitionDiff{
+		FromVersion:             from.Version,
+		ToVersion:               to.Version,
+		AddedSteps:              make([]Change, 0),
+		RemovedSteps:            make([]Change, 0),
+		ChangedSteps:            make([]Change, 0),
+		RewiredEdges:            make([]Change, 0),
+		ChangedParameters:       make([]Change, 0),
+		ChangedInputParameters:  make([]Change, 0),
+		ChangedOutputParameters: make([]Change, 0),
+	}
+
+	compareSteps(d, stepsOf(from), stepsOf(to))
+	compareParameterDefinitions(&d.ChangedInputParameters, "inputParameters", inputsOf(from), inputsOf(to), true)
+	compareOutputs(d, from.OutputParameters, to.OutputParameters)
+
+	d.SuggestedBump = BumpNone
+	for _, group := range [][]Change{d.AddedSteps, d.RemovedSteps, d.ChangedSteps, d.RewiredEdges, d.ChangedParameters, d.ChangedInputParameters, d.ChangedOutputParameters} {
+		for _, change := range group {
+			if bumpRanks[change.Bump] > bumpRanks[d.SuggestedBump] {
+				d.SuggestedBump = change.Bump
+			}
+		}
+	}
+	d.Breaking = d.SuggestedBump == BumpMajor
+	d.SuggestedVersion = suggestVersion(from.Version, d.SuggestedBump)
+	return d
+}
+
+func suggestVersion(version string, bump Bump) string {
+	var next string
+	var err error
+	switch bump {
+	case BumpPatch:
+		next, err = semver.BumpPatch(version)
+	case BumpMinor:
+		next, err = semver.BumpMinor(version)
+	case BumpMajor:
+		next, err = semver.BumpMajor(version)
+	default:
+		return semver.Ensure(version)
+	}
+	if err != nil {
+		return semver.Ensure(version)
+	}
+	return next
+}
+
+func change(stepID, field string, from, to interface{}, bump Bump, format string, args ...interface{}) Change {
+	return Change{
+		StepID:   stepID,
+		Field:    field,
+		From:     from,
+		To:       to,
+		Bump:     bump,
+		Breaking: bump == BumpMajor,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+func stepsOf(definition *models.WorkflowDefinition) []models.WorkflowStepDefinition {
+	if definition.Steps == nil {
+		return nil
+	}
+	return *definition.Steps
+}
+
+func inputsOf(definition *models.WorkflowDefinition) models.WorkflowParameterDefinitionList {
+	if definition.InputParameters == nil {
+		return nil
+	}
+	return *definition.InputParameters
+}
+
+func compareSteps(d *DefinitionDiff, from, to []models.WorkflowStepDefinition) {
+	previous := make(map[string]models.WorkflowStepDefinition, len(from))
+	for _, step := range from {
+		previous[step.StepDefinitionID] = step
+	}
+	current := make(map[string]struct{}, len(to))
+
+	for _, step := range to {
+		current[step.StepDefinitionID] = struct{}{}
+		old, exists := previous[step.StepDefinitionID]
+		if !exists {
+			d.AddedSteps = append(d.AddedSteps, change(step.StepDefinitionID, "", nil, step, BumpMinor, "step added"))
+			continue
+		}
+		compareStep(d, old, step)
+	}
+
+	for _, step := range from {
+		if _, exists := current[step.StepDefinitionID]; !exists {
+			d.RemovedSteps = append(d.RemovedSteps, change(step.StepDefinitionID, "", step, nil, BumpMajor, "step removed"))
+		}
+	}
+}
+
+// fieldChange is a field of a step that may have changed, with the bump a
+// change warrants.
+type fieldChange struct {
+	field    string
+	from, to interface{}
+	bump     Bump
+}
+
+func compareStep(d *DefinitionDiff, from, to models.WorkflowStepDefinition) {
+	id := to.StepDefinitionID
+	if from.Type != to.Type {
+		d.ChangedSteps = append(d.ChangedSteps, change(id, "type", from.Type, to.Type, BumpMajor, "type changed from %s to %s", from.Type, to.Type))
+		return
+	}
+
+	fields := []fieldChange{
+		{"name", from.Name, to.Name, BumpPatch},
+		{"description", from.Description, to.Description, BumpPatch},
+		{"metadata", from.Metadata, to.Metadata, BumpPatch},
+		{"timeoutSeconds", from.TimeoutSeconds, to.TimeoutSeconds, BumpPatch},
+		{"retryCount", from.RetryCount, to.RetryCount, BumpPatch},
+		{"retryPolicy", from.RetryPolicy, to.RetryPolicy, BumpPatch},
+	}
+	switch to.Type {
+	case models.StepTypeTask:
+		if from.TaskConfig != nil && to.TaskConfig != nil {
+			fields = append(fields, fieldChange{"taskConfig.taskDefinitionId", from.TaskConfig.TaskDefinitionID, to.TaskConfig.TaskDefinitionID, BumpMajor})
+		}
+	case models.StepTypeWorkflow:
+		if from.WorkflowConfig != nil && to.WorkflowConfig != nil {
+			fields = append(fields, fieldChange{"workflowConfig.workflowDefinitionId", from.WorkflowConfig.WorkflowDefinitionID, to.WorkflowConfig.WorkflowDefinitionID, BumpMajor})
+		}
+	case models.StepTypeWait:
+		if from.WaitConfig != nil && to.WaitConfig != nil {
+			fields = append(fields, fieldChange{"waitConfig.durationSeconds", from.WaitConfig.DurationSeconds, to.WaitConfig.DurationSeconds, BumpPatch})
+		}
+	case models.StepTypeDecision:
+		if from.DecisionConfig != nil && to.DecisionConfig != nil {
+			for i := 0; i < len(from.DecisionConfig.Cases) && i < len(to.DecisionConfig.Cases); i++ {
+				fields = append(fields, fieldChange{fmt.Sprintf("decisionConfig.cases[%d].condition", i), from.DecisionConfig.Cases[i].Condition, to.DecisionConfig.Cases[i].Condition, BumpMinor})
+			}
+		}
+	case models.StepTypeJoin:
+		if from.JoinConfig != nil && to.JoinConfig != nil {
+			fields = append(fields, fieldChange{"joinConfig.policy", from.JoinConfig.GetPolicy(), to.JoinConfig.GetPolicy(), BumpMinor}, fieldChange{"joinConfig.requiredCount", from.JoinConfig.RequiredCount, to.JoinConfig.RequiredCount, BumpMinor})
+		}
+	}
+
+	for _, f := range fields {
+		if !equal(f.from, f.to) {
+			d.ChangedSteps = append(d.ChangedSteps, change(id, f.field, f.from, f.to, f.bump, "%s changed", f.field))
+		}
+	}
+
+	compareEdges(d, from, to)
+	compareStepParameters(d, from, to)
+}
+
+// compareEdges reports the references to other steps that changed target,
+// appeared or disappeared, such as the next step of a task or the branches of
+// a fork.
+func compareEdges(d *DefinitionDiff, from, to models.WorkflowStepDefinition) {
+	previous := make(map[string]string)
+	for _, ref := range from.GetStepReferences() {
+		previous[ref.Field] = ref.StepID
+	}
+	current := make(map[string]struct{})
+
+	id := to.StepDefinitionID
+	for _, ref := range to.GetStepReferences() {
+		current[ref.Field] = struct{}{}
+		old, exists := previous[ref.Field]
+		switch {
+		case !exists:
+			d.RewiredEdges = append(d.RewiredEdges, change(id, ref.Field, nil, ref.StepID, BumpMinor, "now leads to step %s", ref.StepID))
+		case old != ref.StepID:
+			d.RewiredEdges = append(d.RewiredEdges, change(id, ref.Field, old, ref.StepID, BumpMinor, "leads to step %s instead of step %s", ref.StepID, old))
+		}
+	}
+	for _, ref := range from.GetStepReferences() {
+		if _, exists := current[ref.Field]; !exists {
+			d.RewiredEdges = append(d.RewiredEdges, change(id, ref.Field, ref.StepID, nil, BumpMinor, "no longer leads to step %s", ref.StepID))
+		}
+	}
+}
+
+func compareStepParameters(d *DefinitionDiff, from, to models.WorkflowStepDefinition) {
+	previous := models.StepDefinitionParameters{}
+	if from.Parameters != nil {
+		previous = *from.Parameters
+	}
+	current := models.StepDefinitionParameters{}
+	if to.Parameters != nil {
+		current = *to.Parameters
+	}
+
+	id := to.StepDefinitionID
+	for _, name := range sortedKeys(previous, current) {
+		field := "parameters." + name
+		old, existed := previous[name]
+		param, exists := current[name]
+		switch {
+		case !existed:
+			d.ChangedParameters = append(d.ChangedParameters, change(id, field, nil, param, BumpMinor, "parameter %s added", name))
+		case !exists:
+			d.ChangedParameters = append(d.ChangedParameters, change(id, field, old, nil, BumpMinor, "parameter %s removed", name))
+		case !equal(old, param):
+			d.ChangedParameters = append(d.ChangedParameters, change(id, field, old, param, BumpMinor, "parameter %s changed", name))
+		}
+	}
+}
+
+func sortedKeys(maps ...models.StepDefinitionParameters) []string {
+	keys := make([]string, 0)
+	for _, m := range maps {
+		for key := range m {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// compareParameterDefinitions compares the input or output parameters of two
+// definitions by name. New inputs break the callers when they are required
+// without a default value, while new outputs never do.
+func compareParameterDefinitions(changes *[]Change, field string, from, to models.WorkflowParameterDefinitionList, isInput bool) {
+	previous := make(map[string]models.WorkflowParameterDefinition, len(from))
+	for _, param := range from {
+		previous[param.Name] = param
+	}
+	current := make(map[string]struct{}, len(to))
+
+	for _, param := range to {
+		current[param.Name] = struct{}{}
+		paramField := field + "." + param.Name
+		old, exists := previous[param.Name]
+		if !exists {
+			bump := BumpMinor
+			if isInput && isMandatory(param) {
+				bump = BumpMajor
+			}
+			*changes = append(*changes, change("", paramField, nil, param, bump, "parameter %s added", param.Name))
+			continue
+		}
+
+		if old.Type != param.Type {
+			*changes = append(*changes, change("", paramField+".type", old.Type, param.Type, BumpMajor, "parameter %s type changed from %s to %s", param.Name, old.Type, param.Type))
+		}
+		if isInput && isMandatory(old) != isMandatory(param) {
+			bump := BumpMinor
+			if isMandatory(param) {
+				bump = BumpMajor
+			}
+			*changes = append(*changes, change("", paramField+".required", old.Required, param.Required, bump, "parameter %s required changed", param.Name))
+		} else if old.Required != param.Required {
+			*changes = append(*changes, change("", paramField+".required", old.Required, param.Required, BumpPatch, "parameter %s required changed", param.Name))
+		}
+		for _, f := range []struct {
+			name     string
+			from, to interface{}
+		}{
+			{"default", old.Default, param.Default},
+			{"description", old.Description, param.Description},
+			{"metadata", old.Metadata, param.Metadata},
+		} {
+			if !equal(f.from, f.to) {
+				*changes = append(*changes, change("", paramField+"."+f.name, f.from, f.to, BumpPatch, "parameter %s %s changed", param.Name, f.name))
+			}
+		}
+	}
+
+	for _, param := range from {
+		if _, exists := current[param.Name]; !exists {
+			*changes = append(*changes, change("", field+"."+param.Name, param, nil, BumpMajor, "parameter %s removed", param.Name))
+		}
+	}
+}
+
+// isMandatory reports whether the callers must provide an input parameter.
+func isMandatory(param models.WorkflowParameterDefinition) bool {
+	return param.Required && param.Default == nil
+}
+
+// compareOutputs compares the output parameters of two definitions, by name
+// when both are lists of parameter definitions, and as a whole otherwise.
+func compareOutputs(d *DefinitionDiff, from, to *interface{}) {
+	previous, previousOk := outputList(from)
+	current, currentOk := outputList(to)
+	if previousOk && currentOk {
+		compareParameterDefinitions(&d.ChangedOutputParameters, "outputParameters", previous, current, false)
+		return
+	}
+	if !equal(from, to) {
+		d.ChangedOutputParameters = append(d.ChangedOutputParameters, change("", "outputParameters", from, to, BumpMajor, "output parameters changed"))
+	}
+}
+
+// outputList decodes output parameters as a list of parameter definitions.
+// Missing output parameters are an empty list.
+func outputList(outputs *interface{}) (models.WorkflowParameterDefinitionList, bool) {
+	if outputs == nil || *outputs == nil {
+		return nil, true
+	}
+	bytes, err := json.Marshal(*outputs)
+	if err != nil {
+		return nil, false
+	}
+	var list models.WorkflowParameterDefinitionList
+	if err := json.Unmarshal(bytes, &list); err != nil {
+		return nil, false
+	}
+	for _, param := range list {
+		if param.Name == "" {
+			return nil, false
+		}
+	}
+	return list, true
+}
+
+// equal compares two values as they would be stored, so that nil and empty
+// pointers compare alike.
+func equal(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	aBytes, aErr := json.Marshal(a)
+	bBytes, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aBytes) == string(bBytes)
+}
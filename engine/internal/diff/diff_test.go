@@ -0,0 +1,128 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+func taskStep(id string, next *string) models.WorkflowStepDefinition {
+	return models.WorkflowStepDefinition{
+		StepDefinitionID: id,
+		Name:             id,
+		Type:             models.StepTypeTask,
+		TaskConfig:       &models.TaskConfig{TaskDefinitionID: "echo", NextStepID: next},
+	}
+}
+
+func definitionOf(version string, inputs models.WorkflowParameterDefinitionList, steps ...models.WorkflowStepDefinition) *models.WorkflowDefinition {
+	list := models.WorkflowStepDefinitionList(steps)
+	return &models.WorkflowDefinition{Version: version, InputParameters: &inputs, Steps: &list}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func expectChanges(t *testing.T, group string, changes []Change, expected ...Change) {
+	t.Helper()
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d %s, got %+v", len(expected), group, changes)
+	}
+	for i, want := range expected {
+		if got := changes[i]; got.StepID != want.StepID || got.Field != want.Field || got.Bump != want.Bump {
+			t.Fatalf("expected %s %d on step %q field %q with a %s bump, got %+v", group, i, want.StepID, want.Field, want.Bump, got)
+		}
+	}
+}
+
+func TestCompareNonBreakingChanges(t *testing.T) {
+	inputs := models.WorkflowParameterDefinitionList{{Name: "orderId", Type: "string", Required: true}}
+	from := definitionOf("1.2.0", inputs,
+		taskStep("fetch", ptr("notify")),
+		taskStep("notify", nil),
+	)
+
+	notify := taskStep("notify", nil)
+	notify.Name = "Notify customer"
+	notify.Parameters = &models.StepDefinitionParameters{
+		"orderId": {Type: models.StepParameterTypeWorkflow, Value: "orderId"},
+	}
+	to := definitionOf("1.3.0-1", append(inputs, models.WorkflowParameterDefinition{Name: "express", Type: "boolean", Required: true, Default: false}),
+		taskStep("fetch", ptr("reserve")),
+		taskStep("reserve", ptr("notify")),
+		notify,
+	)
+
+	d := Compare(from, to)
+	expectChanges(t, "added steps", d.AddedSteps, Change{StepID: "reserve", Bump: BumpMinor})
+	expectChanges(t, "removed steps", d.RemovedSteps)
+	expectChanges(t, "changed steps", d.ChangedSteps, Change{StepID: "notify", Field: "name", Bump: BumpPatch})
+	expectChanges(t, "rewired edges", d.RewiredEdges, Change{StepID: "fetch", Field: "taskConfig.nextStepId", Bump: BumpMinor})
+	expectChanges(t, "changed parameters", d.ChangedParameters, Change{StepID: "notify", Field: "parameters.orderId", Bump: BumpMinor})
+	expectChanges(t, "changed input parameters", d.ChangedInputParameters, Change{Field: "inputParameters.express", Bump: BumpMinor})
+
+	if d.Breaking || d.SuggestedBump != BumpMinor || d.SuggestedVersion != "1.3.0" {
+		t.Fatalf("expected a non-breaking minor bump to 1.3.0, got %v %s %s", d.Breaking, d.SuggestedBump, d.SuggestedVersion)
+	}
+}
+
+func TestCompareBreakingChanges(t *testing.T) {
+	from := definitionOf("1.2.0", models.WorkflowParameterDefinitionList{
+		{Name: "orderId", Type: "string", Required: true},
+		{Name: "note", Type: "string"},
+	},
+		taskStep("fetch", ptr("notify")),
+		taskStep("notify", nil),
+	)
+
+	fetch := taskStep("fetch", nil)
+	fetch.TaskConfig.TaskDefinitionID = "fetch-order"
+	to := definitionOf("1.2.1-1", models.WorkflowParameterDefinitionList{
+		{Name: "orderId", Type: "integer", Required: true},
+		{Name: "customerId", Type: "string", Required: true},
+	}, fetch)
+
+	d := Compare(from, to)
+	expectChanges(t, "removed steps", d.RemovedSteps, Change{StepID: "notify", Bump: BumpMajor})
+	expectChanges(t, "changed steps", d.ChangedSteps, Change{StepID: "fetch", Field: "taskConfig.taskDefinitionId", Bump: BumpMajor})
+	expectChanges(t, "rewired edges", d.RewiredEdges, Change{StepID: "fetch", Field: "taskConfig.nextStepId", Bump: BumpMinor})
+	expectChanges(t, "changed input parameters", d.ChangedInputParameters,
+		Change{Field: "inputParameters.orderId.type", Bump: BumpMajor},
+		Change{Field: "inputParameters.customerId", Bump: BumpMajor},
+		Change{Field: "inputParameters.note", Bump: BumpMajor},
+	)
+
+	if !d.Breaking || d.SuggestedBump != BumpMajor || d.SuggestedVersion != "2.0.0" {
+		t.Fatalf("expected a breaking major bump to 2.0.0, got %v %s %s", d.Breaking, d.SuggestedBump, d.SuggestedVersion)
+	}
+}
+
+func TestCompareOutputs(t *testing.T) {
+	var before interface{} = []interface{}{
+		map[string]interface{}{"name": "total", "type": "number"},
+		map[string]interface{}{"name": "currency", "type": "string"},
+	}
+	var after interface{} = []interface{}{
+		map[string]interface{}{"name": "total", "type": "number"},
+		map[string]interface{}{"name": "receiptUrl", "type": "string"},
+	}
+	from := definitionOf("1.0.0", nil, taskStep("a", nil))
+	from.OutputParameters = &before
+	to := definitionOf("1.0.1-1", nil, taskStep("a", nil))
+	to.OutputParameters = &after
+
+	d := Compare(from, to)
+	expectChanges(t, "changed output parameters", d.ChangedOutputParameters,
+		Change{Field: "outputParameters.receiptUrl", Bump: BumpMinor},
+		Change{Field: "outputParameters.currency", Bump: BumpMajor},
+	)
+}
+
+func TestCompareIdentical(t *testing.T) {
+	from := definitionOf("1.0.0", nil, taskStep("a", nil))
+	d := Compare(from, definitionOf("1.0.1-1", nil, taskStep("a", nil)))
+	if d.SuggestedBump != BumpNone || d.SuggestedVersion != "1.0.0" {
+		t.Fatalf("expected no bump, got %s %s", d.SuggestedBump, d.SuggestedVersion)
+	}
+}
@@ -1,6 +1,8 @@
 package dto
 
 import (
+	"time"
+
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/registry"
 	"github.com/paulhalleux/workflow-engine-go/proto"
 	"github.com/paulhalleux/workflow-engine-go/utils/array"
@@ -12,6 +14,7 @@ type AgentOverviewResponse struct {
 	Protocol string                      `json:"protocol"`
 	Address  *string                     `json:"address,omitempty"`
 	Port     string                      `json:"port"`
+	Status   string                      `json:"status"`
 	Tasks    []AgentTaskOverviewResponse `json:"tasks"`
 } // @name AgentOverviewResponse
 
@@ -22,12 +25,17 @@ type AgentTaskOverviewResponse struct {
 } // @name AgentTaskOverviewResponse
 
 type AgentResponse struct {
-	Name           string              `json:"name"`
-	Version        string              `json:"version"`
-	Protocol       string              `json:"protocol"`
-	Address        *string             `json:"address,omitempty"`
-	Port           string              `json:"port"`
-	SupportedTasks []AgentTaskResponse `json:"supportedTasks"`
+	Name                string              `json:"name"`
+	Version             string              `json:"version"`
+	Protocol            string              `json:"protocol"`
+	Address             *string             `json:"address,omitempty"`
+	Port                string              `json:"port"`
+	Weight              int                 `json:"weight"`
+	Status              string              `json:"status"`
+	LastSeenAt          time.Time           `json:"lastSeenAt"`
+	ConsecutiveFailures int                 `json:"consecutiveFailures"`
+	InFlightTasks       int                 `json:"inFlightTasks"`
+	SupportedTasks      []AgentTaskResponse `json:"supportedTasks"`
 } // @name AgentResponse
 
 type AgentTaskResponse struct {
@@ -42,11 +50,16 @@ func NewAgentResponse(
 	agent *registry.RegisteredAgent,
 ) AgentResponse {
 	return AgentResponse{
-		Name:     agent.Name,
-		Version:  agent.Version,
-		Protocol: agent.Protocol.String(),
-		Address:  agent.Address,
-		Port:     agent.Port,
+		Name:                agent.Name,
+		Version:             agent.Version,
+		Protocol:            agent.Protocol.String(),
+		Address:             agent.Address,
+		Port:                agent.Port,
+		Weight:              agent.Weight,
+		Status:              string(agent.Status),
+		LastSeenAt:          agent.LastSeenAt,
+		ConsecutiveFailures: agent.ConsecutiveFailures,
+		InFlightTasks:       agent.InFlightTasks,
 		SupportedTasks: array.ToMapped(
 			agent.SupportedTasks,
 			func(taskDef *proto.TaskDefinition) AgentTaskResponse {
@@ -77,6 +90,7 @@ func NewAgentOverviewResponse(
 		Protocol: agent.Protocol.String(),
 		Address:  agent.Address,
 		Port:     agent.Port,
+		Status:   string(agent.Status),
 		Tasks: array.ToMapped(
 			agent.SupportedTasks,
 			func(taskDef *proto.TaskDefinition) AgentTaskOverviewResponse {
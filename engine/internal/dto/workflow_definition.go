@@ -0,0 +1,18 @@
+package dto
+
+import "github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+
+type ImportAction string // @name ImportAction
+
+const (
+	ImportActionCreated ImportAction = "created"
+	ImportActionUpdated ImportAction = "updated"
+	ImportActionSkipped ImportAction = "skipped"
+)
+
+type ImportWorkflowDefinitionResponse struct {
+	// Action is what the import did, or would do on a dry run.
+	Action     ImportAction               `json:"action"`
+	DryRun     bool                       `json:"dryRun"`
+	Definition *models.WorkflowDefinition `json:"definition"`
+} // @name ImportWorkflowDefinitionResponse
@@ -0,0 +1,5 @@
+package dto
+
+type CancelWorkflowInstanceRequest struct {
+	Reason string `json:"reason"`
+} // @name CancelWorkflowInstanceRequest
@@ -0,0 +1,45 @@
+package errors
+
+import "errors"
+
+// ErrorCodeTimedOut is the code of failures caused by a step timeout. Retry
+// policies can list it as non-retryable to give up on steps that time out.
+const ErrorCodeTimedOut = "TIMED_OUT"
+
+// ErrorCodeAgentOffline is the code of failures caused by an agent that stopped
+// answering while it was running a task.
+const ErrorCodeAgentOffline = "AGENT_OFFLINE"
+
+// CodedError attaches a machine-readable code to an error, so retry policies
+// can tell failures that must not be retried apart from transient ones.
+type CodedError struct {
+	Code string
+	Err  error
+}
+
+func WithCode(code string, err error) error {
+	if code == "" {
+		return err
+	}
+	return &CodedError{
+		Code: code,
+		Err:  err,
+	}
+}
+
+func (e *CodedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// CodeOf returns the code attached to an error, or an empty string.
+func CodeOf(err error) string {
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return ""
+}
@@ -7,5 +7,35 @@ func (e SimpleError) Error() string {
 }
 
 const (
-	ErrWorkflowDefinitionNoSteps SimpleError = "workflow definition has no steps"
+	ErrWorkflowDefinitionNoSteps  SimpleError = "workflow definition has no steps"
+	ErrWorkflowDefinitionDisabled SimpleError = "workflow definition is disabled"
+	ErrWorkflowDefinitionDraft    SimpleError = "workflow definition is a draft"
+	ErrMissingInputParameter      SimpleError = "missing required input parameter"
+	ErrInvalidInputParameterType  SimpleError = "invalid input parameter type"
+	ErrUnknownInputParameter      SimpleError = "unknown input parameter"
+	ErrWorkflowDefinitionNotFound SimpleError = "workflow definition not found"
+	ErrStepDefinitionNotFound     SimpleError = "step definition not found"
+	ErrInvalidStepTransition      SimpleError = "invalid step instance status transition"
+	ErrNoStepHandler              SimpleError = "no handler registered for step type"
+	ErrNoAgentForTask             SimpleError = "no agent registered for task"
+	ErrInvalidParameterReference  SimpleError = "invalid parameter reference"
+	ErrInvalidParameterPath       SimpleError = "invalid parameter path"
+	ErrParameterPathNotFound      SimpleError = "parameter path not found"
+	ErrStepOutputNotFound         SimpleError = "step output not found"
+	ErrInvalidCondition           SimpleError = "invalid condition"
+	ErrConditionEvaluation        SimpleError = "condition evaluation failed"
+	ErrInvalidWorkflowDefinition  SimpleError = "invalid workflow definition"
+	ErrJoinFailed                 SimpleError = "join can no longer be satisfied"
+	ErrTimerNotFound              SimpleError = "timer not found"
+	ErrTimerNotPending            SimpleError = "timer is not pending"
+	ErrTimerCancelled             SimpleError = "timer was cancelled"
+	ErrStepTimedOut               SimpleError = "step timed out"
+	ErrWorkflowTimedOut           SimpleError = "workflow timed out"
+	ErrWorkflowInstanceNotActive  SimpleError = "workflow instance is not active"
+	ErrWorkflowInstanceNotRunning SimpleError = "workflow instance is not running"
+	ErrWorkflowInstanceNotPaused  SimpleError = "workflow instance is not paused"
+	ErrRecursiveWorkflow          SimpleError = "recursive workflow reference"
+	ErrAgentOffline               SimpleError = "agent is offline"
+	ErrConflictingTaskDefinition  SimpleError = "conflicting task definition"
+	ErrInvalidWorkflowDocument    SimpleError = "invalid workflow definition document"
 )
@@ -2,24 +2,37 @@ package grpcserver
 
 import (
 	"context"
+	"errors"
 
+	"github.com/google/uuid"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/registry"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/scheduler"
 	"github.com/paulhalleux/workflow-engine-go/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 type EngineService struct {
 	proto.UnimplementedEngineServiceServer
 
-	agentRegistry *registry.AgentRegistry
+	agentRegistry          *registry.AgentRegistry
+	workflowDefinitionRepo persistance.WorkflowDefinitionRepository
+	scheduler              *scheduler.Scheduler
 }
 
 func NewEngineService(
 	agentRegistry *registry.AgentRegistry,
+	workflowDefinitionRepo persistance.WorkflowDefinitionRepository,
+	scheduler *scheduler.Scheduler,
 ) *EngineService {
 	return &EngineService{
-		agentRegistry: agentRegistry,
+		agentRegistry:          agentRegistry,
+		workflowDefinitionRepo: workflowDefinitionRepo,
+		scheduler:              scheduler,
 	}
 }
 
@@ -33,6 +46,7 @@ func (s *EngineService) RegisterAgent(_ context.Context, req *proto.RegisterAgen
 			Port:           req.Port,
 			Protocol:       req.Protocol,
 			SupportedTasks: req.SupportedTasks,
+			Weight:         int(req.GetWeight()),
 		},
 	)
 
@@ -49,13 +63,112 @@ func (s *EngineService) RegisterAgent(_ context.Context, req *proto.RegisterAgen
 	}, nil
 }
 
+// Ping tells an agent whether the engine knows it. Agents restored from
+// storage are reported unknown until they register again, so the engine gets
+// their current address, version and task definitions.
 func (s *EngineService) Ping(_ context.Context, req *proto.EnginePingRequest) (*proto.EnginePingResponse, error) {
-	_, know := s.agentRegistry.GetAgent(req.Name)
+	agent, know := s.agentRegistry.GetAgent(req.Name)
 	return &proto.EnginePingResponse{
-		KnowAgent: know,
+		KnowAgent: know && !agent.Restored,
 	}, nil
 }
 
-func (s *EngineService) StartWorkflow(context.Context, *proto.StartWorkflowRequest) (*proto.StartWorkflowResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method StartWorkflow not implemented")
+func (s *EngineService) StartWorkflow(_ context.Context, req *proto.StartWorkflowRequest) (*proto.StartWorkflowResponse, error) {
+	if _, err := uuid.Parse(req.WorkflowDefinitionId); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid workflow definition ID: %v", err)
+	}
+
+	definition, err := s.workflowDefinitionRepo.GetByID(req.WorkflowDefinitionId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return startWorkflowFailure(engineErrors.ErrWorkflowDefinitionNotFound), nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to retrieve workflow definition: %v", err)
+	}
+
+	if !definition.IsEnabled {
+		return startWorkflowFailure(engineErrors.ErrWorkflowDefinitionDisabled), nil
+	}
+	if definition.IsDraft() {
+		return startWorkflowFailure(engineErrors.ErrWorkflowDefinitionDraft), nil
+	}
+
+	if _, err := definition.GetFirstStep(); err != nil {
+		return startWorkflowFailure(err), nil
+	}
+
+	var parameterDefinitions models.WorkflowParameterDefinitionList
+	if definition.InputParameters != nil {
+		parameterDefinitions = *definition.InputParameters
+	}
+
+	input, err := parameterDefinitions.ValidateInput(req.InputParameters.AsMap())
+	if err != nil {
+		return startWorkflowFailure(err), nil
+	}
+
+	instance, err := s.scheduler.StartWorkflow(definition, input)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to start workflow instance: %v", err)
+	}
+
+	instanceID := instance.ID.String()
+	return &proto.StartWorkflowResponse{
+		Success:            true,
+		WorkflowInstanceId: &instanceID,
+	}, nil
+}
+
+func (s *EngineService) CancelWorkflow(_ context.Context, req *proto.WorkflowInstanceActionRequest) (*proto.WorkflowInstanceActionResponse, error) {
+	reason := "cancelled by user"
+	if req.Reason != nil && *req.Reason != "" {
+		reason = *req.Reason
+	}
+	return s.applyInstanceAction(req, func(id uuid.UUID) error {
+		return s.scheduler.CancelWorkflow(id, reason)
+	})
+}
+
+func (s *EngineService) PauseWorkflow(_ context.Context, req *proto.WorkflowInstanceActionRequest) (*proto.WorkflowInstanceActionResponse, error) {
+	return s.applyInstanceAction(req, s.scheduler.PauseWorkflow)
+}
+
+func (s *EngineService) ResumeWorkflow(_ context.Context, req *proto.WorkflowInstanceActionRequest) (*proto.WorkflowInstanceActionResponse, error) {
+	return s.applyInstanceAction(req, s.scheduler.ResumeWorkflow)
+}
+
+func (s *EngineService) applyInstanceAction(req *proto.WorkflowInstanceActionRequest, action func(uuid.UUID) error) (*proto.WorkflowInstanceActionResponse, error) {
+	id, err := uuid.Parse(req.WorkflowInstanceId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid workflow instance ID: %v", err)
+	}
+
+	err = action(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.NotFound, "workflow instance %s not found", id)
+	}
+	if errors.Is(err, engineErrors.ErrWorkflowInstanceNotActive) ||
+		errors.Is(err, engineErrors.ErrWorkflowInstanceNotRunning) ||
+		errors.Is(err, engineErrors.ErrWorkflowInstanceNotPaused) {
+		message := err.Error()
+		return &proto.WorkflowInstanceActionResponse{
+			Success: false,
+			Message: &message,
+		}, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update workflow instance: %v", err)
+	}
+
+	return &proto.WorkflowInstanceActionResponse{
+		Success: true,
+	}, nil
+}
+
+func startWorkflowFailure(err error) *proto.StartWorkflowResponse {
+	message := err.Error()
+	return &proto.StartWorkflowResponse{
+		Success: false,
+		Message: &message,
+	}
 }
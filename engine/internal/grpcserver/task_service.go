@@ -2,7 +2,13 @@ package grpcserver
 
 import (
 	"context"
+	"log"
 
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/registry"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/scheduler"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/ws"
 	"github.com/paulhalleux/workflow-engine-go/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -11,16 +17,103 @@ import (
 
 type TaskService struct {
 	proto.UnimplementedTaskServiceServer
+
+	stepInstanceRepo persistance.StepInstanceRepository
+	stepAttemptRepo  persistance.StepAttemptRepository
+	scheduler        *scheduler.Scheduler
+	agentRegistry    *registry.AgentRegistry
+	broadcaster      ws.Broadcaster
 }
 
-func NewTaskService() *TaskService {
-	return &TaskService{}
+func NewTaskService(
+	stepInstanceRepo persistance.StepInstanceRepository,
+	stepAttemptRepo persistance.StepAttemptRepository,
+	scheduler *scheduler.Scheduler,
+	agentRegistry *registry.AgentRegistry,
+	broadcaster ws.Broadcaster,
+) *TaskService {
+	return &TaskService{
+		stepInstanceRepo: stepInstanceRepo,
+		stepAttemptRepo:  stepAttemptRepo,
+		scheduler:        scheduler,
+		agentRegistry:    agentRegistry,
+		broadcaster:      broadcaster,
+	}
 }
 
-func (s *TaskService) NotifyTaskStatus(context.Context, *proto.NotifyTaskStatusRequest) (*emptypb.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method NotifyTaskStatus not implemented")
+func (s *TaskService) NotifyTaskStatus(_ context.Context, req *proto.NotifyTaskStatusRequest) (*emptypb.Empty, error) {
+	switch req.Status {
+	case proto.TaskStatus_COMPLETED, proto.TaskStatus_FAILED, proto.TaskStatus_STOPPED:
+		s.agentRegistry.ReleaseTask(req.TaskId)
+	}
+
+	step, err := s.findStepByTaskID(req.TaskId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.scheduler.HandleTaskStatus(step.ID, req.Status, req.OutputParameters.AsMap(), req.Message, req.GetErrorCode())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update task status: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
 }
 
-func (s *TaskService) NotifyTaskProgress(context.Context, *proto.NotifyTaskProgressRequest) (*emptypb.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method NotifyTaskProgress not implemented")
+func (s *TaskService) NotifyTaskProgress(_ context.Context, req *proto.NotifyTaskProgressRequest) (*emptypb.Empty, error) {
+	step, err := s.findStepByTaskID(req.TaskId)
+	if err != nil {
+		return nil, err
+	}
+
+	var partialOutput map[string]interface{}
+	if req.PartialOutput != nil {
+		partialOutput = req.PartialOutput.AsMap()
+	}
+
+	updated, err := s.scheduler.HandleTaskProgress(step.ID, req.Progress, req.Message, partialOutput)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update task progress: %v", err)
+	}
+	if updated != nil {
+		s.broadcastProgress(updated)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *TaskService) broadcastProgress(step *models.StepInstance) {
+	var partialOutput map[string]interface{}
+	if step.PartialOutput != nil {
+		partialOutput = *step.PartialOutput
+	}
+
+	msg, err := ws.NewTaskInstanceProgressMessage(step.ID.String(), *step.Progress, step.ProgressMessage, partialOutput)
+	if err != nil {
+		log.Printf("[engine] failed to build progress event of step instance %s: %v", step.ID, err)
+		return
+	}
+	s.broadcaster.Broadcast(msg)
+}
+
+// findStepByTaskID returns the step instance running an agent task. The task
+// ID is stored with the step before the task starts, so an unknown task is
+// not running anymore, such as the task of an attempt superseded by a retry.
+func (s *TaskService) findStepByTaskID(taskID string) (*models.StepInstance, error) {
+	step, err := s.stepInstanceRepo.GetByAgentTaskID(taskID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to retrieve step instance: %v", err)
+	}
+	if step != nil {
+		return step, nil
+	}
+
+	attempt, err := s.stepAttemptRepo.GetByAgentTaskID(taskID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to retrieve step attempt: %v", err)
+	}
+	if attempt != nil {
+		return nil, status.Errorf(codes.NotFound, "task %s belongs to attempt %d of step instance %s", taskID, attempt.Attempt, attempt.StepInstanceID)
+	}
+	return nil, status.Errorf(codes.NotFound, "no step instance is running task %s", taskID)
 }
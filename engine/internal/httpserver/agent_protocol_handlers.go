@@ -0,0 +1,42 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/grpc"
+)
+
+// AgentProtocolHandlers serves the engine and task services with the HTTP
+// transport, for agents registered with AGENT_PROTOCOL_HTTP. Each method is
+// served on its gRPC full method name, e.g. /engine.EngineService/RegisterAgent,
+// and answers the same way as the gRPC server.
+type AgentProtocolHandlers struct {
+	engineService proto.EngineServiceServer
+	taskService   proto.TaskServiceServer
+}
+
+func NewAgentProtocolHandlers(
+	engineService proto.EngineServiceServer,
+	taskService proto.TaskServiceServer,
+) *AgentProtocolHandlers {
+	return &AgentProtocolHandlers{
+		engineService: engineService,
+		taskService:   taskService,
+	}
+}
+
+func (h *AgentProtocolHandlers) Register(router gin.IRoutes) {
+	mux := http.NewServeMux()
+	proto.RegisterHttpService(mux, &proto.EngineService_ServiceDesc, h.engineService)
+	proto.RegisterHttpService(mux, &proto.TaskService_ServiceDesc, h.taskService)
+
+	handler := gin.WrapH(mux)
+	for _, desc := range []*grpc.ServiceDesc{&proto.EngineService_ServiceDesc, &proto.TaskService_ServiceDesc} {
+		for _, method := range desc.Methods {
+			router.POST(fmt.Sprintf("/%s/%s", desc.ServiceName, method.MethodName), handler)
+		}
+	}
+}
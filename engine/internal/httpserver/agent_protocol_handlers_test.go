@@ -0,0 +1,120 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeServices answer the calls of agents the way the engine does, and
+// forward the task statuses they are notified.
+type fakeServices struct {
+	proto.UnimplementedEngineServiceServer
+	proto.UnimplementedTaskServiceServer
+
+	statuses chan *proto.NotifyTaskStatusRequest
+}
+
+func (s *fakeServices) RegisterAgent(_ context.Context, req *proto.RegisterAgentRequest) (*proto.RegisterAgentResponse, error) {
+	if req.Name == "" {
+		message := "agent name is required"
+		return &proto.RegisterAgentResponse{Success: false, Message: &message}, nil
+	}
+	return &proto.RegisterAgentResponse{Success: true}, nil
+}
+
+func (s *fakeServices) Ping(_ context.Context, req *proto.EnginePingRequest) (*proto.EnginePingResponse, error) {
+	return &proto.EnginePingResponse{KnowAgent: req.Name == "echo-agent"}, nil
+}
+
+func (s *fakeServices) NotifyTaskStatus(_ context.Context, req *proto.NotifyTaskStatusRequest) (*emptypb.Empty, error) {
+	if req.TaskId != "task-1" {
+		return nil, status.Errorf(codes.NotFound, "no step instance is running task %s", req.TaskId)
+	}
+	s.statuses <- req
+	return &emptypb.Empty{}, nil
+}
+
+// engineTransports serve the services with each protocol and return the
+// connection of an agent to them.
+var engineTransports = map[proto.AgentProtocol]func(t *testing.T, services *fakeServices) grpc.ClientConnInterface{
+	proto.AGENT_PROTOCOL_GRPC: func(t *testing.T, services *fakeServices) grpc.ClientConnInterface {
+		srv := grpc.NewServer()
+		proto.RegisterEngineServiceServer(srv, services)
+		proto.RegisterTaskServiceServer(srv, services)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go srv.Serve(listener)
+		t.Cleanup(srv.Stop)
+
+		conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	},
+	proto.AGENT_PROTOCOL_HTTP: func(t *testing.T, services *fakeServices) grpc.ClientConnInterface {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		NewAgentProtocolHandlers(services, services).Register(router)
+		srv := httptest.NewServer(router)
+		t.Cleanup(srv.Close)
+
+		return proto.NewHttpClientConn(srv.URL)
+	},
+}
+
+// TestAgentProtocolContract checks that the engine answers agents the same way
+// whatever the protocol they registered with.
+func TestAgentProtocolContract(t *testing.T) {
+	for protocol, serve := range engineTransports {
+		t.Run(protocol.String(), func(t *testing.T) {
+			services := &fakeServices{statuses: make(chan *proto.NotifyTaskStatusRequest, 1)}
+			conn := serve(t, services)
+			engineClient := proto.NewEngineServiceClient(conn)
+			taskClient := proto.NewTaskServiceClient(conn)
+			ctx := context.Background()
+
+			if res, err := engineClient.RegisterAgent(ctx, &proto.RegisterAgentRequest{Name: "echo-agent", Protocol: protocol}); err != nil || !res.Success {
+				t.Fatalf("expected the agent to register, got %v, %v", res, err)
+			}
+			if res, err := engineClient.RegisterAgent(ctx, &proto.RegisterAgentRequest{}); err != nil || res.Success || res.GetMessage() != "agent name is required" {
+				t.Fatalf("expected the refusal of the engine to be returned, got %v, %v", res, err)
+			}
+			if res, err := engineClient.Ping(ctx, &proto.EnginePingRequest{Name: "echo-agent"}); err != nil || !res.KnowAgent {
+				t.Fatalf("expected the engine to know the agent, got %v, %v", res, err)
+			}
+
+			output, _ := structpb.NewStruct(map[string]interface{}{"message": "hello"})
+			if _, err := taskClient.NotifyTaskStatus(ctx, &proto.NotifyTaskStatusRequest{TaskId: "task-1", Status: proto.TaskStatus_COMPLETED, OutputParameters: output}); err != nil {
+				t.Fatal(err)
+			}
+			if notified := <-services.statuses; notified.Status != proto.TaskStatus_COMPLETED || notified.OutputParameters.AsMap()["message"] != "hello" {
+				t.Fatalf("expected the status to be notified with its output, got %v", notified)
+			}
+
+			_, err := taskClient.NotifyTaskStatus(ctx, &proto.NotifyTaskStatusRequest{TaskId: "missing", Status: proto.TaskStatus_RUNNING})
+			if status.Code(err) != codes.NotFound || status.Convert(err).Message() != "no step instance is running task missing" {
+				t.Fatalf("expected the error of the engine to be returned, got %v", err)
+			}
+
+			_, err = taskClient.NotifyTaskProgress(ctx, &proto.NotifyTaskProgressRequest{TaskId: "task-1"})
+			if status.Code(err) != codes.Unimplemented {
+				t.Fatalf("expected an unimplemented method to be reported as such, got %v", err)
+			}
+		})
+	}
+}
@@ -3,18 +3,22 @@ package httpserver
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/dto"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/registry"
 )
 
 type AgentsHandlers struct {
-	registry *registry.AgentRegistry
+	registry  *registry.AgentRegistry
+	agentRepo persistance.AgentRepository
 }
 
 func NewAgentsHandlers(
 	registry *registry.AgentRegistry,
+	agentRepo persistance.AgentRepository,
 ) *AgentsHandlers {
 	return &AgentsHandlers{
-		registry: registry,
+		registry:  registry,
+		agentRepo: agentRepo,
 	}
 
 }
@@ -22,6 +26,7 @@ func NewAgentsHandlers(
 func (w *AgentsHandlers) Register(router gin.IRoutes) {
 	router.GET("/agents", w.GetAllAgents)
 	router.GET("/agents/:name", w.GetAgentByName)
+	router.GET("/agents/:name/registrations", w.GetAgentRegistrations)
 }
 
 // GetAllAgents godoc
@@ -55,3 +60,22 @@ func (w *AgentsHandlers) GetAgentByName(c *gin.Context) {
 	}
 	c.JSON(200, dto.NewAgentResponse(agent))
 }
+
+// GetAgentRegistrations godoc
+// @ID GetAgentRegistrations
+// @Summary Get the registrations of an agent
+// @Description Retrieve every registration of an agent, oldest first, with the version and task definitions it declared and whether they changed.
+// @Tags agents
+// @Produce json
+// @Param name path string true "Agent Name"
+// @Success 200 {array} models.AgentRegistration
+// @Failure 500 {object} gin.H
+// @Router /api/agents/{name}/registrations [get]
+func (w *AgentsHandlers) GetAgentRegistrations(c *gin.Context) {
+	registrations, err := w.agentRepo.GetRegistrations(c.Param("name"))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve agent registrations"})
+		return
+	}
+	c.JSON(200, registrations)
+}
@@ -0,0 +1,144 @@
+package httpserver
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/scheduler"
+	"github.com/paulhalleux/workflow-engine-go/utils/pagination"
+)
+
+type TimersHandlers struct {
+	repo      persistance.TimerRepository
+	scheduler *scheduler.Scheduler
+}
+
+func NewTimersHandlers(
+	repo persistance.TimerRepository,
+	scheduler *scheduler.Scheduler,
+) *TimersHandlers {
+	return &TimersHandlers{
+		repo:      repo,
+		scheduler: scheduler,
+	}
+}
+
+func (t *TimersHandlers) Register(router gin.IRoutes) {
+	router.GET("/timers", t.GetAllTimers)
+	router.PATCH("/timers/:id/fire", t.FireTimer)
+	router.PATCH("/timers/:id/cancel", t.CancelTimer)
+}
+
+// GetAllTimers godoc
+// @ID           GetAllTimers
+// @Summary      Get all timers
+// @Description  Retrieve a paginated list of timers ordered by deadline, pending ones by default
+// @Tags         Timers
+// @Accept       json
+// @Produce      json
+// @Param        status  query    string  false  "Timer status (pending, claimed, fired, cancelled or all)"  default(pending)
+// @Param        offset  query    int     false  "Offset"
+// @Param        limit   query    int     false  "Number of items per page"
+// @Success      200  {array}   models.Timer
+// @Failure      400  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/timers [get]
+func (t *TimersHandlers) GetAllTimers(c *gin.Context) {
+	var params struct {
+		Status string `form:"status,default=pending"`
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	var status *models.TimerStatus
+	switch models.TimerStatus(params.Status) {
+	case models.TimerStatusPending, models.TimerStatusClaimed, models.TimerStatusFired, models.TimerStatusCancelled:
+		s := models.TimerStatus(params.Status)
+		status = &s
+	default:
+		if params.Status != "all" {
+			c.JSON(400, gin.H{"error": "Invalid timer status"})
+			return
+		}
+	}
+
+	var paginationParams pagination.Pagination
+	if err := c.ShouldBindQuery(&paginationParams); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	timers, err := t.repo.GetAll(status, paginationParams)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve timers"})
+		return
+	}
+
+	c.JSON(200, timers)
+}
+
+// FireTimer godoc
+// @ID           FireTimer
+// @Summary      Fire a timer
+// @Description  Fire a pending timer ahead of its deadline
+// @Tags         Timers
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Timer ID"
+// @Success      200  {object}  models.Timer
+// @Failure      400  {object}  gin.H
+// @Failure      404  {object}  gin.H
+// @Failure      409  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/timers/{id}/fire [patch]
+func (t *TimersHandlers) FireTimer(c *gin.Context) {
+	t.applyTimerAction(c, t.scheduler.FireTimer, "Failed to fire timer")
+}
+
+// CancelTimer godoc
+// @ID           CancelTimer
+// @Summary      Cancel a timer
+// @Description  Cancel a pending timer, failing the step waiting for a wait or retry timer
+// @Tags         Timers
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Timer ID"
+// @Success      200  {object}  models.Timer
+// @Failure      400  {object}  gin.H
+// @Failure      404  {object}  gin.H
+// @Failure      409  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/timers/{id}/cancel [patch]
+func (t *TimersHandlers) CancelTimer(c *gin.Context) {
+	t.applyTimerAction(c, t.scheduler.CancelTimer, "Failed to cancel timer")
+}
+
+func (t *TimersHandlers) applyTimerAction(c *gin.Context, action func(uuid.UUID) (*models.Timer, error), failure string) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid timer ID"})
+		return
+	}
+
+	timer, err := action(id)
+	if errors.Is(err, engineErrors.ErrTimerNotFound) {
+		c.JSON(404, gin.H{"error": "Timer not found"})
+		return
+	}
+	if errors.Is(err, engineErrors.ErrTimerNotPending) {
+		c.JSON(409, gin.H{"error": "Timer is not pending"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": failure})
+		return
+	}
+
+	c.JSON(200, timer)
+}
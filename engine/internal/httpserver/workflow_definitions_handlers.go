@@ -1,24 +1,41 @@
 package httpserver
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/diff"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/dto"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/portable"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/registry"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/validation"
 	"github.com/paulhalleux/workflow-engine-go/utils/expr"
 	"github.com/paulhalleux/workflow-engine-go/utils/pagination"
 	"github.com/paulhalleux/workflow-engine-go/utils/semver"
+	"gorm.io/gorm"
 )
 
 type WorkflowDefinitionsHandlers struct {
-	repo persistance.WorkflowDefinitionRepository
+	repo          persistance.WorkflowDefinitionRepository
+	instanceRepo  persistance.WorkflowInstanceRepository
+	agentRegistry *registry.AgentRegistry
 }
 
 func NewWorkflowDefinitionsHandlers(
 	repo persistance.WorkflowDefinitionRepository,
+	instanceRepo persistance.WorkflowInstanceRepository,
+	agentRegistry *registry.AgentRegistry,
 ) *WorkflowDefinitionsHandlers {
 	return &WorkflowDefinitionsHandlers{
-		repo: repo,
+		repo:          repo,
+		instanceRepo:  instanceRepo,
+		agentRegistry: agentRegistry,
 	}
 
 }
@@ -28,9 +45,15 @@ func (w *WorkflowDefinitionsHandlers) Register(router gin.IRoutes) {
 	router.GET("/workflow-definitions/:id", w.GetWorkflowDefinitionByID)
 	router.POST("/workflow-definitions/search", w.SearchWorkflowDefinitions)
 	router.POST("/workflow-definitions", w.CreateWorkflowDefinition)
+	router.POST("/workflow-definitions/import", w.ImportWorkflowDefinition)
 	router.PUT("/workflow-definitions/:id", w.UpdateWorkflowDefinition)
 	router.DELETE("/workflow-definitions/:id", w.DeleteWorkflowDefinition)
 	router.PATCH("/workflow-definitions/:id/publish", w.PublishWorkflowDefinition)
+	router.GET("/workflow-definitions/:id/versions", w.GetWorkflowDefinitionVersions)
+	router.POST("/workflow-definitions/:id/versions", w.CreateWorkflowDefinitionVersion)
+	router.GET("/workflow-definitions/:id/latest", w.GetLatestWorkflowDefinitionVersion)
+	router.GET("/workflow-definitions/:id/diff/:otherId", w.DiffWorkflowDefinitions)
+	router.GET("/workflow-definitions/:id/export", w.ExportWorkflowDefinition)
 	router.PATCH("/workflow-definitions/:id/enable", w.EnableWorkflowDefinition)
 	router.PATCH("/workflow-definitions/:id/disable", w.DisableWorkflowDefinition)
 }
@@ -130,7 +153,7 @@ func (w *WorkflowDefinitionsHandlers) GetWorkflowDefinitionByID(c *gin.Context)
 // CreateWorkflowDefinition godoc
 // @ID           CreateWorkflowDefinition
 // @Summary      Create a new workflow definition
-// @Description  Create a new workflow definition, optionally as a draft
+// @Description  Create the first version of a new workflow, optionally as a draft. Its ID is the family ID shared by the later versions of the workflow. An invalid definition is answered with the list of its violations. A definition created published has the parameters of its task steps type-checked like on publish.
 // @Tags         Workflow Definitions
 // @Accept       json
 // @Produce      json
@@ -156,6 +179,10 @@ func (w *WorkflowDefinitionsHandlers) CreateWorkflowDefinition(c *gin.Context) {
 		return
 	}
 
+	if !w.validateDefinition(c, &definition) {
+		return
+	}
+
 	version, err := semver.Parse(semver.InitialVersion())
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to parse initial version"})
@@ -164,8 +191,12 @@ func (w *WorkflowDefinitionsHandlers) CreateWorkflowDefinition(c *gin.Context) {
 
 	if params.IsDraft {
 		version.PreRelease = "1"
+	} else if !w.validateTaskSchemas(c, &definition) {
+		return
 	}
 
+	definition.ID = uuid.New()
+	definition.FamilyID = definition.ID
 	definition.Version = version.String()
 	createdDefinition, err := w.repo.Create(&definition)
 	if err != nil {
@@ -179,7 +210,7 @@ func (w *WorkflowDefinitionsHandlers) CreateWorkflowDefinition(c *gin.Context) {
 // UpdateWorkflowDefinition godoc
 // @ID           UpdateWorkflowDefinition
 // @Summary      Update an existing workflow definition
-// @Description  Update an existing workflow definition by its ID
+// @Description  Update a draft workflow definition by its ID. Published versions are immutable: changing them requires a new version. The family and version of the definition cannot be changed. An invalid definition is answered with the list of its violations.
 // @Tags         Workflow Definitions
 // @Accept       json
 // @Produce      json
@@ -187,6 +218,8 @@ func (w *WorkflowDefinitionsHandlers) CreateWorkflowDefinition(c *gin.Context) {
 // @Param        body  body      models.WorkflowDefinition  true  "Workflow Definition Data"
 // @Success      200  {object}  models.WorkflowDefinition
 // @Failure      400  {object}  gin.H
+// @Failure      404  {object}  gin.H
+// @Failure      409  {object}  gin.H
 // @Failure      500  {object}  gin.H
 // @Router       /api/workflow-definitions/{id} [put]
 func (w *WorkflowDefinitionsHandlers) UpdateWorkflowDefinition(c *gin.Context) {
@@ -202,8 +235,24 @@ func (w *WorkflowDefinitionsHandlers) UpdateWorkflowDefinition(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "Invalid workflow definition ID"})
 		return
 	}
-
 	definition.ID = uuidId
+
+	existing, found := w.getDefinition(c, id)
+	if !found {
+		return
+	}
+	if !existing.IsDraft() {
+		c.JSON(409, gin.H{"error": "Published workflow definition versions are immutable"})
+		return
+	}
+	definition.FamilyID = existing.FamilyID
+	definition.Version = existing.Version
+	definition.CreatedAt = existing.CreatedAt
+
+	if !w.validateDefinition(c, &definition) {
+		return
+	}
+
 	updatedDefinition, err := w.repo.Update(&definition)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to update workflow definition"})
@@ -216,17 +265,36 @@ func (w *WorkflowDefinitionsHandlers) UpdateWorkflowDefinition(c *gin.Context) {
 // DeleteWorkflowDefinition godoc
 // @ID           DeleteWorkflowDefinition
 // @Summary      Delete a workflow definition
-// @Description  Delete a workflow definition by its ID
+// @Description  Delete a workflow definition by its ID. Published versions that have workflow instances cannot be deleted, since their instances would be deleted along with them.
 // @Tags         Workflow Definitions
 // @Accept       json
 // @Produce      json
 // @Param        id   path      string  true  "Workflow Definition ID"
 // @Success      204
 // @Failure      400  {object}  gin.H
+// @Failure      404  {object}  gin.H
+// @Failure      409  {object}  gin.H
 // @Failure      500  {object}  gin.H
 // @Router       /api/workflow-definitions/{id} [delete]
 func (w *WorkflowDefinitionsHandlers) DeleteWorkflowDefinition(c *gin.Context) {
 	id := c.Param("id")
+	definition, found := w.getDefinition(c, id)
+	if !found {
+		return
+	}
+
+	if !definition.IsDraft() {
+		instances, err := w.instanceRepo.CountByWorkflowDefinitionID(id)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to count workflow instances"})
+			return
+		}
+		if instances > 0 {
+			c.JSON(409, gin.H{"error": fmt.Sprintf("Workflow definition version %s has %d workflow instances and cannot be deleted, disable it instead", definition.Version, instances)})
+			return
+		}
+	}
+
 	err := w.repo.Delete(id)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to delete workflow definition"})
@@ -238,7 +306,7 @@ func (w *WorkflowDefinitionsHandlers) DeleteWorkflowDefinition(c *gin.Context) {
 // PublishWorkflowDefinition godoc
 // @ID           PublishWorkflowDefinition
 // @Summary      Publish a workflow definition
-// @Description  Publish a draft workflow definition by its ID
+// @Description  Publish a draft workflow definition by its ID. The parameters of its task steps are type-checked against the schemas published by the agents, and the violations found are returned.
 // @Tags         Workflow Definitions
 // @Accept       json
 // @Produce      json
@@ -266,6 +334,10 @@ func (w *WorkflowDefinitionsHandlers) PublishWorkflowDefinition(c *gin.Context)
 		return
 	}
 
+	if !w.validateTaskSchemas(c, definition) {
+		return
+	}
+
 	version.ReleaseDraft()
 	definition.Version = version.String()
 
@@ -278,6 +350,361 @@ func (w *WorkflowDefinitionsHandlers) PublishWorkflowDefinition(c *gin.Context)
 	c.Status(200)
 }
 
+// GetWorkflowDefinitionVersions godoc
+// @ID           GetWorkflowDefinitionVersions
+// @Summary      Get the versions of a workflow definition
+// @Description  Retrieve every version, draft or published, of the workflow the given workflow definition belongs to, ordered from the oldest to the newest
+// @Tags         Workflow Definitions
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Workflow Definition ID"
+// @Success      200  {array}   models.WorkflowDefinition
+// @Failure      404  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-definitions/{id}/versions [get]
+func (w *WorkflowDefinitionsHandlers) GetWorkflowDefinitionVersions(c *gin.Context) {
+	definition, found := w.getDefinition(c, c.Param("id"))
+	if !found {
+		return
+	}
+
+	versions, err := w.repo.GetVersions(definition.FamilyID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve workflow definition versions"})
+		return
+	}
+
+	c.JSON(200, versions)
+}
+
+// CreateWorkflowDefinitionVersion godoc
+// @ID           CreateWorkflowDefinitionVersion
+// @Summary      Create a new version of a workflow definition
+// @Description  Create a draft of the next version of a workflow from one of its published versions, whose version is bumped at the given level. The draft starts as a disabled copy of the published version, which is left untouched.
+// @Tags         Workflow Definitions
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true   "Workflow Definition ID"
+// @Param        bump  query     string  false  "Bump level"  Enums(patch, minor, major)  default(patch)
+// @Success      201  {object}  models.WorkflowDefinition
+// @Failure      400  {object}  gin.H
+// @Failure      404  {object}  gin.H
+// @Failure      409  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-definitions/{id}/versions [post]
+func (w *WorkflowDefinitionsHandlers) CreateWorkflowDefinitionVersion(c *gin.Context) {
+	var params struct {
+		Bump string `form:"bump,default=patch"`
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	var bump func(string) (string, error)
+	switch params.Bump {
+	case "patch":
+		bump = semver.BumpPatch
+	case "minor":
+		bump = semver.BumpMinor
+	case "major":
+		bump = semver.BumpMajor
+	default:
+		c.JSON(400, gin.H{"error": "Invalid bump level, expected patch, minor or major"})
+		return
+	}
+
+	source, found := w.getDefinition(c, c.Param("id"))
+	if !found {
+		return
+	}
+	if source.IsDraft() {
+		c.JSON(400, gin.H{"error": "New versions can only be created from a published version"})
+		return
+	}
+
+	next, err := bump(source.Version)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to parse workflow definition version"})
+		return
+	}
+
+	versions, err := w.repo.GetVersions(source.FamilyID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve workflow definition versions"})
+		return
+	}
+	for _, existing := range versions {
+		if version, err := semver.Parse(existing.Version); err == nil {
+			version.ReleaseDraft()
+			if version.String() == next {
+				c.JSON(409, gin.H{"error": "Version " + next + " of the workflow definition already exists"})
+				return
+			}
+		}
+	}
+
+	version, _ := semver.Parse(next)
+	version.PreRelease = "1"
+
+	draft := *source
+	draft.ID = uuid.New()
+	draft.Version = version.String()
+	draft.IsEnabled = false
+	draft.CreatedAt = time.Time{}
+	draft.UpdatedAt = time.Time{}
+	createdDefinition, err := w.repo.Create(&draft)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to create workflow definition version"})
+		return
+	}
+
+	c.JSON(201, createdDefinition)
+}
+
+// GetLatestWorkflowDefinitionVersion godoc
+// @ID           GetLatestWorkflowDefinitionVersion
+// @Summary      Get the latest published version of a workflow definition
+// @Description  Resolve the newest published version of the workflow the given workflow definition belongs to
+// @Tags         Workflow Definitions
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Workflow Definition ID"
+// @Success      200  {object}  models.WorkflowDefinition
+// @Failure      404  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-definitions/{id}/latest [get]
+func (w *WorkflowDefinitionsHandlers) GetLatestWorkflowDefinitionVersion(c *gin.Context) {
+	definition, found := w.getDefinition(c, c.Param("id"))
+	if !found {
+		return
+	}
+
+	latest, err := w.repo.GetLatestPublished(definition.FamilyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, gin.H{"error": "Workflow definition has no published version"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve workflow definition versions"})
+		return
+	}
+
+	c.JSON(200, latest)
+}
+
+// DiffWorkflowDefinitions godoc
+// @ID           DiffWorkflowDefinitions
+// @Summary      Compare two workflow definitions
+// @Description  List the changes turning a workflow definition into another one: added, removed and changed steps, rewired step references, changed step parameters and changed input and output parameters. Each change is classified as breaking or not, and the semver bump they warrant is suggested, so reviewers can see what publishing a draft will change.
+// @Tags         Workflow Definitions
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "Workflow Definition ID to compare from"
+// @Param        otherId  path      string  true  "Workflow Definition ID to compare to"
+// @Success      200  {object}  diff.DefinitionDiff
+// @Failure      404  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-definitions/{id}/diff/{otherId} [get]
+func (w *WorkflowDefinitionsHandlers) DiffWorkflowDefinitions(c *gin.Context) {
+	from, found := w.getDefinition(c, c.Param("id"))
+	if !found {
+		return
+	}
+	to, found := w.getDefinition(c, c.Param("otherId"))
+	if !found {
+		return
+	}
+
+	c.JSON(200, diff.Compare(from, to))
+}
+
+// ExportWorkflowDefinition godoc
+// @ID           ExportWorkflowDefinition
+// @Summary      Export a workflow definition
+// @Description  Export a workflow definition as a portable document, in JSON or YAML, that can be kept in git and imported in another environment. IDs, timestamps and state are stripped, and the workflow definitions run by workflow steps are named by name and version.
+// @Tags         Workflow Definitions
+// @Accept       json
+// @Produce      json
+// @Produce      application/yaml
+// @Param        id      path      string  true   "Workflow Definition ID"
+// @Param        format  query     string  false  "Document format"  Enums(json, yaml)  default(json)
+// @Success      200  {object}  portable.Document
+// @Failure      400  {object}  gin.H
+// @Failure      404  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-definitions/{id}/export [get]
+func (w *WorkflowDefinitionsHandlers) ExportWorkflowDefinition(c *gin.Context) {
+	var params struct {
+		Format string `form:"format,default=json"`
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	format, err := portable.ParseFormat(params.Format)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	definition, found := w.getDefinition(c, c.Param("id"))
+	if !found {
+		return
+	}
+
+	document, err := portable.Export(definition, w.lookupDefinition)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to export workflow definition: " + err.Error()})
+		return
+	}
+	bytes, err := portable.Marshal(document, format)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to encode workflow definition document"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.%s", definition.Name, definition.Version, format)))
+	c.Data(200, format.ContentType(), bytes)
+}
+
+// ImportWorkflowDefinition godoc
+// @ID           ImportWorkflowDefinition
+// @Summary      Import a workflow definition
+// @Description  Import a workflow definition document exported by ExportWorkflowDefinition, in JSON or YAML according to the format parameter or the content type. The definition is validated like on create, and joins the versions of the workflow with the same name. When its name and version already exist, the import fails, skips the document, or overwrites the existing version if it is a draft, according to onConflict. A dry run validates the document and reports what the import would do without saving anything.
+// @Tags         Workflow Definitions
+// @Accept       json
+// @Accept       application/yaml
+// @Produce      json
+// @Param        dryRun      query     bool    false  "Validate without saving"  default(false)
+// @Param        onConflict  query     string  false  "What to do when the name and version already exist"  Enums(fail, skip, overwrite)  default(fail)
+// @Param        format      query     string  false  "Document format, defaults to the content type"  Enums(json, yaml)
+// @Param        body        body      portable.Document  true  "Workflow Definition Document"
+// @Success      200  {object}  dto.ImportWorkflowDefinitionResponse
+// @Success      201  {object}  dto.ImportWorkflowDefinitionResponse
+// @Failure      400  {object}  gin.H
+// @Failure      409  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-definitions/import [post]
+func (w *WorkflowDefinitionsHandlers) ImportWorkflowDefinition(c *gin.Context) {
+	var params struct {
+		DryRun     bool   `form:"dryRun"`
+		OnConflict string `form:"onConflict,default=fail"`
+		Format     string `form:"format"`
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	if params.OnConflict != "fail" && params.OnConflict != "skip" && params.OnConflict != "overwrite" {
+		c.JSON(400, gin.H{"error": "Invalid conflict handling, expected fail, skip or overwrite"})
+		return
+	}
+	if params.Format == "" {
+		params.Format = c.ContentType()
+	}
+	format, err := portable.ParseFormat(params.Format)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Failed to read workflow definition document"})
+		return
+	}
+	document, err := portable.Unmarshal(data, format)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	definition, err := document.Definition(w.lookupReference)
+	var validationErr *validation.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondViolations(c, validationErr.Violations)
+		return
+	case errors.Is(err, engineErrors.ErrInvalidWorkflowDocument):
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to resolve workflow references"})
+		return
+	}
+
+	version, err := semver.Parse(definition.Version)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid workflow definition version"})
+		return
+	}
+	definition.Version = version.String()
+
+	if !w.validateDefinition(c, definition) {
+		return
+	}
+	if !version.IsDraft() && !w.validateTaskSchemas(c, definition) {
+		return
+	}
+
+	existing, err := w.lookupReference(definition.Name, definition.Version)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve workflow definition"})
+		return
+	}
+
+	response := dto.ImportWorkflowDefinitionResponse{DryRun: params.DryRun, Definition: definition}
+	switch {
+	case existing == nil:
+		response.Action = dto.ImportActionCreated
+		definition.ID = uuid.New()
+		definition.FamilyID = definition.ID
+		latest, err := w.repo.GetLatestByName(definition.Name)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(500, gin.H{"error": "Failed to retrieve workflow definition versions"})
+			return
+		}
+		if latest != nil {
+			definition.FamilyID = latest.FamilyID
+		}
+	case params.OnConflict == "skip":
+		response.Action = dto.ImportActionSkipped
+		response.Definition = existing
+	case params.OnConflict == "overwrite" && existing.IsDraft():
+		response.Action = dto.ImportActionUpdated
+		definition.ID = existing.ID
+		definition.FamilyID = existing.FamilyID
+		definition.IsEnabled = existing.IsEnabled
+		definition.CreatedAt = existing.CreatedAt
+	case params.OnConflict == "overwrite":
+		c.JSON(409, gin.H{"error": "Published workflow definition versions are immutable", "id": existing.ID})
+		return
+	default:
+		c.JSON(409, gin.H{"error": fmt.Sprintf("Version %s of workflow definition %s already exists", definition.Version, definition.Name), "id": existing.ID})
+		return
+	}
+
+	if params.DryRun || response.Action == dto.ImportActionSkipped {
+		c.JSON(200, response)
+		return
+	}
+
+	if response.Action == dto.ImportActionCreated {
+		if response.Definition, err = w.repo.Create(definition); err != nil {
+			c.JSON(500, gin.H{"error": "Failed to create workflow definition"})
+			return
+		}
+		c.JSON(201, response)
+		return
+	}
+	if response.Definition, err = w.repo.Update(definition); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to update workflow definition"})
+		return
+	}
+	c.JSON(200, response)
+}
+
 // EnableWorkflowDefinition godoc
 // @ID           EnableWorkflowDefinition
 // @Summary      Enable a workflow definition
@@ -321,3 +748,96 @@ func (w *WorkflowDefinitionsHandlers) DisableWorkflowDefinition(c *gin.Context)
 	}
 	c.Status(200)
 }
+
+// validateDefinition answers 400 with every violation found in a definition,
+// naming the failing step and field so clients can highlight them, and reports
+// whether the definition is valid.
+func (w *WorkflowDefinitionsHandlers) validateDefinition(c *gin.Context, definition *models.WorkflowDefinition) bool {
+	violations := make([]validation.Violation, 0)
+	var validationErr *validation.ValidationError
+	if err := validation.ValidateWorkflowDefinition(definition); errors.As(err, &validationErr) {
+		violations = append(violations, validationErr.Violations...)
+	}
+
+	err := validation.ValidateWorkflowReferences(definition, w.lookupDefinition)
+	if errors.As(err, &validationErr) {
+		violations = append(violations, validationErr.Violations...)
+	} else if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to validate workflow references"})
+		return false
+	}
+
+	if len(violations) > 0 {
+		respondViolations(c, violations)
+		return false
+	}
+	return true
+}
+
+// validateTaskSchemas answers 400 with the violations found by type-checking
+// the task steps of a definition being published against the schemas of their
+// tasks, and reports whether the definition can be published.
+func (w *WorkflowDefinitionsHandlers) validateTaskSchemas(c *gin.Context, definition *models.WorkflowDefinition) bool {
+	var validationErr *validation.ValidationError
+	if err := validation.ValidateTaskSchemas(definition, w.lookupTaskSchemas); errors.As(err, &validationErr) {
+		respondViolations(c, validationErr.Violations)
+		return false
+	}
+	return true
+}
+
+// lookupTaskSchemas returns the schemas of a task as declared by the agents
+// supporting it.
+func (w *WorkflowDefinitionsHandlers) lookupTaskSchemas(taskID string) (*validation.TaskSchemas, bool) {
+	taskDef, found := w.agentRegistry.GetTaskDefinition(taskID)
+	if !found {
+		return nil, false
+	}
+	schemas := &validation.TaskSchemas{}
+	if taskDef.InputParameters != nil {
+		schemas.Input = taskDef.InputParameters.AsMap()
+	}
+	if taskDef.OutputParameters != nil {
+		schemas.Output = taskDef.OutputParameters.AsMap()
+	}
+	return schemas, true
+}
+
+func respondViolations(c *gin.Context, violations []validation.Violation) {
+	c.JSON(400, gin.H{"error": engineErrors.ErrInvalidWorkflowDefinition.Error(), "violations": violations})
+}
+
+// getDefinition returns the workflow definition with the given ID, answering
+// 404 if it does not exist, and reports whether it was found.
+func (w *WorkflowDefinitionsHandlers) getDefinition(c *gin.Context, id string) (*models.WorkflowDefinition, bool) {
+	definition, err := w.lookupDefinition(id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve workflow definition"})
+		return nil, false
+	}
+	if definition == nil {
+		c.JSON(404, gin.H{"error": "Workflow definition not found"})
+		return nil, false
+	}
+	return definition, true
+}
+
+// lookupReference returns the workflow definition with the given name and
+// version, or nil if it does not exist.
+func (w *WorkflowDefinitionsHandlers) lookupReference(name, version string) (*models.WorkflowDefinition, error) {
+	definition, err := w.repo.GetByNameAndVersion(name, version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return definition, err
+}
+
+// lookupDefinition returns the workflow definition with the given ID, or nil
+// if it does not exist.
+func (w *WorkflowDefinitionsHandlers) lookupDefinition(id string) (*models.WorkflowDefinition, error) {
+	definition, err := w.repo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return definition, err
+}
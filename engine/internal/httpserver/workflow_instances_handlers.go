@@ -0,0 +1,266 @@
+package httpserver
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/dto"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/scheduler"
+	"github.com/paulhalleux/workflow-engine-go/utils/pagination"
+	"gorm.io/gorm"
+)
+
+type WorkflowInstancesHandlers struct {
+	repo        persistance.WorkflowInstanceRepository
+	stepRepo    persistance.StepInstanceRepository
+	attemptRepo persistance.StepAttemptRepository
+	scheduler   *scheduler.Scheduler
+}
+
+func NewWorkflowInstancesHandlers(
+	repo persistance.WorkflowInstanceRepository,
+	stepRepo persistance.StepInstanceRepository,
+	attemptRepo persistance.StepAttemptRepository,
+	scheduler *scheduler.Scheduler,
+) *WorkflowInstancesHandlers {
+	return &WorkflowInstancesHandlers{
+		repo:        repo,
+		stepRepo:    stepRepo,
+		attemptRepo: attemptRepo,
+		scheduler:   scheduler,
+	}
+}
+
+func (w *WorkflowInstancesHandlers) Register(router gin.IRoutes) {
+	router.GET("/workflow-instances", w.GetAllWorkflowInstances)
+	router.GET("/workflow-instances/:id", w.GetWorkflowInstanceByID)
+	router.GET("/workflow-instances/:id/steps", w.GetWorkflowInstanceSteps)
+	router.GET("/workflow-instances/:id/steps/:stepId/attempts", w.GetStepInstanceAttempts)
+	router.PATCH("/workflow-instances/:id/cancel", w.CancelWorkflowInstance)
+	router.PATCH("/workflow-instances/:id/pause", w.PauseWorkflowInstance)
+	router.PATCH("/workflow-instances/:id/resume", w.ResumeWorkflowInstance)
+}
+
+// GetAllWorkflowInstances godoc
+// @ID           GetAllWorkflowInstances
+// @Summary      Get all workflow instances
+// @Description  Retrieve a paginated list of all workflow instances, most recent first
+// @Tags         Workflow Instances
+// @Accept       json
+// @Produce      json
+// @Param        offset  query    int     false  "Offset"
+// @Param        limit   query    int     false  "Number of items per page"
+// @Success      200  {array}   models.WorkflowInstance
+// @Failure      400  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-instances [get]
+func (w *WorkflowInstancesHandlers) GetAllWorkflowInstances(c *gin.Context) {
+	var paginationParams pagination.Pagination
+	if err := c.ShouldBindQuery(&paginationParams); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	instances, err := w.repo.GetAll(paginationParams)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve workflow instances"})
+		return
+	}
+
+	c.JSON(200, instances)
+}
+
+// GetWorkflowInstanceByID godoc
+// @ID           GetWorkflowInstanceByID
+// @Summary      Get workflow instance by ID
+// @Description  Retrieve a workflow instance by its ID
+// @Tags         Workflow Instances
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Workflow Instance ID"
+// @Success      200  {object}  models.WorkflowInstance
+// @Failure      404  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-instances/{id} [get]
+func (w *WorkflowInstancesHandlers) GetWorkflowInstanceByID(c *gin.Context) {
+	id := c.Param("id")
+	instance, err := w.repo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, gin.H{"error": "Workflow instance not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve workflow instance"})
+		return
+	}
+	c.JSON(200, instance)
+}
+
+// GetWorkflowInstanceSteps godoc
+// @ID           GetWorkflowInstanceSteps
+// @Summary      Get the steps of a workflow instance
+// @Description  Retrieve the step instances of a workflow instance in the order they were scheduled
+// @Tags         Workflow Instances
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Workflow Instance ID"
+// @Success      200  {array}   models.StepInstance
+// @Failure      404  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-instances/{id}/steps [get]
+func (w *WorkflowInstancesHandlers) GetWorkflowInstanceSteps(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := w.repo.GetByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "Workflow instance not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to retrieve workflow instance"})
+		return
+	}
+
+	steps, err := w.stepRepo.GetByWorkflowInstanceID(id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve workflow instance steps"})
+		return
+	}
+	c.JSON(200, steps)
+}
+
+// GetStepInstanceAttempts godoc
+// @ID           GetStepInstanceAttempts
+// @Summary      Get the attempts of a step instance
+// @Description  Retrieve every execution attempt of a step instance, with the reason each one ended
+// @Tags         Workflow Instances
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string  true  "Workflow Instance ID"
+// @Param        stepId  path      string  true  "Step Instance ID"
+// @Success      200  {array}   models.StepAttempt
+// @Failure      404  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-instances/{id}/steps/{stepId}/attempts [get]
+func (w *WorkflowInstancesHandlers) GetStepInstanceAttempts(c *gin.Context) {
+	step, err := w.stepRepo.GetByID(c.Param("stepId"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "Step instance not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to retrieve step instance"})
+		return
+	}
+	if step.WorkflowInstanceID.String() != c.Param("id") {
+		c.JSON(404, gin.H{"error": "Step instance not found"})
+		return
+	}
+
+	attempts, err := w.attemptRepo.GetByStepInstanceID(step.ID.String())
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve step instance attempts"})
+		return
+	}
+	c.JSON(200, attempts)
+}
+
+// CancelWorkflowInstance godoc
+// @ID           CancelWorkflowInstance
+// @Summary      Cancel a workflow instance
+// @Description  Cancel an active workflow instance, stopping its running tasks and child workflows
+// @Tags         Workflow Instances
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true   "Workflow Instance ID"
+// @Param        body  body      dto.CancelWorkflowInstanceRequest  false  "Cancellation reason"
+// @Success      200  {object}  models.WorkflowInstance
+// @Failure      400  {object}  gin.H
+// @Failure      404  {object}  gin.H
+// @Failure      409  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-instances/{id}/cancel [patch]
+func (w *WorkflowInstancesHandlers) CancelWorkflowInstance(c *gin.Context) {
+	var req dto.CancelWorkflowInstanceRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid cancellation request"})
+			return
+		}
+	}
+	if req.Reason == "" {
+		req.Reason = "cancelled by user"
+	}
+
+	w.applyInstanceAction(c, func(id uuid.UUID) error {
+		return w.scheduler.CancelWorkflow(id, req.Reason)
+	}, "Failed to cancel workflow instance")
+}
+
+// PauseWorkflowInstance godoc
+// @ID           PauseWorkflowInstance
+// @Summary      Pause a workflow instance
+// @Description  Pause an active workflow instance, so none of its steps start until it is resumed
+// @Tags         Workflow Instances
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Workflow Instance ID"
+// @Success      200  {object}  models.WorkflowInstance
+// @Failure      400  {object}  gin.H
+// @Failure      404  {object}  gin.H
+// @Failure      409  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-instances/{id}/pause [patch]
+func (w *WorkflowInstancesHandlers) PauseWorkflowInstance(c *gin.Context) {
+	w.applyInstanceAction(c, w.scheduler.PauseWorkflow, "Failed to pause workflow instance")
+}
+
+// ResumeWorkflowInstance godoc
+// @ID           ResumeWorkflowInstance
+// @Summary      Resume a workflow instance
+// @Description  Resume a paused workflow instance
+// @Tags         Workflow Instances
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Workflow Instance ID"
+// @Success      200  {object}  models.WorkflowInstance
+// @Failure      400  {object}  gin.H
+// @Failure      404  {object}  gin.H
+// @Failure      409  {object}  gin.H
+// @Failure      500  {object}  gin.H
+// @Router       /api/workflow-instances/{id}/resume [patch]
+func (w *WorkflowInstancesHandlers) ResumeWorkflowInstance(c *gin.Context) {
+	w.applyInstanceAction(c, w.scheduler.ResumeWorkflow, "Failed to resume workflow instance")
+}
+
+func (w *WorkflowInstancesHandlers) applyInstanceAction(c *gin.Context, action func(uuid.UUID) error, failure string) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid workflow instance ID"})
+		return
+	}
+
+	err = action(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, gin.H{"error": "Workflow instance not found"})
+		return
+	}
+	if errors.Is(err, engineErrors.ErrWorkflowInstanceNotActive) ||
+		errors.Is(err, engineErrors.ErrWorkflowInstanceNotRunning) ||
+		errors.Is(err, engineErrors.ErrWorkflowInstanceNotPaused) {
+		c.JSON(409, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": failure})
+		return
+	}
+
+	instance, err := w.repo.GetByID(id.String())
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to retrieve workflow instance"})
+		return
+	}
+	c.JSON(200, instance)
+}
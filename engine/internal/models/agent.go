@@ -0,0 +1,66 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AgentTaskDefinition is a task definition declared by an agent, with the JSON
+// schemas of its parameters.
+type AgentTaskDefinition struct {
+	ID               string   `json:"id" validate:"required"`
+	Name             string   `json:"name" validate:"required"`
+	Description      string   `json:"description"`
+	InputParameters  *JsonMap `json:"inputParameters,omitempty"`
+	OutputParameters *JsonMap `json:"outputParameters,omitempty"`
+} // @name AgentTaskDefinition
+
+type AgentTaskDefinitionList []AgentTaskDefinition // @name AgentTaskDefinitionList
+
+func (list *AgentTaskDefinitionList) Value() (driver.Value, error) {
+	bytes, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	return bytes, nil
+}
+
+func (list *AgentTaskDefinitionList) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, list)
+}
+
+// Agent is a registered agent. Agents are stored so the engine can reconnect
+// to them after a restart, without waiting for them to register again.
+type Agent struct {
+	Name           string                   `gorm:"primaryKey;type:varchar(255)" json:"name" validate:"required"`
+	Version        string                   `gorm:"type:varchar(50);not null" json:"version" validate:"required"`
+	Address        *string                  `gorm:"type:varchar(255)" json:"address,omitempty"`
+	Port           string                   `gorm:"type:varchar(50);not null" json:"port" validate:"required"`
+	Protocol       string                   `gorm:"type:varchar(50);not null" json:"protocol" validate:"required"`
+	Weight         int                      `gorm:"not null;default:1" json:"weight" validate:"required"`
+	SupportedTasks *AgentTaskDefinitionList `gorm:"type:jsonb;not null" json:"supportedTasks" validate:"required"`
+	LastSeenAt     time.Time                `gorm:"not null" json:"lastSeenAt" validate:"required"`
+	CreatedAt      time.Time                `gorm:"autoCreateTime" json:"createdAt" validate:"required"`
+	UpdatedAt      time.Time                `gorm:"autoUpdateTime" json:"updatedAt" validate:"required"`
+} // @name Agent
+
+// AgentRegistration records one registration of an agent, so changes to its
+// version and to the task definitions it declares can be traced.
+type AgentRegistration struct {
+	ID              uuid.UUID                `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id" validate:"required"`
+	AgentName       string                   `gorm:"type:varchar(255);not null;index" json:"agentName" validate:"required"`
+	Version         string                   `gorm:"type:varchar(50);not null" json:"version" validate:"required"`
+	PreviousVersion *string                  `gorm:"type:varchar(50)" json:"previousVersion,omitempty"`
+	SupportedTasks  *AgentTaskDefinitionList `gorm:"type:jsonb;not null" json:"supportedTasks" validate:"required"`
+	// ContractChanged tells whether the task definitions differ from the ones
+	// of the previous registration of the agent.
+	ContractChanged bool      `gorm:"not null;default:false" json:"contractChanged" validate:"required"`
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"createdAt" validate:"required"`
+} // @name AgentRegistration
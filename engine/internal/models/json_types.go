@@ -0,0 +1,34 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+type JsonMap map[string]interface{} // @name JsonMap
+
+func (m *JsonMap) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+func (m *JsonMap) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, m)
+}
+
+type StringList []string // @name StringList
+
+func (list *StringList) Value() (driver.Value, error) {
+	return json.Marshal(list)
+}
+
+func (list *StringList) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, list)
+}
@@ -0,0 +1,61 @@
+package models
+
+const (
+	defaultRetryInitialIntervalSeconds = 1.0
+	defaultRetryMultiplier             = 2.0
+	defaultRetryMaxIntervalSeconds     = 60.0
+)
+
+// RetryPolicy describes how a failed step is retried. The delay before
+// attempt n+1 is InitialIntervalSeconds * Multiplier^(n-1), capped at
+// MaxIntervalSeconds and randomized by +/- Jitter (a factor between 0 and 1).
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts            int      `json:"maxAttempts" validate:"required"`
+	InitialIntervalSeconds *float64 `json:"initialIntervalSeconds,omitempty"`
+	Multiplier             *float64 `json:"multiplier,omitempty"`
+	MaxIntervalSeconds     *float64 `json:"maxIntervalSeconds,omitempty"`
+	Jitter                 *float64 `json:"jitter,omitempty"`
+	// NonRetryableErrorCodes lists the error codes that fail the step without
+	// retrying it.
+	NonRetryableErrorCodes []string `json:"nonRetryableErrorCodes,omitempty"`
+} // @name RetryPolicy
+
+func (p RetryPolicy) GetInitialIntervalSeconds() float64 {
+	if p.InitialIntervalSeconds == nil {
+		return defaultRetryInitialIntervalSeconds
+	}
+	return *p.InitialIntervalSeconds
+}
+
+func (p RetryPolicy) GetMultiplier() float64 {
+	if p.Multiplier == nil {
+		return defaultRetryMultiplier
+	}
+	return *p.Multiplier
+}
+
+func (p RetryPolicy) GetMaxIntervalSeconds() float64 {
+	if p.MaxIntervalSeconds == nil {
+		return defaultRetryMaxIntervalSeconds
+	}
+	return *p.MaxIntervalSeconds
+}
+
+func (p RetryPolicy) GetJitter() float64 {
+	if p.Jitter == nil {
+		return 0
+	}
+	return *p.Jitter
+}
+
+// IsRetryable reports whether a failure with the given error code may be
+// retried.
+func (p RetryPolicy) IsRetryable(code string) bool {
+	for _, nonRetryable := range p.NonRetryableErrorCodes {
+		if nonRetryable == code {
+			return false
+		}
+	}
+	return true
+}
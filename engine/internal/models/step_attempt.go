@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type StepAttemptStatus string // @name StepAttemptStatus
+
+const (
+	StepAttemptStatusRunning   StepAttemptStatus = "running"
+	StepAttemptStatusCompleted StepAttemptStatus = "completed"
+	StepAttemptStatusFailed    StepAttemptStatus = "failed"
+	StepAttemptStatusCancelled StepAttemptStatus = "cancelled"
+	StepAttemptStatusTimedOut  StepAttemptStatus = "timedOut"
+)
+
+// StepAttempt records one execution attempt of a step instance, and why it
+// ended.
+type StepAttempt struct {
+	ID                 uuid.UUID         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id" validate:"required"`
+	WorkflowInstanceID uuid.UUID         `gorm:"type:uuid;not null;index" json:"workflowInstanceId" validate:"required"`
+	StepInstanceID     uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_step_attempts_step_instance_attempt" json:"stepInstanceId" validate:"required"`
+	Attempt            int               `gorm:"not null;uniqueIndex:idx_step_attempts_step_instance_attempt" json:"attempt" validate:"required"`
+	Status             StepAttemptStatus `gorm:"type:varchar(50);not null" json:"status" validate:"required"`
+	Error              *string           `gorm:"type:text" json:"error,omitempty"`
+	ErrorCode          *string           `gorm:"type:varchar(255)" json:"errorCode,omitempty"`
+	// AgentTaskID is the agent task the attempt ran, if any.
+	AgentTaskID *string `gorm:"type:varchar(255);index" json:"agentTaskId,omitempty"`
+	// RetryAt is when the next attempt is scheduled, if this one is retried.
+	RetryAt     *time.Time `json:"retryAt,omitempty"`
+	StartedAt   time.Time  `gorm:"not null" json:"startedAt" validate:"required"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt" validate:"required"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updatedAt" validate:"required"`
+} // @name StepAttempt
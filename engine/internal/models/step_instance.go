@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+)
+
+type StepInstanceStatus string // @name StepInstanceStatus
+
+const (
+	StepInstanceStatusPending   StepInstanceStatus = "pending"
+	StepInstanceStatusRunning   StepInstanceStatus = "running"
+	StepInstanceStatusCompleted StepInstanceStatus = "completed"
+	StepInstanceStatusFailed    StepInstanceStatus = "failed"
+	StepInstanceStatusCancelled StepInstanceStatus = "cancelled"
+	// StepInstanceStatusRetrying is the status of a failed step waiting for
+	// its next attempt.
+	StepInstanceStatusRetrying StepInstanceStatus = "retrying"
+	// StepInstanceStatusTimedOut is the status of a step that did not finish
+	// within its timeout.
+	StepInstanceStatusTimedOut StepInstanceStatus = "timedOut"
+)
+
+var stepInstanceTransitions = map[StepInstanceStatus][]StepInstanceStatus{
+	StepInstanceStatusPending:  {StepInstanceStatusRunning, StepInstanceStatusFailed, StepInstanceStatusCancelled},
+	StepInstanceStatusRunning:  {StepInstanceStatusCompleted, StepInstanceStatusFailed, StepInstanceStatusCancelled, StepInstanceStatusRetrying, StepInstanceStatusTimedOut},
+	StepInstanceStatusRetrying: {StepInstanceStatusPending, StepInstanceStatusFailed, StepInstanceStatusCancelled},
+}
+
+type StepInstance struct {
+	ID                 uuid.UUID          `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id" validate:"required"`
+	WorkflowInstanceID uuid.UUID          `gorm:"type:uuid;not null;uniqueIndex:idx_step_instances_workflow_instance_step" json:"workflowInstanceId" validate:"required"`
+	StepDefinitionID   string             `gorm:"type:varchar(255);not null;uniqueIndex:idx_step_instances_workflow_instance_step" json:"stepDefinitionId" validate:"required"`
+	StepType           StepType           `gorm:"type:varchar(50);not null" json:"stepType" validate:"required"`
+	Status             StepInstanceStatus `gorm:"type:varchar(50);not null;index" json:"status" validate:"required"`
+	Attempt            int                `gorm:"not null;default:1" json:"attempt" validate:"required"`
+	// ParentStepInstanceID references the fork or decision step instance
+	// whose branch this step belongs to, if any.
+	ParentStepInstanceID *uuid.UUID `gorm:"type:uuid" json:"parentStepInstanceId,omitempty"`
+	AgentName            *string    `gorm:"type:varchar(255)" json:"agentName,omitempty"`
+	AgentTaskID          *string    `gorm:"type:varchar(255);index" json:"agentTaskId,omitempty"`
+	AgentTaskStatus      *string    `gorm:"type:varchar(50)" json:"agentTaskStatus,omitempty"`
+	Progress             *float32   `json:"progress,omitempty"`
+	ProgressMessage      *string    `gorm:"type:text" json:"progressMessage,omitempty"`
+	PartialOutput        *JsonMap   `gorm:"type:jsonb" json:"partialOutput,omitempty"`
+	Input                *JsonMap   `gorm:"type:jsonb" json:"input,omitempty"`
+	Output               *JsonMap   `gorm:"type:jsonb" json:"output,omitempty"`
+	Error                *string    `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt            time.Time  `gorm:"autoCreateTime" json:"createdAt" validate:"required"`
+	UpdatedAt            time.Time  `gorm:"autoUpdateTime" json:"updatedAt" validate:"required"`
+	StartedAt            *time.Time `json:"startedAt,omitempty"`
+	CompletedAt          *time.Time `json:"completedAt,omitempty"`
+} // @name StepInstance
+
+func (s StepInstanceStatus) IsTerminal() bool {
+	_, hasTransitions := stepInstanceTransitions[s]
+	return !hasTransitions
+}
+
+// IsFailure reports whether the step ended without completing, either because
+// it failed or because it timed out.
+func (s StepInstanceStatus) IsFailure() bool {
+	return s == StepInstanceStatusFailed || s == StepInstanceStatusTimedOut
+}
+
+func (s StepInstanceStatus) CanTransitionTo(next StepInstanceStatus) bool {
+	for _, allowed := range stepInstanceTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo moves the step instance to the given status, updating its
+// timestamps, or returns an error if the transition is not allowed.
+func (s *StepInstance) TransitionTo(status StepInstanceStatus) error {
+	if !s.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", errors.ErrInvalidStepTransition, s.Status, status)
+	}
+
+	now := time.Now()
+	if status == StepInstanceStatusRunning {
+		s.StartedAt = &now
+	}
+	if status.IsTerminal() {
+		s.CompletedAt = &now
+	}
+
+	s.Status = status
+	return nil
+}
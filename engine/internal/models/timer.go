@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TimerType string // @name TimerType
+
+const (
+	// TimerTypeWait completes a wait step once its duration elapsed.
+	TimerTypeWait TimerType = "wait"
+	// TimerTypeRetry starts the next attempt of a failed step.
+	TimerTypeRetry TimerType = "retry"
+	// TimerTypeStepTimeout times out the current attempt of a step.
+	TimerTypeStepTimeout TimerType = "stepTimeout"
+	// TimerTypeWorkflowTimeout times out a whole workflow instance. It is not
+	// attached to a step instance.
+	TimerTypeWorkflowTimeout TimerType = "workflowTimeout"
+)
+
+type TimerStatus string // @name TimerStatus
+
+const (
+	TimerStatusPending TimerStatus = "pending"
+	// TimerStatusClaimed is a timer claimed by an engine process, whose effect
+	// has not been applied yet. A claim that is not applied within the claim
+	// timeout is taken over, so the effect survives a failure or a crash.
+	TimerStatusClaimed   TimerStatus = "claimed"
+	TimerStatusFired     TimerStatus = "fired"
+	TimerStatusCancelled TimerStatus = "cancelled"
+)
+
+// Timer is a durable deadline attached to a step or workflow instance. Timers
+// are stored so they survive engine restarts, and are claimed by a single
+// engine process when they are due. A timer is only marked fired once its
+// effect was applied.
+type Timer struct {
+	ID                 uuid.UUID   `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id" validate:"required"`
+	WorkflowInstanceID uuid.UUID   `gorm:"type:uuid;not null;index" json:"workflowInstanceId" validate:"required"`
+	StepInstanceID     *uuid.UUID  `gorm:"type:uuid;index" json:"stepInstanceId,omitempty"`
+	Type               TimerType   `gorm:"type:varchar(50);not null" json:"type" validate:"required"`
+	Status             TimerStatus `gorm:"type:varchar(50);not null" json:"status" validate:"required"`
+	FireAt             time.Time   `gorm:"not null" json:"fireAt" validate:"required"`
+	ClaimedAt          *time.Time  `json:"claimedAt,omitempty"`
+	FiredAt            *time.Time  `json:"firedAt,omitempty"`
+	CreatedAt          time.Time   `gorm:"autoCreateTime" json:"createdAt" validate:"required"`
+	UpdatedAt          time.Time   `gorm:"autoUpdateTime" json:"updatedAt" validate:"required"`
+} // @name Timer
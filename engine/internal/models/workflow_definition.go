@@ -5,22 +5,36 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/utils/semver"
 )
 
+// WorkflowDefinition is a version of a workflow. The versions of a workflow
+// share its FamilyID, which is the ID of its first version. Published versions
+// are immutable, so the workflow instances started from them stay pinned to
+// the steps they started with.
 type WorkflowDefinition struct {
 	ID               uuid.UUID                        `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id" validate:"required"`
-	Name             string                           `gorm:"type:varchar(255);not null" json:"name" validate:"required"`
+	FamilyID         uuid.UUID                        `gorm:"type:uuid;not null;uniqueIndex:idx_family_version" json:"familyId" validate:"required"`
+	Name             string                           `gorm:"type:varchar(255);not null;uniqueIndex:idx_name_version" json:"name" validate:"required"`
 	Description      string                           `gorm:"type:text" json:"description"`
-	Version          string                           `gorm:"type:varchar(50);not null;uniqueIndex:idx_name_version" json:"version" validate:"required"`
+	Version          string                           `gorm:"type:varchar(50);not null;uniqueIndex:idx_name_version;uniqueIndex:idx_family_version" json:"version" validate:"required"`
 	IsEnabled        bool                             `gorm:"not null;default:false" json:"isEnabled" validate:"required"`
 	InputParameters  *WorkflowParameterDefinitionList `gorm:"type:jsonb" json:"inputParameters,omitempty"`
 	OutputParameters *interface{}                     `gorm:"type:jsonb" json:"outputParameters,omitempty"`
 	Steps            *WorkflowStepDefinitionList      `gorm:"type:jsonb;not null" json:"steps,omitempty" validate:"required"`
+	TimeoutSeconds   *int                             `gorm:"type:integer" json:"timeoutSeconds,omitempty"`
 	CreatedAt        time.Time                        `gorm:"autoCreateTime" json:"createdAt" validate:"required"`
 	UpdatedAt        time.Time                        `gorm:"autoUpdateTime" json:"updatedAt" validate:"required"`
 	Metadata         *map[string]interface{}          `gorm:"type:jsonb" json:"metadata,omitempty"`
 } // @name WorkflowDefinition
 
+// IsDraft reports whether the definition is a draft, which can still be
+// updated, rather than a published version.
+func (def WorkflowDefinition) IsDraft() bool {
+	version, err := semver.Parse(def.Version)
+	return err == nil && version.IsDraft()
+}
+
 func (def WorkflowDefinition) GetFirstStep() (*WorkflowStepDefinition, error) {
 	if def.Steps == nil || len(*def.Steps) == 0 {
 		return nil, errors.ErrWorkflowDefinitionNoSteps
@@ -39,3 +53,17 @@ func (def WorkflowDefinition) GetStepByID(id string) (*WorkflowStepDefinition, b
 	}
 	return nil, false
 }
+
+// GetStepJoinedAt returns the fork or decision step whose branches converge at
+// the given join step.
+func (def WorkflowDefinition) GetStepJoinedAt(joinStepID string) (*WorkflowStepDefinition, bool) {
+	if def.Steps == nil {
+		return nil, false
+	}
+	for _, step := range *def.Steps {
+		if joinID := step.GetJoinStepID(); joinID != nil && *joinID == joinStepID {
+			return &step, true
+		}
+	}
+	return nil, false
+}
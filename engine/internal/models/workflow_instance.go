@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WorkflowInstanceStatus string // @name WorkflowInstanceStatus
+
+const (
+	WorkflowInstanceStatusPending   WorkflowInstanceStatus = "pending"
+	WorkflowInstanceStatusRunning   WorkflowInstanceStatus = "running"
+	WorkflowInstanceStatusPaused    WorkflowInstanceStatus = "paused"
+	WorkflowInstanceStatusCompleted WorkflowInstanceStatus = "completed"
+	WorkflowInstanceStatusFailed    WorkflowInstanceStatus = "failed"
+	WorkflowInstanceStatusCancelled WorkflowInstanceStatus = "cancelled"
+	WorkflowInstanceStatusTimedOut  WorkflowInstanceStatus = "timedOut"
+)
+
+type WorkflowInstance struct {
+	ID                        uuid.UUID              `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id" validate:"required"`
+	WorkflowDefinitionID      uuid.UUID              `gorm:"type:uuid;not null;index" json:"workflowDefinitionId" validate:"required"`
+	WorkflowDefinitionVersion string                 `gorm:"type:varchar(50);not null" json:"workflowDefinitionVersion" validate:"required"`
+	Status                    WorkflowInstanceStatus `gorm:"type:varchar(50);not null;index" json:"status" validate:"required"`
+	// ParentWorkflowInstanceID and ParentStepInstanceID reference the workflow
+	// step that started this instance as a child workflow, if any.
+	ParentWorkflowInstanceID *uuid.UUID  `gorm:"type:uuid;index" json:"parentWorkflowInstanceId,omitempty"`
+	ParentStepInstanceID     *uuid.UUID  `gorm:"type:uuid;index" json:"parentStepInstanceId,omitempty"`
+	Input                    *JsonMap    `gorm:"type:jsonb" json:"input,omitempty"`
+	Output                   *JsonMap    `gorm:"type:jsonb" json:"output,omitempty"`
+	CurrentStepIDs           *StringList `gorm:"type:jsonb" json:"currentStepIds,omitempty"`
+	Error                    *string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt                time.Time   `gorm:"autoCreateTime" json:"createdAt" validate:"required"`
+	UpdatedAt                time.Time   `gorm:"autoUpdateTime" json:"updatedAt" validate:"required"`
+	StartedAt                *time.Time  `json:"startedAt,omitempty"`
+	CompletedAt              *time.Time  `json:"completedAt,omitempty"`
+} // @name WorkflowInstance
+
+func (s WorkflowInstanceStatus) IsTerminal() bool {
+	return s == WorkflowInstanceStatusCompleted || s == WorkflowInstanceStatusFailed || s == WorkflowInstanceStatusCancelled || s == WorkflowInstanceStatusTimedOut
+}
@@ -3,6 +3,10 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
 )
 
 type WorkflowParameterDefinition struct {
@@ -31,3 +35,66 @@ func (list *WorkflowParameterDefinitionList) Scan(value interface{}) error {
 	}
 	return json.Unmarshal(bytes, list)
 }
+
+// ValidateInput checks the given input against the parameter definitions and
+// returns a copy of it with default values applied for missing parameters.
+func (list WorkflowParameterDefinitionList) ValidateInput(input map[string]interface{}) (map[string]interface{}, error) {
+	validated := make(map[string]interface{}, len(list))
+	known := make(map[string]struct{}, len(list))
+
+	for _, param := range list {
+		known[param.Name] = struct{}{}
+
+		value, exists := input[param.Name]
+		if !exists || value == nil {
+			if param.Default != nil {
+				validated[param.Name] = param.Default
+				continue
+			}
+			if param.Required {
+				return nil, fmt.Errorf("%w: %s", errors.ErrMissingInputParameter, param.Name)
+			}
+			continue
+		}
+
+		if !param.AcceptsValue(value) {
+			return nil, fmt.Errorf("%w: %s must be of type %s", errors.ErrInvalidInputParameterType, param.Name, param.Type)
+		}
+		validated[param.Name] = value
+	}
+
+	for name := range input {
+		if _, exists := known[name]; !exists {
+			return nil, fmt.Errorf("%w: %s", errors.ErrUnknownInputParameter, name)
+		}
+	}
+
+	return validated, nil
+}
+
+// AcceptsValue reports whether value matches the declared parameter type.
+// Parameters without a known type accept any value.
+func (param WorkflowParameterDefinition) AcceptsValue(value interface{}) bool {
+	switch param.Type {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	default:
+		return true
+	}
+}
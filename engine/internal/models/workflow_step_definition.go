@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 )
 
 type StepType string // @name StepType
@@ -24,6 +25,7 @@ type WorkflowStepDefinition struct {
 	Metadata         *map[string]interface{}   `json:"metadata,omitempty"`
 	TimeoutSeconds   *int                      `json:"timeoutSeconds,omitempty"`
 	RetryCount       *int                      `json:"retryCount,omitempty"`
+	RetryPolicy      *RetryPolicy              `json:"retryPolicy,omitempty"`
 
 	Type           StepType        `json:"type" validate:"required"`
 	TaskConfig     *TaskConfig     `json:"taskConfig,omitempty" validate:"required_if=Type task"`
@@ -49,11 +51,36 @@ type WaitConfig struct {
 	NextStepID      *string                 `json:"nextStepId,omitempty"`
 } // @name WaitConfig
 
+type JoinPolicy string // @name JoinPolicy
+
+const (
+	// JoinPolicyAll waits for every incoming branch and fails if any failed.
+	JoinPolicyAll JoinPolicy = "all"
+	// JoinPolicyAny completes as soon as one incoming branch completed.
+	JoinPolicyAny JoinPolicy = "any"
+	// JoinPolicyNOfM completes as soon as RequiredCount incoming branches
+	// completed.
+	JoinPolicyNOfM JoinPolicy = "nOfM"
+	// JoinPolicyFailFast waits for every incoming branch but fails as soon as
+	// one of them failed.
+	JoinPolicyFailFast JoinPolicy = "failFast"
+)
+
 type JoinConfig struct {
-	IncomingStepIDs []string `json:"incomingStepIds" validate:"required"`
-	NextStepID      *string  `json:"nextStepId,omitempty"`
+	IncomingStepIDs []string   `json:"incomingStepIds" validate:"required"`
+	Policy          JoinPolicy `json:"policy,omitempty"`
+	RequiredCount   *int       `json:"requiredCount,omitempty" validate:"required_if=Policy nOfM"`
+	NextStepID      *string    `json:"nextStepId,omitempty"`
 } // @name JoinConfig
 
+// GetPolicy returns the join policy, defaulting to JoinPolicyAll.
+func (config JoinConfig) GetPolicy() JoinPolicy {
+	if config.Policy == "" {
+		return JoinPolicyAll
+	}
+	return config.Policy
+}
+
 type ForkBranch struct {
 	Name        *string `json:"name,omitempty"`
 	Description *string `json:"description,omitempty"`
@@ -75,8 +102,102 @@ type DecisionCase struct {
 type DecisionConfig struct {
 	JoinStepID string         `json:"joinStepId" validate:"required"`
 	Cases      []DecisionCase `json:"cases" validate:"required"`
+	// DefaultNextStepID is taken when no case matches. Without it, the
+	// decision goes straight to its join step.
+	DefaultNextStepID *string `json:"defaultNextStepId,omitempty"`
 } // @name DecisionConfig
 
+// GetNextStepID returns the step that follows this one once it completes.
+// Fork and decision steps have no single next step and return nil.
+func (step WorkflowStepDefinition) GetNextStepID() *string {
+	switch step.Type {
+	case StepTypeTask:
+		if step.TaskConfig != nil {
+			return step.TaskConfig.NextStepID
+		}
+	case StepTypeWorkflow:
+		if step.WorkflowConfig != nil {
+			return step.WorkflowConfig.NextStepID
+		}
+	case StepTypeWait:
+		if step.WaitConfig != nil {
+			return step.WaitConfig.NextStepID
+		}
+	case StepTypeJoin:
+		if step.JoinConfig != nil {
+			return step.JoinConfig.NextStepID
+		}
+	}
+	return nil
+}
+
+// GetRetryPolicy returns the retry policy of the step, or nil if it is never
+// retried. RetryCount is a shorthand for a policy retrying that many times
+// with the default intervals.
+func (step WorkflowStepDefinition) GetRetryPolicy() *RetryPolicy {
+	if step.RetryPolicy != nil {
+		return step.RetryPolicy
+	}
+	if step.RetryCount != nil && *step.RetryCount > 0 {
+		return &RetryPolicy{MaxAttempts: *step.RetryCount + 1}
+	}
+	return nil
+}
+
+// GetJoinStepID returns the step where the branches of a fork or decision
+// step converge.
+func (step WorkflowStepDefinition) GetJoinStepID() *string {
+	switch step.Type {
+	case StepTypeFork:
+		if step.ForkConfig != nil {
+			return &step.ForkConfig.JoinStepID
+		}
+	case StepTypeDecision:
+		if step.DecisionConfig != nil {
+			return &step.DecisionConfig.JoinStepID
+		}
+	}
+	return nil
+}
+
+// StepReference is a step referenced by another one, with the JSON path of the
+// field holding the reference within the referencing step.
+type StepReference struct {
+	Field  string
+	StepID string
+}
+
+// GetStepReferences returns the steps referenced by the step: the steps that
+// can run after it, the join step of a fork or decision, and the incoming
+// steps of a join.
+func (step WorkflowStepDefinition) GetStepReferences() []StepReference {
+	refs := make([]StepReference, 0)
+	if next := step.GetNextStepID(); next != nil {
+		refs = append(refs, StepReference{string(step.Type) + "Config.nextStepId", *next})
+	}
+	if step.ForkConfig != nil {
+		refs = append(refs, StepReference{"forkConfig.joinStepId", step.ForkConfig.JoinStepID})
+		for i, branch := range step.ForkConfig.Branches {
+			refs = append(refs, StepReference{fmt.Sprintf("forkConfig.branches[%d].nextStepId", i), branch.NextStepID})
+		}
+	}
+	if step.DecisionConfig != nil {
+		refs = append(refs, StepReference{"decisionConfig.joinStepId", step.DecisionConfig.JoinStepID})
+		for i, decisionCase := range step.DecisionConfig.Cases {
+			refs = append(refs, StepReference{fmt.Sprintf("decisionConfig.cases[%d].nextStepId", i), decisionCase.NextStepID})
+		}
+		if step.DecisionConfig.DefaultNextStepID != nil {
+			refs = append(refs, StepReference{"decisionConfig.defaultNextStepId", *step.DecisionConfig.DefaultNextStepID})
+		}
+	}
+	if step.JoinConfig != nil {
+		for i, incomingStepID := range step.JoinConfig.IncomingStepIDs {
+			refs = append(refs, StepReference{fmt.Sprintf("joinConfig.incomingStepIds[%d]", i), incomingStepID})
+		}
+	}
+	return refs
+}
+
 type WorkflowStepDefinitionList []WorkflowStepDefinition // @name WorkflowStepDefinitionList
 
 func (list *WorkflowStepDefinitionList) Value() (driver.Value, error) {
@@ -0,0 +1,83 @@
+package persistance
+
+import (
+	"errors"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"gorm.io/gorm"
+)
+
+type AgentRepository interface {
+	GetAll() ([]models.Agent, error)
+	GetByName(name string) (*models.Agent, error)
+	Save(agent *models.Agent) (*models.Agent, error)
+	Delete(name string) error
+	GetRegistrations(agentName string) ([]models.AgentRegistration, error)
+	CreateRegistration(registration *models.AgentRegistration) (*models.AgentRegistration, error)
+}
+
+type agentRepository struct {
+	db *gorm.DB
+}
+
+func NewAgentRepository(
+	db *gorm.DB,
+) AgentRepository {
+	return &agentRepository{
+		db: db,
+	}
+}
+
+func (r *agentRepository) GetAll() ([]models.Agent, error) {
+	agents := make([]models.Agent, 0)
+	result := r.db.Order("name ASC").Find(&agents)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return agents, nil
+}
+
+// GetByName returns the stored agent with the given name, or nil if it was
+// never registered.
+func (r *agentRepository) GetByName(name string) (*models.Agent, error) {
+	agent := &models.Agent{}
+	result := r.db.First(agent, "name = ?", name)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return agent, nil
+}
+
+// Save creates the agent, or replaces the stored agent with the same name.
+func (r *agentRepository) Save(agent *models.Agent) (*models.Agent, error) {
+	result := r.db.Save(agent)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return agent, nil
+}
+
+func (r *agentRepository) Delete(name string) error {
+	return r.db.Delete(&models.Agent{}, "name = ?", name).Error
+}
+
+// GetRegistrations returns the registrations of an agent, oldest first.
+func (r *agentRepository) GetRegistrations(agentName string) ([]models.AgentRegistration, error) {
+	registrations := make([]models.AgentRegistration, 0)
+	result := r.db.Where("agent_name = ?", agentName).Order("created_at ASC").Find(&registrations)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return registrations, nil
+}
+
+func (r *agentRepository) CreateRegistration(registration *models.AgentRegistration) (*models.AgentRegistration, error) {
+	result := r.db.Create(registration)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return registration, nil
+}
@@ -0,0 +1,81 @@
+package persistance
+
+import (
+	"errors"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"gorm.io/gorm"
+)
+
+type StepAttemptRepository interface {
+	GetByStepInstanceID(stepInstanceID string) ([]models.StepAttempt, error)
+	GetByAttempt(stepInstanceID string, attempt int) (*models.StepAttempt, error)
+	GetByAgentTaskID(agentTaskID string) (*models.StepAttempt, error)
+	Create(attempt *models.StepAttempt) (*models.StepAttempt, error)
+	Update(attempt *models.StepAttempt) (*models.StepAttempt, error)
+}
+
+type stepAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewStepAttemptRepository(
+	db *gorm.DB,
+) StepAttemptRepository {
+	return &stepAttemptRepository{
+		db: db,
+	}
+}
+
+func (r *stepAttemptRepository) GetByStepInstanceID(stepInstanceID string) ([]models.StepAttempt, error) {
+	attempts := make([]models.StepAttempt, 0)
+	result := r.db.Where("step_instance_id = ?", stepInstanceID).Order("attempt ASC").Find(&attempts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return attempts, nil
+}
+
+// GetByAttempt returns the given attempt of a step instance, or nil if it was
+// not recorded.
+func (r *stepAttemptRepository) GetByAttempt(stepInstanceID string, attempt int) (*models.StepAttempt, error) {
+	stepAttempt := &models.StepAttempt{}
+	result := r.db.First(stepAttempt, "step_instance_id = ? AND attempt = ?", stepInstanceID, attempt)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return stepAttempt, nil
+}
+
+// GetByAgentTaskID returns the attempt that ran the given agent task, or nil
+// if no finished attempt ran it.
+func (r *stepAttemptRepository) GetByAgentTaskID(agentTaskID string) (*models.StepAttempt, error) {
+	stepAttempt := &models.StepAttempt{}
+	result := r.db.First(stepAttempt, "agent_task_id = ?", agentTaskID)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return stepAttempt, nil
+}
+
+func (r *stepAttemptRepository) Create(attempt *models.StepAttempt) (*models.StepAttempt, error) {
+	result := r.db.Create(attempt)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return attempt, nil
+}
+
+func (r *stepAttemptRepository) Update(attempt *models.StepAttempt) (*models.StepAttempt, error) {
+	result := r.db.Save(attempt)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return attempt, nil
+}
@@ -0,0 +1,103 @@
+package persistance
+
+import (
+	"errors"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"gorm.io/gorm"
+)
+
+type StepInstanceRepository interface {
+	GetByID(id string) (*models.StepInstance, error)
+	GetByWorkflowInstanceID(workflowInstanceID string) ([]models.StepInstance, error)
+	GetByStepDefinitionID(workflowInstanceID string, stepDefinitionID string) (*models.StepInstance, error)
+	GetByAgentTaskID(agentTaskID string) (*models.StepInstance, error)
+	GetRunningByAgentName(agentName string) ([]models.StepInstance, error)
+	Create(step *models.StepInstance) (*models.StepInstance, error)
+	Update(step *models.StepInstance) (*models.StepInstance, error)
+}
+
+type stepInstanceRepository struct {
+	db *gorm.DB
+}
+
+func NewStepInstanceRepository(
+	db *gorm.DB,
+) StepInstanceRepository {
+	return &stepInstanceRepository{
+		db: db,
+	}
+}
+
+func (r *stepInstanceRepository) GetByID(id string) (*models.StepInstance, error) {
+	step := &models.StepInstance{}
+	result := r.db.First(step, "id = ?", id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return step, nil
+}
+
+func (r *stepInstanceRepository) GetByWorkflowInstanceID(workflowInstanceID string) ([]models.StepInstance, error) {
+	steps := make([]models.StepInstance, 0)
+	result := r.db.Where("workflow_instance_id = ?", workflowInstanceID).Order("created_at ASC").Find(&steps)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return steps, nil
+}
+
+// GetByStepDefinitionID returns the instance of a step definition within a
+// workflow instance, or nil if the step has not been scheduled yet.
+func (r *stepInstanceRepository) GetByStepDefinitionID(workflowInstanceID string, stepDefinitionID string) (*models.StepInstance, error) {
+	step := &models.StepInstance{}
+	result := r.db.First(step, "workflow_instance_id = ? AND step_definition_id = ?", workflowInstanceID, stepDefinitionID)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return step, nil
+}
+
+// GetByAgentTaskID returns the step instance running the given agent task, or
+// nil if no step is linked to it.
+func (r *stepInstanceRepository) GetByAgentTaskID(agentTaskID string) (*models.StepInstance, error) {
+	step := &models.StepInstance{}
+	result := r.db.First(step, "agent_task_id = ?", agentTaskID)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return step, nil
+}
+
+// GetRunningByAgentName returns the running step instances whose task was
+// dispatched to the given agent.
+func (r *stepInstanceRepository) GetRunningByAgentName(agentName string) ([]models.StepInstance, error) {
+	steps := make([]models.StepInstance, 0)
+	result := r.db.Where("agent_name = ? AND status = ?", agentName, models.StepInstanceStatusRunning).Find(&steps)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return steps, nil
+}
+
+func (r *stepInstanceRepository) Create(step *models.StepInstance) (*models.StepInstance, error) {
+	result := r.db.Create(step)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return step, nil
+}
+
+func (r *stepInstanceRepository) Update(step *models.StepInstance) (*models.StepInstance, error) {
+	result := r.db.Save(step)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return step, nil
+}
@@ -0,0 +1,179 @@
+package persistance
+
+import (
+	"errors"
+	"time"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/utils/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TimerRepository interface {
+	GetAll(status *models.TimerStatus, pagination pagination.Pagination) (*pagination.PaginatedResult[models.Timer], error)
+	GetByID(id string) (*models.Timer, error)
+	GetByStepInstanceID(stepInstanceID string, timerType models.TimerType) (*models.Timer, error)
+	Create(timer *models.Timer) (*models.Timer, error)
+	ClaimDue(now time.Time, claimTimeout time.Duration, limit int) ([]models.Timer, error)
+	Claim(id string) (*models.Timer, error)
+	MarkFired(id string) error
+	Cancel(id string) (*models.Timer, error)
+	CancelByStepInstanceID(stepInstanceID string) error
+	CancelByWorkflowInstanceID(workflowInstanceID string) error
+}
+
+type timerRepository struct {
+	db *gorm.DB
+}
+
+func NewTimerRepository(
+	db *gorm.DB,
+) TimerRepository {
+	return &timerRepository{
+		db: db,
+	}
+}
+
+func (r *timerRepository) GetAll(
+	status *models.TimerStatus,
+	pg pagination.Pagination,
+) (*pagination.PaginatedResult[models.Timer], error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		if status != nil {
+			return db.Where("status = ?", *status)
+		}
+		return db
+	}
+
+	var totalCount int64
+	if err := r.db.Model(&models.Timer{}).Scopes(filter).Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	timers := make([]models.Timer, 0)
+	result := pg.ToGorm(r.db.Scopes(filter)).Order("fire_at ASC").Find(&timers)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &pagination.PaginatedResult[models.Timer]{
+		TotalCount: totalCount,
+		Items:      timers,
+	}, nil
+}
+
+func (r *timerRepository) GetByID(id string) (*models.Timer, error) {
+	timer := &models.Timer{}
+	result := r.db.First(timer, "id = ?", id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return timer, nil
+}
+
+// GetByStepInstanceID returns the most recent timer of the given type attached
+// to a step instance, or nil if there is none.
+func (r *timerRepository) GetByStepInstanceID(stepInstanceID string, timerType models.TimerType) (*models.Timer, error) {
+	timer := &models.Timer{}
+	result := r.db.Order("created_at DESC").First(timer, "step_instance_id = ? AND type = ?", stepInstanceID, timerType)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return timer, nil
+}
+
+func (r *timerRepository) Create(timer *models.Timer) (*models.Timer, error) {
+	result := r.db.Create(timer)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return timer, nil
+}
+
+// ClaimDue claims up to limit pending timers whose deadline passed, along with
+// the timers claimed more than claimTimeout ago whose effect was never
+// applied, and returns them. Rows locked by another engine process are
+// skipped, so a timer has a single claimer at a time.
+func (r *timerRepository) ClaimDue(now time.Time, claimTimeout time.Duration, limit int) ([]models.Timer, error) {
+	timers := make([]models.Timer, 0)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND fire_at <= ?) OR (status = ? AND claimed_at <= ?)",
+				models.TimerStatusPending, now, models.TimerStatusClaimed, now.Add(-claimTimeout)).
+			Order("fire_at ASC").
+			Limit(limit).
+			Find(&timers)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		for i := range timers {
+			timers[i].Status = models.TimerStatusClaimed
+			timers[i].ClaimedAt = &now
+			if err := tx.Save(&timers[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return timers, nil
+}
+
+// Claim claims a pending timer ahead of its deadline. It returns nil if the
+// timer is no longer pending.
+func (r *timerRepository) Claim(id string) (*models.Timer, error) {
+	return r.transition(id, models.TimerStatusClaimed)
+}
+
+// MarkFired marks a claimed timer as fired once its effect was applied.
+func (r *timerRepository) MarkFired(id string) error {
+	return r.db.Model(&models.Timer{}).
+		Where("id = ? AND status = ?", id, models.TimerStatusClaimed).
+		Updates(map[string]interface{}{"status": models.TimerStatusFired, "fired_at": time.Now()}).Error
+}
+
+// Cancel marks a pending timer as cancelled. It returns nil if the timer is no
+// longer pending.
+func (r *timerRepository) Cancel(id string) (*models.Timer, error) {
+	return r.transition(id, models.TimerStatusCancelled)
+}
+
+func (r *timerRepository) CancelByStepInstanceID(stepInstanceID string) error {
+	return r.db.Model(&models.Timer{}).
+		Where("step_instance_id = ? AND status = ?", stepInstanceID, models.TimerStatusPending).
+		Update("status", models.TimerStatusCancelled).Error
+}
+
+func (r *timerRepository) CancelByWorkflowInstanceID(workflowInstanceID string) error {
+	return r.db.Model(&models.Timer{}).
+		Where("workflow_instance_id = ? AND status = ?", workflowInstanceID, models.TimerStatusPending).
+		Update("status", models.TimerStatusCancelled).Error
+}
+
+func (r *timerRepository) transition(id string, status models.TimerStatus) (*models.Timer, error) {
+	timer := &models.Timer{}
+	now := time.Now()
+	updates := map[string]interface{}{"status": status}
+	if status == models.TimerStatusClaimed {
+		updates["claimed_at"] = now
+	}
+
+	result := r.db.Model(timer).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ?", id, models.TimerStatusPending).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return timer, nil
+}
@@ -1,9 +1,13 @@
 package persistance
 
 import (
+	"slices"
+
+	"github.com/google/uuid"
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
 	"github.com/paulhalleux/workflow-engine-go/utils/expr"
 	"github.com/paulhalleux/workflow-engine-go/utils/pagination"
+	"github.com/paulhalleux/workflow-engine-go/utils/semver"
 	"gorm.io/gorm"
 )
 
@@ -11,6 +15,10 @@ type WorkflowDefinitionRepository interface {
 	GetAll(pagination pagination.Pagination) (*pagination.PaginatedResult[models.WorkflowDefinition], error)
 	Search(expression expr.Expression, pagination pagination.Pagination) (*pagination.PaginatedResult[models.WorkflowDefinition], error)
 	GetByID(id string) (*models.WorkflowDefinition, error)
+	GetByNameAndVersion(name, version string) (*models.WorkflowDefinition, error)
+	GetLatestByName(name string) (*models.WorkflowDefinition, error)
+	GetVersions(familyID uuid.UUID) ([]models.WorkflowDefinition, error)
+	GetLatestPublished(familyID uuid.UUID) (*models.WorkflowDefinition, error)
 	Create(definition *models.WorkflowDefinition) (*models.WorkflowDefinition, error)
 	Update(definition *models.WorkflowDefinition) (*models.WorkflowDefinition, error)
 	Delete(id string) error
@@ -77,6 +85,60 @@ func (r *workflowDefinitionRepository) GetByID(id string) (*models.WorkflowDefin
 	return definition, nil
 }
 
+func (r *workflowDefinitionRepository) GetByNameAndVersion(name, version string) (*models.WorkflowDefinition, error) {
+	definition := &models.WorkflowDefinition{}
+	result := r.db.First(definition, "name = ? AND version = ?", name, version)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return definition, nil
+}
+
+// GetLatestByName returns the most recently created workflow definition with
+// the given name, whatever its version.
+func (r *workflowDefinitionRepository) GetLatestByName(name string) (*models.WorkflowDefinition, error) {
+	definition := &models.WorkflowDefinition{}
+	result := r.db.Order("created_at DESC").First(definition, "name = ?", name)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return definition, nil
+}
+
+// GetVersions returns the versions of a workflow family, ordered from the
+// oldest to the newest.
+func (r *workflowDefinitionRepository) GetVersions(familyID uuid.UUID) ([]models.WorkflowDefinition, error) {
+	definitions := make([]models.WorkflowDefinition, 0)
+	result := r.db.Where("family_id = ?", familyID).Find(&definitions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// Versions are ordered by semver precedence, which their text does not
+	// follow. Invalid versions come first.
+	slices.SortStableFunc(definitions, func(a, b models.WorkflowDefinition) int {
+		av, _ := semver.Parse(a.Version)
+		bv, _ := semver.Parse(b.Version)
+		return semver.Compare(av, bv)
+	})
+	return definitions, nil
+}
+
+// GetLatestPublished returns the newest published version of a workflow
+// family, or gorm.ErrRecordNotFound if it only has drafts.
+func (r *workflowDefinitionRepository) GetLatestPublished(familyID uuid.UUID) (*models.WorkflowDefinition, error) {
+	definitions, err := r.GetVersions(familyID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(definitions) - 1; i >= 0; i-- {
+		if !definitions[i].IsDraft() {
+			return &definitions[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
 func (r *workflowDefinitionRepository) Create(definition *models.WorkflowDefinition) (*models.WorkflowDefinition, error) {
 	result := r.db.Create(definition)
 	if result.Error != nil {
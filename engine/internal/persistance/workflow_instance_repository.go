@@ -0,0 +1,110 @@
+package persistance
+
+import (
+	"errors"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/utils/pagination"
+	"gorm.io/gorm"
+)
+
+type WorkflowInstanceRepository interface {
+	GetAll(pagination pagination.Pagination) (*pagination.PaginatedResult[models.WorkflowInstance], error)
+	GetByID(id string) (*models.WorkflowInstance, error)
+	GetByStatus(status models.WorkflowInstanceStatus) ([]models.WorkflowInstance, error)
+	GetByParentStepInstanceID(parentStepInstanceID string) (*models.WorkflowInstance, error)
+	CountByWorkflowDefinitionID(workflowDefinitionID string) (int64, error)
+	Create(instance *models.WorkflowInstance) (*models.WorkflowInstance, error)
+	Update(instance *models.WorkflowInstance) (*models.WorkflowInstance, error)
+}
+
+type workflowInstanceRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkflowInstanceRepository(
+	db *gorm.DB,
+) WorkflowInstanceRepository {
+	return &workflowInstanceRepository{
+		db: db,
+	}
+}
+
+func (r *workflowInstanceRepository) GetAll(
+	pg pagination.Pagination,
+) (*pagination.PaginatedResult[models.WorkflowInstance], error) {
+	var totalCount int64
+	if err := r.db.Model(&models.WorkflowInstance{}).Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	instances := make([]models.WorkflowInstance, 0)
+	result := pg.ToGorm(r.db).Order("created_at DESC").Find(&instances)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &pagination.PaginatedResult[models.WorkflowInstance]{
+		TotalCount: totalCount,
+		Items:      instances,
+	}, nil
+}
+
+func (r *workflowInstanceRepository) GetByID(id string) (*models.WorkflowInstance, error) {
+	instance := &models.WorkflowInstance{}
+	result := r.db.First(instance, "id = ?", id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return instance, nil
+}
+
+func (r *workflowInstanceRepository) GetByStatus(status models.WorkflowInstanceStatus) ([]models.WorkflowInstance, error) {
+	instances := make([]models.WorkflowInstance, 0)
+	result := r.db.Where("status = ?", status).Find(&instances)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return instances, nil
+}
+
+func (r *workflowInstanceRepository) Create(instance *models.WorkflowInstance) (*models.WorkflowInstance, error) {
+	result := r.db.Create(instance)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return instance, nil
+}
+
+func (r *workflowInstanceRepository) Update(instance *models.WorkflowInstance) (*models.WorkflowInstance, error) {
+	result := r.db.Save(instance)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return instance, nil
+}
+
+// GetByParentStepInstanceID returns the most recent child workflow instance
+// started by a workflow step, or nil if the step has not started one.
+func (r *workflowInstanceRepository) GetByParentStepInstanceID(parentStepInstanceID string) (*models.WorkflowInstance, error) {
+	instance := &models.WorkflowInstance{}
+	result := r.db.Order("created_at DESC").First(instance, "parent_step_instance_id = ?", parentStepInstanceID)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return instance, nil
+}
+
+// CountByWorkflowDefinitionID returns the number of workflow instances of a
+// workflow definition, whatever their status.
+func (r *workflowInstanceRepository) CountByWorkflowDefinitionID(workflowDefinitionID string) (int64, error) {
+	var count int64
+	result := r.db.Model(&models.WorkflowInstance{}).Where("workflow_definition_id = ?", workflowDefinitionID).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
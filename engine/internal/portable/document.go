@@ -0,0 +1,224 @@
+package portable
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/validation"
+)
+
+const (
+	// DocumentKind identifies workflow definition documents.
+	DocumentKind = "WorkflowDefinition"
+	// DocumentFormatVersion is the version of the document format written by
+	// Export. Documents of a newer format are rejected on import.
+	DocumentFormatVersion = 1
+)
+
+// Format is the encoding of a document.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// ParseFormat returns the format with the given name, or of the given media
+// type, defaulting to JSON.
+func ParseFormat(format string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "json", "application/json":
+		return FormatJSON, nil
+	case "yaml", "yml", "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("unsupported document format %q", format)
+	}
+}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	if f == FormatYAML {
+		return "application/yaml"
+	}
+	return "application/json"
+}
+
+// WorkflowReference names a workflow definition by name and version, which
+// unlike its ID are the same in every environment.
+type WorkflowReference struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+} // @name WorkflowReference
+
+// Document is a workflow definition that can be kept in git and promoted
+// between environments. It carries none of the IDs, timestamps and state of
+// the environment it was exported from: the workflow definitions run by
+// workflow steps are named in WorkflowReferences, keyed by step ID, and the
+// workflowDefinitionId of those steps is left empty.
+type Document struct {
+	FormatVersion      int                                     `json:"formatVersion"`
+	Kind               string                                  `json:"kind"`
+	Name               string                                  `json:"name"`
+	Description        string                                  `json:"description,omitempty"`
+	Version            string                                  `json:"version"`
+	InputParameters    *models.WorkflowParameterDefinitionList `json:"inputParameters,omitempty"`
+	OutputParameters   *interface{}                            `json:"outputParameters,omitempty"`
+	Steps              *models.WorkflowStepDefinitionList      `json:"steps"`
+	TimeoutSeconds     *int                                    `json:"timeoutSeconds,omitempty"`
+	Metadata           *map[string]interface{}                 `json:"metadata,omitempty"`
+	WorkflowReferences map[string]WorkflowReference            `json:"workflowReferences,omitempty"`
+} // @name WorkflowDefinitionDocument
+
+// DefinitionLookup returns the workflow definition with the given ID, or nil
+// if it does not exist.
+type DefinitionLookup func(id string) (*models.WorkflowDefinition, error)
+
+// ReferenceLookup returns the workflow definition with the given name and
+// version, or nil if it does not exist.
+type ReferenceLookup func(name, version string) (*models.WorkflowDefinition, error)
+
+// Export returns the document of a workflow definition, naming the workflow
+// definitions its workflow steps run.
+func Export(definition *models.WorkflowDefinition, lookup DefinitionLookup) (*Document, error) {
+	document := &Document{
+		FormatVersion:    DocumentFormatVersion,
+		Kind:             DocumentKind,
+		Name:             definition.Name,
+		Description:      definition.Description,
+		Version:          definition.Version,
+		InputParameters:  definition.InputParameters,
+		OutputParameters: definition.OutputParameters,
+		TimeoutSeconds:   definition.TimeoutSeconds,
+		Metadata:         definition.Metadata,
+	}
+
+	steps, err := copySteps(definition.Steps)
+	if err != nil {
+		return nil, err
+	}
+	for i, step := range steps {
+		if step.Type != models.StepTypeWorkflow || step.WorkflowConfig == nil {
+			continue
+		}
+		referenced, err := lookup(step.WorkflowConfig.WorkflowDefinitionID)
+		if err != nil {
+			return nil, err
+		}
+		if referenced == nil {
+			return nil, fmt.Errorf("%w: step %s runs unknown workflow definition %s", errors.ErrWorkflowDefinitionNotFound, step.StepDefinitionID, step.WorkflowConfig.WorkflowDefinitionID)
+		}
+		if document.WorkflowReferences == nil {
+			document.WorkflowReferences = make(map[string]WorkflowReference)
+		}
+		document.WorkflowReferences[step.StepDefinitionID] = WorkflowReference{Name: referenced.Name, Version: referenced.Version}
+		steps[i].WorkflowConfig.WorkflowDefinitionID = ""
+	}
+	document.Steps = &steps
+	return document, nil
+}
+
+// Definition returns the workflow definition described by the document, with
+// the workflow definitions run by its workflow steps resolved in the current
+// environment. Unresolved references are reported as a validation.ValidationError.
+func (d *Document) Definition(lookup ReferenceLookup) (*models.WorkflowDefinition, error) {
+	if d.Kind != DocumentKind {
+		return nil, fmt.Errorf("%w: unsupported kind %q", errors.ErrInvalidWorkflowDocument, d.Kind)
+	}
+	if d.FormatVersion < 1 || d.FormatVersion > DocumentFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", errors.ErrInvalidWorkflowDocument, d.FormatVersion)
+	}
+
+	steps, err := copySteps(d.Steps)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []validation.Violation
+	for i, step := range steps {
+		if step.Type != models.StepTypeWorkflow || step.WorkflowConfig == nil {
+			continue
+		}
+		ref, named := d.WorkflowReferences[step.StepDefinitionID]
+		if !named {
+			violations = append(violations, validation.Violation{
+				StepID:  step.StepDefinitionID,
+				Field:   "workflowConfig.workflowDefinitionId",
+				Message: "the workflow definition run by the step is missing from workflowReferences",
+			})
+			continue
+		}
+		referenced, err := lookup(ref.Name, ref.Version)
+		if err != nil {
+			return nil, err
+		}
+		if referenced == nil {
+			violations = append(violations, validation.Violation{
+				StepID:  step.StepDefinitionID,
+				Field:   "workflowConfig.workflowDefinitionId",
+				Message: fmt.Sprintf("no workflow definition %s version %s", ref.Name, ref.Version),
+			})
+			continue
+		}
+		steps[i].WorkflowConfig.WorkflowDefinitionID = referenced.ID.String()
+	}
+	if len(violations) > 0 {
+		return nil, &validation.ValidationError{Violations: violations}
+	}
+
+	return &models.WorkflowDefinition{
+		Name:             d.Name,
+		Description:      d.Description,
+		Version:          d.Version,
+		InputParameters:  d.InputParameters,
+		OutputParameters: d.OutputParameters,
+		Steps:            &steps,
+		TimeoutSeconds:   d.TimeoutSeconds,
+		Metadata:         d.Metadata,
+	}, nil
+}
+
+// Marshal encodes a document in the given format.
+func Marshal(document *Document, format Format) ([]byte, error) {
+	bytes, err := json.MarshalIndent(document, "", "  ")
+	if err != nil || format != FormatYAML {
+		return bytes, err
+	}
+	return yaml.JSONToYAML(bytes)
+}
+
+// Unmarshal decodes a document in the given format.
+func Unmarshal(data []byte, format Format) (*Document, error) {
+	if format == FormatYAML {
+		var err error
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidWorkflowDocument, err)
+		}
+	}
+	var document Document
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrInvalidWorkflowDocument, err)
+	}
+	return &document, nil
+}
+
+// copySteps returns a deep copy of the steps, so that their references can be
+// rewritten without touching the original.
+func copySteps(steps *models.WorkflowStepDefinitionList) (models.WorkflowStepDefinitionList, error) {
+	copied := make(models.WorkflowStepDefinitionList, 0)
+	if steps == nil {
+		return copied, nil
+	}
+	bytes, err := json.Marshal(steps)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bytes, &copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
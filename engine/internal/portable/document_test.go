@@ -0,0 +1,147 @@
+package portable
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/validation"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func testDefinitions() (parent, child *models.WorkflowDefinition) {
+	child = &models.WorkflowDefinition{ID: uuid.New(), Name: "notify", Version: "1.0.0"}
+	steps := models.WorkflowStepDefinitionList{
+		{
+			StepDefinitionID: "fetch",
+			Name:             "Fetch",
+			Type:             models.StepTypeTask,
+			TaskConfig:       &models.TaskConfig{TaskDefinitionID: "fetch-order", NextStepID: ptr("notify")},
+			Parameters: &models.StepDefinitionParameters{
+				"orderId": {Type: models.StepParameterTypeWorkflow, Value: "orderId"},
+			},
+		},
+		{
+			StepDefinitionID: "notify",
+			Name:             "Notify",
+			Type:             models.StepTypeWorkflow,
+			WorkflowConfig:   &models.WorkflowConfig{WorkflowDefinitionID: child.ID.String()},
+		},
+	}
+	parent = &models.WorkflowDefinition{
+		ID:              uuid.New(),
+		FamilyID:        uuid.New(),
+		Name:            "orders",
+		Version:         "1.2.0",
+		IsEnabled:       true,
+		InputParameters: &models.WorkflowParameterDefinitionList{{Name: "orderId", Type: "string", Required: true}},
+		Steps:           &steps,
+	}
+	return parent, child
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	parent, child := testDefinitions()
+	byID := func(id string) (*models.WorkflowDefinition, error) {
+		if id == child.ID.String() {
+			return child, nil
+		}
+		return nil, nil
+	}
+
+	document, err := Export(parent, byID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (*parent.Steps)[1].WorkflowConfig.WorkflowDefinitionID != child.ID.String() {
+		t.Fatalf("expected the exported definition to be left untouched")
+	}
+
+	for _, format := range []Format{FormatJSON, FormatYAML} {
+		data, err := Marshal(document, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), child.ID.String()) || strings.Contains(string(data), parent.ID.String()) {
+			t.Fatalf("expected the %s document to carry no IDs, got %s", format, data)
+		}
+
+		decoded, err := Unmarshal(data, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The child workflow has another ID in the target environment.
+		promoted := *child
+		promoted.ID = uuid.New()
+		definition, err := decoded.Definition(func(name, version string) (*models.WorkflowDefinition, error) {
+			if name == child.Name && version == child.Version {
+				return &promoted, nil
+			}
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if definition.Name != parent.Name || definition.Version != parent.Version || definition.ID != uuid.Nil || definition.IsEnabled {
+			t.Fatalf("expected the %s document to carry the definition without its state, got %+v", format, definition)
+		}
+		steps := *definition.Steps
+		if len(steps) != 2 || (*steps[0].Parameters)["orderId"].Value != "orderId" {
+			t.Fatalf("expected the steps to survive the %s round trip, got %+v", format, steps)
+		}
+		if steps[1].WorkflowConfig.WorkflowDefinitionID != promoted.ID.String() {
+			t.Fatalf("expected the workflow step to run %s, got %s", promoted.ID, steps[1].WorkflowConfig.WorkflowDefinitionID)
+		}
+	}
+}
+
+func TestImportUnresolvedReference(t *testing.T) {
+	document, err := Unmarshal([]byte(`
+formatVersion: 1
+kind: WorkflowDefinition
+name: orders
+version: 1.0.0
+steps:
+  - stepDefinitionId: notify
+    name: Notify
+    type: workflow
+    workflowConfig:
+      workflowDefinitionId: ""
+workflowReferences:
+  notify:
+    name: notify
+    version: 2.0.0
+`), FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = document.Definition(func(string, string) (*models.WorkflowDefinition, error) { return nil, nil })
+	var validationErr *validation.ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Violations) != 1 || validationErr.Violations[0].StepID != "notify" {
+		t.Fatalf("expected a violation on the notify step, got %v", err)
+	}
+}
+
+func TestImportUnsupportedDocument(t *testing.T) {
+	for _, source := range []string{
+		`{"formatVersion": 2, "kind": "WorkflowDefinition", "name": "orders", "version": "1.0.0", "steps": []}`,
+		`{"formatVersion": 1, "kind": "Agent", "name": "orders", "version": "1.0.0", "steps": []}`,
+	} {
+		document, err := Unmarshal([]byte(source), FormatJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := document.Definition(nil); !errors.Is(err, engineErrors.ErrInvalidWorkflowDocument) {
+			t.Fatalf("expected %s to be rejected, got %v", source, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package registry
+
+import "log"
+
+// subscriberBuffer is the number of events a subscriber can lag behind before
+// it misses events.
+const subscriberBuffer = 64
+
+type AgentEventType string
+
+const (
+	AgentEventRegistered    AgentEventType = "registered"
+	AgentEventUnregistered  AgentEventType = "unregistered"
+	AgentEventStatusChanged AgentEventType = "statusChanged"
+)
+
+// AgentEvent describes a change made to the registry. Agent is a copy of the
+// agent once the change was applied, or before it was removed.
+type AgentEvent struct {
+	Type  AgentEventType
+	Agent RegisteredAgent
+}
+
+// Subscribe returns a channel receiving the changes made to the registry from
+// now on, in the order they were made, and a function ending the subscription
+// and closing the channel. The registry never waits for a subscriber: one
+// that does not drain its channel misses the events that do not fit in it.
+func (ar *AgentRegistry) Subscribe() (<-chan AgentEvent, func()) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	id := ar.nextSubscriberID
+	ar.nextSubscriberID++
+	events := make(chan AgentEvent, subscriberBuffer)
+	ar.subscribers[id] = events
+
+	return events, func() {
+		ar.mu.Lock()
+		defer ar.mu.Unlock()
+
+		if _, subscribed := ar.subscribers[id]; subscribed {
+			delete(ar.subscribers, id)
+			close(events)
+		}
+	}
+}
+
+// publish sends an event to every subscriber. The caller must hold the write
+// lock, so events are delivered in the order the changes were made and never
+// sent on the channel of a subscription that ended.
+func (ar *AgentRegistry) publish(eventType AgentEventType, agent RegisteredAgent) {
+	event := AgentEvent{
+		Type:  eventType,
+		Agent: *agent.clone(),
+	}
+	for id, events := range ar.subscribers {
+		select {
+		case events <- event:
+		default:
+			log.Printf("[registry] subscriber %d is not keeping up, dropping %s event of agent %s", id, eventType, agent.Name)
+		}
+	}
+}
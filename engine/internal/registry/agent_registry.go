@@ -1,42 +1,110 @@
 package registry
 
 import (
+	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/connector"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
 	"github.com/paulhalleux/workflow-engine-go/proto"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
+// AgentStatus tells whether an agent answers the pings of the engine.
+type AgentStatus string
+
+const (
+	// AgentStatusHealthy agents answered their last ping.
+	AgentStatusHealthy AgentStatus = "healthy"
+	// AgentStatusDegraded agents missed their last pings but are still used.
+	AgentStatusDegraded AgentStatus = "degraded"
+	// AgentStatusOffline agents missed too many pings. No task is routed to
+	// them until they answer again.
+	AgentStatusOffline AgentStatus = "offline"
+)
+
+// RegisteredAgent is an agent known to the registry. The registry only hands
+// out copies of its agents, which callers are free to keep. Their task
+// definitions are shared and must not be modified.
 type RegisteredAgent struct {
-	Name           string                  `json:"name"`
-	Version        string                  `json:"version"`
-	Address        *string                 `json:"address,omitempty"`
-	Port           string                  `json:"port"`
-	Protocol       proto.AgentProtocol     `json:"protocol"`
-	SupportedTasks []*proto.TaskDefinition `json:"supportedTasks"`
+	Name                string                  `json:"name"`
+	Version             string                  `json:"version"`
+	Address             *string                 `json:"address,omitempty"`
+	Port                string                  `json:"port"`
+	Protocol            proto.AgentProtocol     `json:"protocol"`
+	SupportedTasks      []*proto.TaskDefinition `json:"supportedTasks"`
+	Weight              int                     `json:"weight"`
+	Status              AgentStatus             `json:"status"`
+	LastSeenAt          time.Time               `json:"lastSeenAt"`
+	ConsecutiveFailures int                     `json:"consecutiveFailures"`
+	InFlightTasks       int                     `json:"inFlightTasks"`
+	// Restored agents were restored from storage and did not register since,
+	// so their registration may be stale.
+	Restored bool `json:"restored"`
 }
 
 type RegisteredAgentsList []*RegisteredAgent
 
+// Snapshot is a consistent copy of the registry at a point in time. It shares
+// nothing the registry modifies afterwards.
+type Snapshot struct {
+	// Agents are the registered agents, sorted by name.
+	Agents RegisteredAgentsList
+	// AgentsByTask lists the online agents supporting each task definition.
+	AgentsByTask map[string][]string
+}
+
+// AgentRegistry keeps track of the agents connected to the engine. It is safe
+// for concurrent use: every method holds the registry lock, and changes are
+// published to subscribers while it is held, so they observe them in order.
 type AgentRegistry struct {
-	agents           map[string]RegisteredAgent
-	tasks            map[string]*proto.TaskDefinition
-	agentByTask      map[string]string
+	mu       sync.RWMutex
+	balancer Balancer
+	agents   map[string]RegisteredAgent
+	tasks    map[string]*proto.TaskDefinition
+	// agentByTask lists the online agents supporting each task definition,
+	// in registration order.
+	agentByTask      map[string][]string
 	agentsConnectors map[string]*connector.AgentConnector
+	// agentByAgentTask is the agent running each in-flight agent task.
+	agentByAgentTask map[string]string
+
+	subscribers      map[int]chan AgentEvent
+	nextSubscriberID int
+
+	agentRepo    persistance.AgentRepository
+	newConnector func(protocol proto.AgentProtocol, address *string, port string) (connector.AgentConnector, error)
 }
 
-func NewAgentRegistry() *AgentRegistry {
+// NewAgentRegistry creates an empty registry. The balancer chooses between
+// the agents supporting a task definition when a task is dispatched, and
+// registrations are stored through agentRepo so they can be restored.
+func NewAgentRegistry(balancer Balancer, agentRepo persistance.AgentRepository) *AgentRegistry {
 	return &AgentRegistry{
+		balancer:         balancer,
 		agents:           make(map[string]RegisteredAgent),
 		tasks:            make(map[string]*proto.TaskDefinition),
-		agentByTask:      make(map[string]string),
+		agentByTask:      make(map[string][]string),
 		agentsConnectors: make(map[string]*connector.AgentConnector),
+		agentByAgentTask: make(map[string]string),
+		subscribers:      make(map[int]chan AgentEvent),
+		agentRepo:        agentRepo,
+		newConnector:     connector.NewAgentConnector,
 	}
 }
 
+// RegisterAgent adds an agent to the registry, or replaces the registration
+// of an agent with the same name. Several agents can support the same task
+// definition, but they must declare identical definitions: an agent declaring
+// a task that conflicts with the definition of another agent is rejected.
 func (ar *AgentRegistry) RegisterAgent(name string, agent RegisteredAgent) error {
 	log.Printf("[registry] registering agent %s at %v:%s using protocol %s", name, agent.Address, agent.Port, agent.Protocol.String())
-	agentConnector, err := connector.NewAgentConnector(agent.Protocol, agent.Address, agent.Port)
+	agentConnector, err := ar.newConnector(agent.Protocol, agent.Address, agent.Port)
 	if err == nil {
 		err := agentConnector.Ping()
 		if err != nil {
@@ -44,12 +112,32 @@ func (ar *AgentRegistry) RegisterAgent(name string, agent RegisteredAgent) error
 			return err
 		}
 
-		ar.agents[name] = agent
-		ar.agentsConnectors[name] = &agentConnector
+		agent.Name = name
+		agent.SupportedTasks = slices.Clone(agent.SupportedTasks)
+		agent.Weight = max(agent.Weight, 1)
+		agent.Status = AgentStatusHealthy
+		agent.LastSeenAt = time.Now()
+		agent.ConsecutiveFailures = 0
+		agent.InFlightTasks = 0
+		agent.Restored = false
 
-		for _, taskDef := range agent.SupportedTasks {
-			ar.tasks[taskDef.Id] = taskDef
-			ar.agentByTask[taskDef.Id] = name
+		ar.mu.Lock()
+		if err := ar.checkConflicts(name, agent.SupportedTasks); err != nil {
+			ar.mu.Unlock()
+			_ = agentConnector.Close()
+			log.Printf("[registry] failed to register agent %s: %v", name, err)
+			return err
+		}
+		previous, replaced := ar.agentsConnectors[name]
+		if existing, found := ar.agents[name]; found {
+			agent.InFlightTasks = existing.InFlightTasks
+		}
+		ar.add(agent, agentConnector)
+		ar.persist(agent)
+		ar.mu.Unlock()
+
+		if replaced {
+			_ = (*previous).Close()
 		}
 
 		log.Printf("[registry] registered agent %s at %v:%s using protocol %s", name, agent.Address, agent.Port, agent.Protocol.String())
@@ -59,31 +147,309 @@ func (ar *AgentRegistry) RegisterAgent(name string, agent RegisteredAgent) error
 	return err
 }
 
+// GetAgent returns a copy of the agent with the given name.
 func (ar *AgentRegistry) GetAgent(name string) (*RegisteredAgent, bool) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
 	agent, exists := ar.agents[name]
-	return &agent, exists
+	if !exists {
+		return nil, false
+	}
+	return agent.clone(), true
 }
 
 func (ar *AgentRegistry) GetAgentConnector(name string) (*connector.AgentConnector, bool) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
 	conn, exists := ar.agentsConnectors[name]
 	return conn, exists
 }
 
+// GetAgentByTask returns the agent a task of the given task definition must
+// be dispatched to, as chosen by the balancer among the online agents that
+// support it.
+func (ar *AgentRegistry) GetAgentByTask(taskId string) (*RegisteredAgent, bool) {
+	candidates := ar.GetAgentsByTask(taskId)
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	return ar.balancer.Pick(taskId, candidates), true
+}
+
+// GetAgentsByTask returns copies of the online agents that support the given
+// task definition.
+func (ar *AgentRegistry) GetAgentsByTask(taskId string) RegisteredAgentsList {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	names := ar.agentByTask[taskId]
+	agents := make([]*RegisteredAgent, 0, len(names))
+	for _, name := range names {
+		agent := ar.agents[name]
+		agents = append(agents, agent.clone())
+	}
+	return agents
+}
+
+// GetTaskDefinition returns the definition of a task as declared by the known
+// agents, offline ones included. Agents supporting the same task declare the
+// same definition, since conflicting ones are refused at registration.
+func (ar *AgentRegistry) GetTaskDefinition(taskId string) (*proto.TaskDefinition, bool) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	for _, agent := range ar.agents {
+		for _, taskDef := range agent.SupportedTasks {
+			if taskDef.Id == taskId {
+				return protobuf.Clone(taskDef).(*proto.TaskDefinition), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// UnregisterAgent removes an agent from the registry and closes its
+// connector. It is a no-op for unknown agents.
 func (ar *AgentRegistry) UnregisterAgent(name string) {
+	ar.mu.Lock()
+	agent, exists := ar.agents[name]
+	if !exists {
+		ar.mu.Unlock()
+		return
+	}
+	agentConnector := ar.agentsConnectors[name]
 	delete(ar.agents, name)
 	delete(ar.agentsConnectors, name)
-	for taskId, agentName := range ar.agentByTask {
-		if agentName == name {
-			delete(ar.tasks, taskId)
-			delete(ar.agentByTask, taskId)
+	ar.removeRoutes(name)
+	for _, taskDef := range agent.SupportedTasks {
+		if !ar.isSupported(taskDef.Id) {
+			delete(ar.tasks, taskDef.Id)
 		}
 	}
+	ar.forgetTasks(name)
+	ar.publish(AgentEventUnregistered, agent)
+	if err := ar.agentRepo.Delete(name); err != nil {
+		log.Printf("[registry] failed to delete stored agent %s: %v", name, err)
+	}
+	ar.mu.Unlock()
+
+	if agentConnector != nil {
+		_ = (*agentConnector).Close()
+	}
+	log.Printf("[registry] unregistered agent %s", name)
 }
 
+// ListAgents returns copies of the registered agents, sorted by name.
 func (ar *AgentRegistry) ListAgents() RegisteredAgentsList {
-	agents := make([]*RegisteredAgent, 0, len(ar.agents))
+	return ar.Snapshot().Agents
+}
+
+// Snapshot returns a consistent copy of the registry.
+func (ar *AgentRegistry) Snapshot() *Snapshot {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	names := make([]string, 0, len(ar.agents))
+	for name := range ar.agents {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	snapshot := &Snapshot{
+		Agents:       make([]*RegisteredAgent, 0, len(names)),
+		AgentsByTask: make(map[string][]string, len(ar.agentByTask)),
+	}
+	for _, name := range names {
+		agent := ar.agents[name]
+		snapshot.Agents = append(snapshot.Agents, agent.clone())
+	}
+	for taskId, agentNames := range ar.agentByTask {
+		snapshot.AgentsByTask[taskId] = slices.Clone(agentNames)
+	}
+	return snapshot
+}
+
+// TrackTask records that an agent started a task, so it counts towards the
+// tasks in flight on the agent until it is released.
+func (ar *AgentRegistry) TrackTask(agentName string, agentTaskId string) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	agent, exists := ar.agents[agentName]
+	if !exists {
+		return
+	}
+	if _, tracked := ar.agentByAgentTask[agentTaskId]; tracked {
+		return
+	}
+	ar.agentByAgentTask[agentTaskId] = agentName
+	agent.InFlightTasks++
+	ar.agents[agentName] = agent
+}
+
+// ReleaseTask records that a task is no longer running on its agent. It is a
+// no-op for tasks that are not tracked.
+func (ar *AgentRegistry) ReleaseTask(agentTaskId string) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	agentName, tracked := ar.agentByAgentTask[agentTaskId]
+	if !tracked {
+		return
+	}
+	delete(ar.agentByAgentTask, agentTaskId)
+	if agent, exists := ar.agents[agentName]; exists {
+		agent.InFlightTasks = max(agent.InFlightTasks-1, 0)
+		ar.agents[agentName] = agent
+	}
+}
+
+// add stores an agent and its connector, replacing any agent with the same
+// name. Tasks are routed to the agent unless it is offline. The caller must
+// hold the write lock.
+func (ar *AgentRegistry) add(agent RegisteredAgent, agentConnector connector.AgentConnector) {
+	ar.removeRoutes(agent.Name)
+	ar.agents[agent.Name] = agent
+	ar.agentsConnectors[agent.Name] = &agentConnector
+
+	for _, taskDef := range agent.SupportedTasks {
+		ar.tasks[taskDef.Id] = taskDef
+		if agent.Status != AgentStatusOffline {
+			ar.agentByTask[taskDef.Id] = append(ar.agentByTask[taskDef.Id], agent.Name)
+		}
+	}
+	ar.publish(AgentEventRegistered, agent)
+}
+
+// checkConflicts returns an error listing the supported tasks of an agent
+// whose definition differs from the one declared by another agent. The caller
+// must hold the lock.
+func (ar *AgentRegistry) checkConflicts(name string, supportedTasks []*proto.TaskDefinition) error {
+	conflicts := make([]string, 0)
+	for _, taskDef := range supportedTasks {
+		for otherName, other := range ar.agents {
+			if otherName == name {
+				continue
+			}
+			index := slices.IndexFunc(other.SupportedTasks, func(def *proto.TaskDefinition) bool {
+				return def.Id == taskDef.Id
+			})
+			if index >= 0 && !protobuf.Equal(other.SupportedTasks[index], taskDef) {
+				conflicts = append(conflicts, fmt.Sprintf("%s (declared differently by agent %s)", taskDef.Id, otherName))
+				break
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s", engineErrors.ErrConflictingTaskDefinition, strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// removeRoutes stops routing tasks to an agent. The caller must hold the
+// write lock.
+func (ar *AgentRegistry) removeRoutes(name string) {
+	for taskId, names := range ar.agentByTask {
+		names = slices.DeleteFunc(names, func(agentName string) bool {
+			return agentName == name
+		})
+		if len(names) == 0 {
+			delete(ar.agentByTask, taskId)
+		} else {
+			ar.agentByTask[taskId] = names
+		}
+	}
+}
+
+// forgetTasks forgets the tasks in flight on an agent. The caller must hold
+// the write lock.
+func (ar *AgentRegistry) forgetTasks(name string) {
+	for agentTaskId, agentName := range ar.agentByAgentTask {
+		if agentName == name {
+			delete(ar.agentByAgentTask, agentTaskId)
+		}
+	}
+}
+
+// isSupported reports whether any registered agent supports a task
+// definition. The caller must hold the lock.
+func (ar *AgentRegistry) isSupported(taskId string) bool {
 	for _, agent := range ar.agents {
-		agents = append(agents, &agent)
+		for _, taskDef := range agent.SupportedTasks {
+			if taskDef.Id == taskId {
+				return true
+			}
+		}
 	}
-	return agents
+	return false
+}
+
+// recordPing updates the liveness of an agent after a ping. An agent is
+// degraded as soon as it misses a ping, and goes offline once it missed
+// offlineAfter pings in a row. Tasks are no longer routed to offline agents,
+// and are routed to them again once they answer. The tasks in flight on an
+// agent that goes offline are forgotten. It returns the new status of the
+// agent and whether it changed.
+func (ar *AgentRegistry) recordPing(name string, pingErr error, offlineAfter int) (AgentStatus, bool) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	agent, exists := ar.agents[name]
+	if !exists {
+		return "", false
+	}
+	previous := agent.Status
+
+	if pingErr == nil {
+		agent.Status = AgentStatusHealthy
+		agent.LastSeenAt = time.Now()
+		agent.ConsecutiveFailures = 0
+	} else {
+		agent.ConsecutiveFailures++
+		agent.Status = AgentStatusDegraded
+		if agent.ConsecutiveFailures >= offlineAfter {
+			agent.Status = AgentStatusOffline
+		}
+	}
+
+	if agent.Status != previous {
+		switch {
+		case agent.Status == AgentStatusOffline:
+			ar.removeRoutes(name)
+			ar.forgetTasks(name)
+			agent.InFlightTasks = 0
+		case previous == AgentStatusOffline:
+			for _, taskDef := range agent.SupportedTasks {
+				ar.agentByTask[taskDef.Id] = append(ar.agentByTask[taskDef.Id], name)
+			}
+		}
+	}
+	ar.agents[name] = agent
+
+	if agent.Status == previous {
+		return agent.Status, false
+	}
+	ar.publish(AgentEventStatusChanged, agent)
+	return agent.Status, true
+}
+
+// connectors returns the connectors of every registered agent, keyed by agent
+// name.
+func (ar *AgentRegistry) connectors() map[string]connector.AgentConnector {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	connectors := make(map[string]connector.AgentConnector, len(ar.agentsConnectors))
+	for name, conn := range ar.agentsConnectors {
+		connectors[name] = *conn
+	}
+	return connectors
+}
+
+func (a RegisteredAgent) clone() *RegisteredAgent {
+	a.SupportedTasks = slices.Clone(a.SupportedTasks)
+	return &a
 }
@@ -0,0 +1,391 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/connector"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+// fakeConnector answers pings without connecting to an agent.
+type fakeConnector struct {
+	connector.AgentConnector
+	pingErr error
+}
+
+func (c fakeConnector) Ping() error { return c.pingErr }
+func (fakeConnector) Close() error  { return nil }
+
+// memoryAgentRepository stores agents in memory.
+type memoryAgentRepository struct {
+	mu            sync.Mutex
+	agents        map[string]models.Agent
+	registrations []models.AgentRegistration
+}
+
+func newMemoryAgentRepository() *memoryAgentRepository {
+	return &memoryAgentRepository{agents: make(map[string]models.Agent)}
+}
+
+func (r *memoryAgentRepository) GetAll() ([]models.Agent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	agents := make([]models.Agent, 0, len(r.agents))
+	for _, agent := range r.agents {
+		agents = append(agents, agent)
+	}
+	return agents, nil
+}
+
+func (r *memoryAgentRepository) GetByName(name string) (*models.Agent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	agent, found := r.agents[name]
+	if !found {
+		return nil, nil
+	}
+	return &agent, nil
+}
+
+func (r *memoryAgentRepository) Save(agent *models.Agent) (*models.Agent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.agents[agent.Name] = *agent
+	return agent, nil
+}
+
+func (r *memoryAgentRepository) Delete(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.agents, name)
+	return nil
+}
+
+func (r *memoryAgentRepository) GetRegistrations(agentName string) ([]models.AgentRegistration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	registrations := make([]models.AgentRegistration, 0)
+	for _, registration := range r.registrations {
+		if registration.AgentName == agentName {
+			registrations = append(registrations, registration)
+		}
+	}
+	return registrations, nil
+}
+
+func (r *memoryAgentRepository) CreateRegistration(registration *models.AgentRegistration) (*models.AgentRegistration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registrations = append(r.registrations, *registration)
+	return registration, nil
+}
+
+func newTestRegistry() *AgentRegistry {
+	return newTestRegistryWithRepo(newMemoryAgentRepository(), nil)
+}
+
+// newTestRegistryWithRepo creates a registry storing agents in agentRepo,
+// whose agents fail to answer pings if they are listed in unreachable.
+func newTestRegistryWithRepo(agentRepo persistance.AgentRepository, unreachable map[string]bool) *AgentRegistry {
+	registry := NewAgentRegistry(NewRoundRobinBalancer(), agentRepo)
+	registry.newConnector = func(_ proto.AgentProtocol, _ *string, port string) (connector.AgentConnector, error) {
+		if unreachable[port] {
+			return fakeConnector{pingErr: errors.New("unreachable")}, nil
+		}
+		return fakeConnector{}, nil
+	}
+	return registry
+}
+
+func testAgent(name string, taskIds ...string) RegisteredAgent {
+	agent := RegisteredAgent{
+		Name:     name,
+		Version:  "v1.0.0",
+		Port:     "50051",
+		Protocol: proto.AGENT_PROTOCOL_GRPC,
+	}
+	for _, taskId := range taskIds {
+		agent.SupportedTasks = append(agent.SupportedTasks, &proto.TaskDefinition{Id: taskId, Name: taskId})
+	}
+	return agent
+}
+
+func TestConcurrentRegisterUnregisterList(t *testing.T) {
+	registry := newTestRegistry()
+	events, unsubscribe := registry.Subscribe()
+	defer unsubscribe()
+	go func() {
+		for range events {
+		}
+	}()
+
+	const workers = 16
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		name := fmt.Sprintf("agent-%d", i)
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if err := registry.RegisterAgent(name, testAgent(name, "echo")); err != nil {
+					t.Errorf("register %s: %v", name, err)
+					return
+				}
+				if j%2 == 0 {
+					registry.UnregisterAgent(name)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				for _, agent := range registry.ListAgents() {
+					agent.SupportedTasks = nil
+				}
+				registry.Snapshot()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if agent, found := registry.GetAgentByTask("echo"); found {
+					taskId := fmt.Sprintf("%s-%d", name, j)
+					registry.TrackTask(agent.Name, taskId)
+					registry.ReleaseTask(taskId)
+				}
+				registry.GetAgent(name)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				registry.recordPing(name, errors.New("unreachable"), 3)
+				registry.recordPing(name, nil, 3)
+			}
+		}()
+	}
+	wg.Wait()
+
+	snapshot := registry.Snapshot()
+	if len(snapshot.Agents) != workers {
+		t.Fatalf("expected %d agents, got %d", workers, len(snapshot.Agents))
+	}
+	if len(snapshot.AgentsByTask["echo"]) != workers {
+		t.Fatalf("expected %d agents routed for echo, got %v", workers, snapshot.AgentsByTask["echo"])
+	}
+	for _, agent := range snapshot.Agents {
+		if len(agent.SupportedTasks) != 1 {
+			t.Fatalf("expected agent %s to keep its task, got %v", agent.Name, agent.SupportedTasks)
+		}
+		if agent.InFlightTasks != 0 {
+			t.Fatalf("expected no task in flight on agent %s, got %d", agent.Name, agent.InFlightTasks)
+		}
+	}
+}
+
+func TestSnapshotIsIsolated(t *testing.T) {
+	registry := newTestRegistry()
+	if err := registry.RegisterAgent("a", testAgent("a", "echo")); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := registry.Snapshot()
+	if err := registry.RegisterAgent("b", testAgent("b", "echo")); err != nil {
+		t.Fatal(err)
+	}
+	registry.recordPing("a", errors.New("unreachable"), 1)
+
+	if len(snapshot.Agents) != 1 || snapshot.Agents[0].Status != AgentStatusHealthy {
+		t.Fatalf("expected the snapshot to keep a single healthy agent, got %+v", snapshot.Agents)
+	}
+	if routed := snapshot.AgentsByTask["echo"]; len(routed) != 1 || routed[0] != "a" {
+		t.Fatalf("expected the snapshot to route echo to a, got %v", routed)
+	}
+	if routed := registry.Snapshot().AgentsByTask["echo"]; len(routed) != 1 || routed[0] != "b" {
+		t.Fatalf("expected echo to be routed to b only, got %v", routed)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	registry := newTestRegistry()
+	events, unsubscribe := registry.Subscribe()
+
+	if err := registry.RegisterAgent("a", testAgent("a", "echo")); err != nil {
+		t.Fatal(err)
+	}
+	registry.recordPing("a", errors.New("unreachable"), 1)
+	registry.UnregisterAgent("a")
+	unsubscribe()
+
+	expected := []struct {
+		eventType AgentEventType
+		status    AgentStatus
+	}{
+		{AgentEventRegistered, AgentStatusHealthy},
+		{AgentEventStatusChanged, AgentStatusOffline},
+		{AgentEventUnregistered, AgentStatusOffline},
+	}
+	for _, want := range expected {
+		event, ok := <-events
+		if !ok {
+			t.Fatalf("expected a %s event, the channel is closed", want.eventType)
+		}
+		if event.Type != want.eventType || event.Agent.Name != "a" || event.Agent.Status != want.status {
+			t.Fatalf("expected %s event with status %s, got %+v", want.eventType, want.status, event)
+		}
+	}
+	if _, ok := <-events; ok {
+		t.Fatal("expected the channel to be closed once unsubscribed")
+	}
+}
+
+func TestRegisterConflictingTaskDefinition(t *testing.T) {
+	registry := newTestRegistry()
+	if err := registry.RegisterAgent("a", testAgent("a", "echo")); err != nil {
+		t.Fatal(err)
+	}
+
+	conflicting := testAgent("b", "echo")
+	conflicting.SupportedTasks[0].Description = "another echo"
+	err := registry.RegisterAgent("b", conflicting)
+	if !errors.Is(err, engineErrors.ErrConflictingTaskDefinition) {
+		t.Fatalf("expected a conflicting task definition error, got %v", err)
+	}
+	if _, found := registry.GetAgent("b"); found {
+		t.Fatal("expected the conflicting agent not to be registered")
+	}
+}
+
+func TestRegistrationHistory(t *testing.T) {
+	agentRepo := newMemoryAgentRepository()
+	registry := newTestRegistryWithRepo(agentRepo, nil)
+
+	agent := testAgent("a", "echo")
+	if err := registry.RegisterAgent("a", agent); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterAgent("a", agent); err != nil {
+		t.Fatal(err)
+	}
+	upgraded := testAgent("a", "echo", "reverse")
+	upgraded.Version = "v1.1.0"
+	if err := registry.RegisterAgent("a", upgraded); err != nil {
+		t.Fatal(err)
+	}
+
+	registrations, _ := agentRepo.GetRegistrations("a")
+	if len(registrations) != 3 {
+		t.Fatalf("expected 3 registrations, got %d", len(registrations))
+	}
+	if registrations[0].PreviousVersion != nil || registrations[0].ContractChanged {
+		t.Fatalf("expected the first registration to have no previous version, got %+v", registrations[0])
+	}
+	if registrations[1].ContractChanged {
+		t.Fatal("expected the contract not to change when registering the same tasks again")
+	}
+	if !registrations[2].ContractChanged || *registrations[2].PreviousVersion != "v1.0.0" || registrations[2].Version != "v1.1.0" {
+		t.Fatalf("expected the upgrade to change the contract, got %+v", registrations[2])
+	}
+}
+
+// slowAgentRepository stores agents in memory, taking its time to load them.
+type slowAgentRepository struct {
+	*memoryAgentRepository
+}
+
+func (r slowAgentRepository) GetByName(name string) (*models.Agent, error) {
+	time.Sleep(100 * time.Microsecond)
+	return r.memoryAgentRepository.GetByName(name)
+}
+
+func TestConcurrentRegistrationsStoredInOrder(t *testing.T) {
+	agentRepo := slowAgentRepository{newMemoryAgentRepository()}
+	registry := newTestRegistryWithRepo(agentRepo, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				agent := testAgent("a", "echo")
+				agent.Version = fmt.Sprintf("v1.%d.%d", i, j)
+				if err := registry.RegisterAgent("a", agent); err != nil {
+					t.Errorf("register v1.%d.%d: %v", i, j, err)
+					return
+				}
+				if j%5 == 0 {
+					registry.UnregisterAgent("a")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The stored agent is the one the registry kept, and each registration
+	// follows the one stored before it.
+	registered, found := registry.GetAgent("a")
+	stored, _ := agentRepo.GetByName("a")
+	if found != (stored != nil) || (found && stored.Version != registered.Version) {
+		t.Fatalf("expected the stored agent to match the registered one, got %+v and %+v", stored, registered)
+	}
+	registrations, _ := agentRepo.GetRegistrations("a")
+	for i := 1; i < len(registrations); i++ {
+		if previous := registrations[i].PreviousVersion; previous != nil && *previous != registrations[i-1].Version {
+			t.Fatalf("expected registration %d to follow %s, got %s", i, registrations[i-1].Version, *previous)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	agentRepo := newMemoryAgentRepository()
+	previous := newTestRegistryWithRepo(agentRepo, nil)
+	online := testAgent("online", "echo")
+	online.Port = "1"
+	offline := testAgent("offline", "echo")
+	offline.Port = "2"
+	if err := previous.RegisterAgent("online", online); err != nil {
+		t.Fatal(err)
+	}
+	if err := previous.RegisterAgent("offline", offline); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := newTestRegistryWithRepo(agentRepo, map[string]bool{"2": true})
+	if err := registry.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := registry.Snapshot()
+	if len(snapshot.Agents) != 2 {
+		t.Fatalf("expected 2 restored agents, got %d", len(snapshot.Agents))
+	}
+	if agent, _ := registry.GetAgent("offline"); agent.Status != AgentStatusOffline {
+		t.Fatalf("expected the unreachable agent to be offline, got %s", agent.Status)
+	}
+	if agent, _ := registry.GetAgent("online"); !agent.Restored {
+		t.Fatal("expected the agent to be flagged restored until it registers again")
+	}
+	if routed := snapshot.AgentsByTask["echo"]; len(routed) != 1 || routed[0] != "online" {
+		t.Fatalf("expected echo to be routed to the online agent only, got %v", routed)
+	}
+
+	registry.recordPing("offline", nil, 3)
+	if routed := registry.Snapshot().AgentsByTask["echo"]; len(routed) != 2 {
+		t.Fatalf("expected echo to be routed to both agents once the offline one answers, got %v", routed)
+	}
+
+	if err := registry.RegisterAgent("online", online); err != nil {
+		t.Fatal(err)
+	}
+	if agent, _ := registry.GetAgent("online"); agent.Restored {
+		t.Fatal("expected the agent not to be flagged restored once it registered again")
+	}
+}
@@ -0,0 +1,118 @@
+package registry
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	BalancerRoundRobin    = "round-robin"
+	BalancerLeastInFlight = "least-in-flight"
+	BalancerWeighted      = "weighted"
+)
+
+// Balancer chooses the agent a task is dispatched to when several agents
+// support its task definition.
+type Balancer interface {
+	// Pick returns one of the candidates, which are the online agents
+	// supporting the task definition. Candidates are never empty and are
+	// always listed in the same order.
+	Pick(taskId string, candidates []*RegisteredAgent) *RegisteredAgent
+}
+
+// NewBalancer returns the balancer implementing the given strategy.
+func NewBalancer(strategy string) (Balancer, error) {
+	switch strategy {
+	case BalancerRoundRobin:
+		return NewRoundRobinBalancer(), nil
+	case BalancerLeastInFlight:
+		return NewLeastInFlightBalancer(), nil
+	case BalancerWeighted:
+		return NewWeightedBalancer(), nil
+	default:
+		return nil, fmt.Errorf("unknown balancer strategy %q", strategy)
+	}
+}
+
+// RoundRobinBalancer dispatches the tasks of a task definition to each of its
+// agents in turn.
+type RoundRobinBalancer struct {
+	mu   sync.Mutex
+	next map[string]int
+}
+
+func NewRoundRobinBalancer() *RoundRobinBalancer {
+	return &RoundRobinBalancer{
+		next: make(map[string]int),
+	}
+}
+
+func (b *RoundRobinBalancer) Pick(taskId string, candidates []*RegisteredAgent) *RegisteredAgent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	index := b.next[taskId] % len(candidates)
+	b.next[taskId] = index + 1
+	return candidates[index]
+}
+
+// LeastInFlightBalancer dispatches a task to the agent running the fewest
+// tasks. Agents running as many tasks are used in turn.
+type LeastInFlightBalancer struct {
+	ties *RoundRobinBalancer
+}
+
+func NewLeastInFlightBalancer() *LeastInFlightBalancer {
+	return &LeastInFlightBalancer{
+		ties: NewRoundRobinBalancer(),
+	}
+}
+
+func (b *LeastInFlightBalancer) Pick(taskId string, candidates []*RegisteredAgent) *RegisteredAgent {
+	least := make([]*RegisteredAgent, 0, len(candidates))
+	for _, candidate := range candidates {
+		if len(least) > 0 && candidate.InFlightTasks > least[0].InFlightTasks {
+			continue
+		}
+		if len(least) > 0 && candidate.InFlightTasks < least[0].InFlightTasks {
+			least = least[:0]
+		}
+		least = append(least, candidate)
+	}
+	return b.ties.Pick(taskId, least)
+}
+
+// WeightedBalancer dispatches the tasks of a task definition to its agents in
+// proportion to their weight, using smooth weighted round-robin so the tasks
+// of an agent are spread out rather than dispatched in bursts.
+type WeightedBalancer struct {
+	mu      sync.Mutex
+	current map[string]map[string]int
+}
+
+func NewWeightedBalancer() *WeightedBalancer {
+	return &WeightedBalancer{
+		current: make(map[string]map[string]int),
+	}
+}
+
+func (b *WeightedBalancer) Pick(taskId string, candidates []*RegisteredAgent) *RegisteredAgent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous := b.current[taskId]
+	current := make(map[string]int, len(candidates))
+	total := 0
+	var picked *RegisteredAgent
+	for _, candidate := range candidates {
+		weight := max(candidate.Weight, 1)
+		total += weight
+		current[candidate.Name] = previous[candidate.Name] + weight
+		if picked == nil || current[candidate.Name] > current[picked.Name] {
+			picked = candidate
+		}
+	}
+	current[picked.Name] -= total
+	b.current[taskId] = current
+	return picked
+}
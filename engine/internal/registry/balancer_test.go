@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func pickAll(b Balancer, candidates []*RegisteredAgent, count int) string {
+	picks := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		picks = append(picks, b.Pick("echo", candidates).Name)
+	}
+	return strings.Join(picks, ",")
+}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	candidates := []*RegisteredAgent{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	if picks := pickAll(NewRoundRobinBalancer(), candidates, 5); picks != "a,b,c,a,b" {
+		t.Fatalf("expected a,b,c,a,b, got %s", picks)
+	}
+}
+
+func TestLeastInFlightBalancer(t *testing.T) {
+	candidates := []*RegisteredAgent{
+		{Name: "a", InFlightTasks: 2},
+		{Name: "b", InFlightTasks: 0},
+		{Name: "c", InFlightTasks: 0},
+	}
+
+	if picks := pickAll(NewLeastInFlightBalancer(), candidates, 4); picks != "b,c,b,c" {
+		t.Fatalf("expected b,c,b,c, got %s", picks)
+	}
+}
+
+func TestWeightedBalancer(t *testing.T) {
+	candidates := []*RegisteredAgent{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}}
+
+	if picks := pickAll(NewWeightedBalancer(), candidates, 8); picks != "a,a,b,a,a,a,b,a" {
+		t.Fatalf("expected a,a,b,a,a,a,b,a, got %s", picks)
+	}
+}
+
+func TestNewBalancerUnknownStrategy(t *testing.T) {
+	if _, err := NewBalancer("random"); err == nil {
+		t.Fatal("expected an error for an unknown strategy")
+	}
+}
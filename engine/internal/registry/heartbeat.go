@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type HeartbeatConfig struct {
+	// Interval is the delay between two pings of every registered agent.
+	Interval time.Duration
+	// OfflineAfter is the number of pings in a row an agent can miss before
+	// it is considered offline.
+	OfflineAfter int
+}
+
+// Heartbeat pings the agents of a registry on a schedule and keeps their
+// status up to date. Status changes are published to the subscribers of the
+// registry.
+type Heartbeat struct {
+	registry *AgentRegistry
+	config   *HeartbeatConfig
+}
+
+func NewHeartbeat(registry *AgentRegistry, config *HeartbeatConfig) *Heartbeat {
+	return &Heartbeat{
+		registry: registry,
+		config:   config,
+	}
+}
+
+func (h *Heartbeat) Start(ctx context.Context) {
+	ticker := time.NewTicker(h.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.pingAgents()
+		}
+	}
+}
+
+// pingAgents pings every registered agent concurrently, so a slow agent does
+// not delay the others. Offline agents are pinged as well, so they are used
+// again once they answer.
+func (h *Heartbeat) pingAgents() {
+	var wg sync.WaitGroup
+	for name, conn := range h.registry.connectors() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := conn.Ping()
+			status, changed := h.registry.recordPing(name, err, h.config.OfflineAfter)
+			if !changed {
+				return
+			}
+
+			if err != nil {
+				log.Printf("[registry] agent %s is %s: %v", name, status, err)
+			} else {
+				log.Printf("[registry] agent %s is %s", name, status)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,194 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Restore registers again the agents stored by previous engine runs, so they
+// are known as soon as the engine starts. Every stored agent is pinged: the
+// ones that answer are healthy, and the others are restored offline, so no
+// task is routed to them until the heartbeat reaches them again. Restored
+// agents stay flagged as such until they register again. Agents that
+// registered in the meantime are left untouched.
+func (ar *AgentRegistry) Restore() error {
+	records, err := ar.agentRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("load stored agents: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, record := range records {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := ar.restore(record); err != nil {
+				log.Printf("[registry] failed to restore agent %s: %v", record.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (ar *AgentRegistry) restore(record models.Agent) error {
+	agent, err := fromAgentModel(record)
+	if err != nil {
+		return err
+	}
+	agentConnector, err := ar.newConnector(agent.Protocol, agent.Address, agent.Port)
+	if err != nil {
+		return err
+	}
+
+	agent.Restored = true
+	agent.Status = AgentStatusHealthy
+	if err := agentConnector.Ping(); err != nil {
+		agent.Status = AgentStatusOffline
+		agent.ConsecutiveFailures = 1
+		log.Printf("[registry] stored agent %s does not answer, restoring it offline: %v", agent.Name, err)
+	} else {
+		agent.LastSeenAt = time.Now()
+	}
+
+	ar.mu.Lock()
+	if _, registered := ar.agents[agent.Name]; registered {
+		ar.mu.Unlock()
+		_ = agentConnector.Close()
+		return nil
+	}
+	ar.add(agent, agentConnector)
+	ar.mu.Unlock()
+
+	log.Printf("[registry] restored agent %s at %v:%s as %s", agent.Name, agent.Address, agent.Port, agent.Status)
+	return nil
+}
+
+// persist stores a registered agent and records its registration. Failing to
+// store it is only logged: the agent is registered all the same, and is only
+// unknown after a restart until it registers again. The caller holds the
+// lock of the registry, so concurrent registrations of an agent are stored in
+// the order the registry applied them.
+func (ar *AgentRegistry) persist(agent RegisteredAgent) {
+	record := toAgentModel(agent)
+	previous, err := ar.agentRepo.GetByName(agent.Name)
+	if err != nil {
+		log.Printf("[registry] failed to load stored agent %s: %v", agent.Name, err)
+		return
+	}
+
+	registration := &models.AgentRegistration{
+		AgentName:      agent.Name,
+		Version:        agent.Version,
+		SupportedTasks: record.SupportedTasks,
+	}
+	if previous != nil {
+		registration.PreviousVersion = &previous.Version
+		registration.ContractChanged = !sameContract(previous.SupportedTasks, record.SupportedTasks)
+		if registration.ContractChanged {
+			log.Printf("[registry] task definitions of agent %s changed from version %s to %s", agent.Name, previous.Version, agent.Version)
+		}
+	}
+
+	if _, err := ar.agentRepo.Save(record); err != nil {
+		log.Printf("[registry] failed to store agent %s: %v", agent.Name, err)
+		return
+	}
+	if _, err := ar.agentRepo.CreateRegistration(registration); err != nil {
+		log.Printf("[registry] failed to record registration of agent %s: %v", agent.Name, err)
+	}
+}
+
+// sameContract reports whether two lists of task definitions are identical,
+// parameter schemas included.
+func sameContract(a, b *models.AgentTaskDefinitionList) bool {
+	aBytes, aErr := json.Marshal(a)
+	bBytes, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aBytes, bBytes)
+}
+
+func toAgentModel(agent RegisteredAgent) *models.Agent {
+	supportedTasks := make(models.AgentTaskDefinitionList, 0, len(agent.SupportedTasks))
+	for _, taskDef := range agent.SupportedTasks {
+		supportedTasks = append(supportedTasks, models.AgentTaskDefinition{
+			ID:               taskDef.Id,
+			Name:             taskDef.Name,
+			Description:      taskDef.Description,
+			InputParameters:  structToJsonMap(taskDef.InputParameters),
+			OutputParameters: structToJsonMap(taskDef.OutputParameters),
+		})
+	}
+
+	return &models.Agent{
+		Name:           agent.Name,
+		Version:        agent.Version,
+		Address:        agent.Address,
+		Port:           agent.Port,
+		Protocol:       agent.Protocol.String(),
+		Weight:         agent.Weight,
+		SupportedTasks: &supportedTasks,
+		LastSeenAt:     agent.LastSeenAt,
+	}
+}
+
+func fromAgentModel(record models.Agent) (RegisteredAgent, error) {
+	protocol, known := proto.AgentProtocol_value[record.Protocol]
+	if !known {
+		return RegisteredAgent{}, fmt.Errorf("unknown agent protocol %s", record.Protocol)
+	}
+
+	agent := RegisteredAgent{
+		Name:       record.Name,
+		Version:    record.Version,
+		Address:    record.Address,
+		Port:       record.Port,
+		Protocol:   proto.AgentProtocol(protocol),
+		Weight:     max(record.Weight, 1),
+		LastSeenAt: record.LastSeenAt,
+	}
+	if record.SupportedTasks == nil {
+		return agent, nil
+	}
+
+	for _, taskDef := range *record.SupportedTasks {
+		inputParameters, err := jsonMapToStruct(taskDef.InputParameters)
+		if err != nil {
+			return RegisteredAgent{}, fmt.Errorf("input parameters of task %s: %w", taskDef.ID, err)
+		}
+		outputParameters, err := jsonMapToStruct(taskDef.OutputParameters)
+		if err != nil {
+			return RegisteredAgent{}, fmt.Errorf("output parameters of task %s: %w", taskDef.ID, err)
+		}
+		agent.SupportedTasks = append(agent.SupportedTasks, &proto.TaskDefinition{
+			Id:               taskDef.ID,
+			Name:             taskDef.Name,
+			Description:      taskDef.Description,
+			InputParameters:  inputParameters,
+			OutputParameters: outputParameters,
+		})
+	}
+	return agent, nil
+}
+
+func structToJsonMap(s *structpb.Struct) *models.JsonMap {
+	if s == nil {
+		return nil
+	}
+	m := models.JsonMap(s.AsMap())
+	return &m
+}
+
+func jsonMapToStruct(m *models.JsonMap) (*structpb.Struct, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return structpb.NewStruct(*m)
+}
@@ -0,0 +1,112 @@
+package resolver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+)
+
+// PathSegment is either an object key or an array index.
+type PathSegment struct {
+	Key   string
+	Index int
+	// IsIndex is true when the segment selects an array element.
+	IsIndex bool
+}
+
+type Path []PathSegment
+
+// ParsePath parses a JSON path such as "items[0].name" or "$.items[0].name".
+// Keys containing dots or brackets can be quoted: "labels['app.kubernetes.io/name']".
+func ParsePath(path string) (Path, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	segments := make(Path, 0)
+
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			if i == len(path)-1 || path[i+1] == '.' {
+				return nil, fmt.Errorf("%w: empty key in %q", errors.ErrInvalidParameterPath, path)
+			}
+			i++
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unclosed bracket in %q", errors.ErrInvalidParameterPath, path)
+			}
+			inner := path[i+1 : i+end]
+			segment, err := parseBracket(inner)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v in %q", errors.ErrInvalidParameterPath, err, path)
+			}
+			segments = append(segments, segment)
+			i += end + 1
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			segments = append(segments, PathSegment{Key: path[i : i+end]})
+			i += end
+		}
+	}
+
+	return segments, nil
+}
+
+func parseBracket(inner string) (PathSegment, error) {
+	if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+		return PathSegment{Key: inner[1 : len(inner)-1]}, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 {
+		return PathSegment{}, fmt.Errorf("invalid index %q", inner)
+	}
+	return PathSegment{Index: index, IsIndex: true}, nil
+}
+
+// Lookup returns the value found at the path inside value.
+func (p Path) Lookup(value interface{}) (interface{}, error) {
+	current := value
+	for i, segment := range p {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			if segment.IsIndex {
+				return nil, fmt.Errorf("%w: %s is an object, not an array", errors.ErrParameterPathNotFound, p[:i])
+			}
+			next, exists := node[segment.Key]
+			if !exists {
+				return nil, fmt.Errorf("%w: %s", errors.ErrParameterPathNotFound, p[:i+1])
+			}
+			current = next
+		case []interface{}:
+			if !segment.IsIndex {
+				return nil, fmt.Errorf("%w: %s is an array, not an object", errors.ErrParameterPathNotFound, p[:i])
+			}
+			if segment.Index >= len(node) {
+				return nil, fmt.Errorf("%w: %s is out of range", errors.ErrParameterPathNotFound, p[:i+1])
+			}
+			current = node[segment.Index]
+		default:
+			return nil, fmt.Errorf("%w: %s", errors.ErrParameterPathNotFound, p[:i+1])
+		}
+	}
+	return current, nil
+}
+
+func (p Path) String() string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, segment := range p {
+		if segment.IsIndex {
+			sb.WriteString("[" + strconv.Itoa(segment.Index) + "]")
+		} else if strings.ContainsAny(segment.Key, ".[]") {
+			sb.WriteString("['" + segment.Key + "']")
+		} else {
+			sb.WriteString("." + segment.Key)
+		}
+	}
+	return sb.String()
+}
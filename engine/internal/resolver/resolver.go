@@ -0,0 +1,116 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+// Context holds the values step parameters are resolved against.
+type Context struct {
+	WorkflowInput map[string]interface{}
+	// StepOutputs contains the output of every completed step, keyed by step
+	// definition ID.
+	StepOutputs map[string]map[string]interface{}
+}
+
+// Reference points at a value inside the workflow input or a step output.
+//
+// A workflowInput parameter value is a path into the workflow input, e.g.
+// "customer.address.city". A taskOutput parameter value is a step ID followed
+// by a path into that step's output, e.g. "fetch-order.items[0].sku", or an
+// object {"stepId": "fetch-order", "path": "items[0].sku"} for step IDs
+// containing dots.
+type Reference struct {
+	StepID string
+	Path   Path
+}
+
+// Resolve returns the concrete value of a single parameter.
+func Resolve(param models.StepDefinitionParameter, ctx *Context) (interface{}, error) {
+	switch param.Type {
+	case models.StepParameterTypeConstant:
+		return param.Value, nil
+	case models.StepParameterTypeWorkflow:
+		path, err := parseWorkflowInputReference(param.Value)
+		if err != nil {
+			return nil, err
+		}
+		return path.Lookup(ctx.WorkflowInput)
+	case models.StepParameterTypeTaskOutput:
+		ref, err := ParseTaskOutputReference(param.Value)
+		if err != nil {
+			return nil, err
+		}
+		output, exists := ctx.StepOutputs[ref.StepID]
+		if !exists {
+			return nil, fmt.Errorf("%w: step %s has not completed", errors.ErrStepOutputNotFound, ref.StepID)
+		}
+		value, err := ref.Path.Lookup(output)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %w", ref.StepID, err)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported parameter type %q", errors.ErrInvalidParameterReference, param.Type)
+	}
+}
+
+// ResolveAll resolves every parameter of a step into its input map.
+func ResolveAll(params *models.StepDefinitionParameters, ctx *Context) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{})
+	if params == nil {
+		return resolved, nil
+	}
+
+	for name, param := range *params {
+		value, err := Resolve(param, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		resolved[name] = value
+	}
+	return resolved, nil
+}
+
+func parseWorkflowInputReference(value interface{}) (Path, error) {
+	raw, ok := value.(string)
+	if !ok || raw == "" {
+		return nil, fmt.Errorf("%w: workflow input reference must be a non-empty string, got %v", errors.ErrInvalidParameterReference, value)
+	}
+	return ParsePath(raw)
+}
+
+// ParseTaskOutputReference parses the value of a taskOutput parameter.
+func ParseTaskOutputReference(value interface{}) (*Reference, error) {
+	switch v := value.(type) {
+	case string:
+		stepID, rawPath := v, ""
+		if i := strings.IndexAny(v, ".["); i >= 0 {
+			stepID, rawPath = v[:i], v[i:]
+		}
+		if stepID == "" {
+			return nil, fmt.Errorf("%w: missing step ID in %q", errors.ErrInvalidParameterReference, v)
+		}
+		path, err := ParsePath(rawPath)
+		if err != nil {
+			return nil, err
+		}
+		return &Reference{StepID: stepID, Path: path}, nil
+	case map[string]interface{}:
+		stepID, _ := v["stepId"].(string)
+		if stepID == "" {
+			return nil, fmt.Errorf("%w: missing stepId in %v", errors.ErrInvalidParameterReference, v)
+		}
+		rawPath, _ := v["path"].(string)
+		path, err := ParsePath(rawPath)
+		if err != nil {
+			return nil, err
+		}
+		return &Reference{StepID: stepID, Path: path}, nil
+	default:
+		return nil, fmt.Errorf("%w: task output reference must be a string or an object, got %v", errors.ErrInvalidParameterReference, value)
+	}
+}
@@ -0,0 +1,65 @@
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+func testContext() *Context {
+	return &Context{
+		WorkflowInput: map[string]interface{}{
+			"customer": map[string]interface{}{"name": "Ada"},
+		},
+		StepOutputs: map[string]map[string]interface{}{
+			"fetch": {
+				"items": []interface{}{
+					map[string]interface{}{"sku": "A-1"},
+				},
+			},
+		},
+	}
+}
+
+func TestResolveReferences(t *testing.T) {
+	ctx := testContext()
+
+	v, err := Resolve(models.StepDefinitionParameter{Type: models.StepParameterTypeWorkflow, Value: "customer.name"}, ctx)
+	if err != nil || v != "Ada" {
+		t.Fatalf("expected Ada, got %v (%v)", v, err)
+	}
+
+	v, err = Resolve(models.StepDefinitionParameter{Type: models.StepParameterTypeTaskOutput, Value: "fetch.items[0].sku"}, ctx)
+	if err != nil || v != "A-1" {
+		t.Fatalf("expected A-1, got %v (%v)", v, err)
+	}
+
+	v, err = Resolve(models.StepDefinitionParameter{
+		Type:  models.StepParameterTypeTaskOutput,
+		Value: map[string]interface{}{"stepId": "fetch", "path": "$.items[0]['sku']"},
+	}, ctx)
+	if err != nil || v != "A-1" {
+		t.Fatalf("expected A-1, got %v (%v)", v, err)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	ctx := testContext()
+
+	_, err := Resolve(models.StepDefinitionParameter{Type: models.StepParameterTypeTaskOutput, Value: "missing.value"}, ctx)
+	if !errors.Is(err, engineErrors.ErrStepOutputNotFound) {
+		t.Fatalf("expected step output not found, got %v", err)
+	}
+
+	_, err = Resolve(models.StepDefinitionParameter{Type: models.StepParameterTypeTaskOutput, Value: "fetch.items[3].sku"}, ctx)
+	if !errors.Is(err, engineErrors.ErrParameterPathNotFound) {
+		t.Fatalf("expected path not found, got %v", err)
+	}
+
+	_, err = Resolve(models.StepDefinitionParameter{Type: models.StepParameterTypeWorkflow, Value: "customer[x"}, ctx)
+	if !errors.Is(err, engineErrors.ErrInvalidParameterPath) {
+		t.Fatalf("expected invalid path, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/registry"
+)
+
+// WatchAgents handles the agents going offline, as published by the agent
+// registry, until the context is done or the subscription ends.
+func (s *Scheduler) WatchAgents(ctx context.Context, events <-chan registry.AgentEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Type == registry.AgentEventStatusChanged && event.Agent.Status == registry.AgentStatusOffline {
+				go s.HandleAgentOffline(event.Agent.Name)
+			}
+		}
+	}
+}
+
+// HandleAgentOffline fails the current attempt of every step running a task on
+// an agent that went offline. Steps whose retry policy allows it start another
+// attempt, which is dispatched to an agent that is still online, and the others
+// fail.
+func (s *Scheduler) HandleAgentOffline(agentName string) {
+	steps, err := s.stepInstanceRepo.GetRunningByAgentName(agentName)
+	if err != nil {
+		log.Printf("[scheduler] failed to load the steps running on agent %s: %v", agentName, err)
+		return
+	}
+
+	cause := engineErrors.WithCode(engineErrors.ErrorCodeAgentOffline, fmt.Errorf("%w: %s", engineErrors.ErrAgentOffline, agentName))
+	for _, step := range steps {
+		err := s.withExecution(step.ID, func(execution *StepExecution) error {
+			step := execution.Step
+			if step.Status != models.StepInstanceStatusRunning || execution.Instance.Status.IsTerminal() {
+				return nil
+			}
+			if step.AgentName == nil || *step.AgentName != agentName {
+				return nil
+			}
+			return s.failStep(execution, cause)
+		})
+		if err != nil {
+			log.Printf("[scheduler] failed to reschedule step %s from offline agent %s: %v", step.ID, agentName, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/condition"
+)
+
+type DecisionStepHandler struct{}
+
+func NewDecisionStepHandler() *DecisionStepHandler {
+	return &DecisionStepHandler{}
+}
+
+// Handle evaluates the cases in order and continues with the first one that
+// matches, falling back to the default step and then to the join step.
+func (h *DecisionStepHandler) Handle(_ context.Context, execution *StepExecution) (*StepResult, error) {
+	config := execution.StepDefinition.DecisionConfig
+	if config == nil {
+		return nil, errors.New("decision step has no decision configuration")
+	}
+
+	ctx := resolverContext(execution)
+	for i, decisionCase := range config.Cases {
+		expression, err := condition.Parse(decisionCase.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("case %d: %w", i, err)
+		}
+		matches, err := expression.Evaluate(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("case %d: %w", i, err)
+		}
+		if matches {
+			return completed(decisionOutput(&i, decisionCase.NextStepID), decisionCase.NextStepID), nil
+		}
+	}
+
+	nextStepID := config.JoinStepID
+	if config.DefaultNextStepID != nil {
+		nextStepID = *config.DefaultNextStepID
+	}
+	return completed(decisionOutput(nil, nextStepID), nextStepID), nil
+}
+
+func decisionOutput(caseIndex *int, nextStepID string) map[string]interface{} {
+	output := map[string]interface{}{
+		"nextStepId": nextStepID,
+		"caseIndex":  nil,
+	}
+	if caseIndex != nil {
+		output["caseIndex"] = *caseIndex
+	}
+	return output
+}
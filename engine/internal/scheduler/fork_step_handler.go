@@ -0,0 +1,26 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+)
+
+type ForkStepHandler struct{}
+
+func NewForkStepHandler() *ForkStepHandler {
+	return &ForkStepHandler{}
+}
+
+func (h *ForkStepHandler) Handle(_ context.Context, execution *StepExecution) (*StepResult, error) {
+	config := execution.StepDefinition.ForkConfig
+	if config == nil {
+		return nil, errors.New("fork step has no fork configuration")
+	}
+
+	branches := make([]string, 0, len(config.Branches))
+	for _, branch := range config.Branches {
+		branches = append(branches, branch.NextStepID)
+	}
+
+	return completed(nil, branches...), nil
+}
@@ -0,0 +1,130 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+type JoinStepHandler struct {
+	scheduler *Scheduler
+}
+
+func NewJoinStepHandler(scheduler *Scheduler) *JoinStepHandler {
+	return &JoinStepHandler{
+		scheduler: scheduler,
+	}
+}
+
+// Handle evaluates the join policy against the incoming branches. The join of
+// a decision only ever receives the branch that was taken, so it completes as
+// soon as it is reached. Once a fork join is settled before every branch
+// finished, the branches still running are cancelled.
+//
+// The output of a completed join holds the output of each completed incoming
+// step, keyed by step ID.
+func (h *JoinStepHandler) Handle(_ context.Context, execution *StepExecution) (*StepResult, error) {
+	config := execution.StepDefinition.JoinConfig
+	if config == nil {
+		return nil, errors.New("join step has no join configuration")
+	}
+
+	owner, exists := execution.Definition.GetStepJoinedAt(execution.StepDefinition.StepDefinitionID)
+	if exists && owner.Type == models.StepTypeDecision {
+		return completed(nil), nil
+	}
+
+	var fork *models.StepInstance
+	if exists {
+		fork, _ = execution.GetStepInstance(owner.StepDefinitionID)
+	}
+
+	outputs := make(map[string]interface{})
+	failed := 0
+	for _, incomingStepID := range config.IncomingStepIDs {
+		incoming, exists := execution.GetStepInstance(incomingStepID)
+		if !exists {
+			continue
+		}
+		switch incoming.Status {
+		case models.StepInstanceStatusCompleted:
+			output := make(map[string]interface{})
+			if incoming.Output != nil {
+				output = *incoming.Output
+			}
+			outputs[incomingStepID] = output
+		case models.StepInstanceStatusFailed, models.StepInstanceStatusTimedOut:
+			if fork == nil {
+				failed++
+			}
+		}
+	}
+
+	// Each branch stops at its first failed step, so counting the failed steps
+	// of the fork scope counts the failed branches.
+	if fork != nil {
+		for _, step := range execution.Steps {
+			if step.ParentStepInstanceID != nil && *step.ParentStepInstanceID == fork.ID && step.Status.IsFailure() {
+				failed++
+			}
+		}
+	}
+
+	total := len(config.IncomingStepIDs)
+	succeeded := len(outputs)
+	pending := max(total-succeeded-failed, 0)
+
+	settle := func(err error) (*StepResult, error) {
+		if fork != nil && pending > 0 {
+			reason := "join step " + execution.StepDefinition.StepDefinitionID + " settled"
+			if cancelErr := h.scheduler.cancelScope(execution, fork.ID, reason); cancelErr != nil {
+				return nil, cancelErr
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return completed(outputs), nil
+	}
+	failure := fmt.Errorf("%w: %d of %d branches failed", engineErrors.ErrJoinFailed, failed, total)
+
+	switch config.GetPolicy() {
+	case models.JoinPolicyAny:
+		if succeeded > 0 {
+			return settle(nil)
+		}
+		if pending == 0 {
+			return settle(failure)
+		}
+	case models.JoinPolicyNOfM:
+		required := total
+		if config.RequiredCount != nil {
+			required = *config.RequiredCount
+		}
+		if succeeded >= required {
+			return settle(nil)
+		}
+		if succeeded+pending < required {
+			return settle(failure)
+		}
+	case models.JoinPolicyFailFast:
+		if failed > 0 {
+			return settle(failure)
+		}
+		if pending == 0 {
+			return settle(nil)
+		}
+	default:
+		if pending == 0 && failed > 0 {
+			return settle(failure)
+		}
+		if pending == 0 {
+			return settle(nil)
+		}
+	}
+
+	return inProgress(), nil
+}
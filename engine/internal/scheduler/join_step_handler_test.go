@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+// forkJoinDefinition forks into the tasks b1, b2 and b3, which converge at a
+// join step with the given policy, followed by a last task.
+func forkJoinDefinition(e *testEnv, policy models.JoinPolicy, requiredCount *int) *models.WorkflowDefinition {
+	return e.define(
+		models.WorkflowStepDefinition{
+			StepDefinitionID: "fork",
+			Name:             "fork",
+			Type:             models.StepTypeFork,
+			ForkConfig: &models.ForkConfig{
+				JoinStepID: "join",
+				Branches:   []models.ForkBranch{{NextStepID: "b1"}, {NextStepID: "b2"}, {NextStepID: "b3"}},
+			},
+		},
+		taskStep("b1"),
+		taskStep("b2"),
+		taskStep("b3"),
+		models.WorkflowStepDefinition{
+			StepDefinitionID: "join",
+			Name:             "join",
+			Type:             models.StepTypeJoin,
+			JoinConfig: &models.JoinConfig{
+				IncomingStepIDs: []string{"b1", "b2", "b3"},
+				Policy:          policy,
+				RequiredCount:   requiredCount,
+				NextStepID:      ptr("after"),
+			},
+		},
+		taskStep("after"),
+	)
+}
+
+func TestJoinPolicies(t *testing.T) {
+	completed, failed := proto.TaskStatus_COMPLETED, proto.TaskStatus_FAILED
+
+	type outcome struct {
+		branch string
+		status proto.TaskStatus
+	}
+	tests := []struct {
+		name          string
+		policy        models.JoinPolicy
+		requiredCount *int
+		outcomes      []outcome
+		want          map[string]models.StepInstanceStatus
+		instance      models.WorkflowInstanceStatus
+		stopped       []string
+	}{
+		{
+			name:     "all waits for every branch",
+			policy:   models.JoinPolicyAll,
+			outcomes: []outcome{{"b1", completed}, {"b2", completed}},
+			want: map[string]models.StepInstanceStatus{
+				"b3":   models.StepInstanceStatusRunning,
+				"join": models.StepInstanceStatusRunning,
+			},
+			instance: models.WorkflowInstanceStatusRunning,
+		},
+		{
+			name:     "all completes once every branch completed",
+			outcomes: []outcome{{"b1", completed}, {"b2", completed}, {"b3", completed}},
+			want: map[string]models.StepInstanceStatus{
+				"join":  models.StepInstanceStatusCompleted,
+				"after": models.StepInstanceStatusRunning,
+			},
+			instance: models.WorkflowInstanceStatusRunning,
+		},
+		{
+			name:     "all fails once every branch finished with a failure",
+			policy:   models.JoinPolicyAll,
+			outcomes: []outcome{{"b1", completed}, {"b2", failed}, {"b3", completed}},
+			want: map[string]models.StepInstanceStatus{
+				"b2":   models.StepInstanceStatusFailed,
+				"join": models.StepInstanceStatusFailed,
+			},
+			instance: models.WorkflowInstanceStatusFailed,
+		},
+		{
+			name:     "any completes with the first completed branch",
+			policy:   models.JoinPolicyAny,
+			outcomes: []outcome{{"b1", failed}, {"b2", completed}},
+			want: map[string]models.StepInstanceStatus{
+				"b1":    models.StepInstanceStatusFailed,
+				"b3":    models.StepInstanceStatusCancelled,
+				"join":  models.StepInstanceStatusCompleted,
+				"after": models.StepInstanceStatusRunning,
+			},
+			instance: models.WorkflowInstanceStatusRunning,
+			stopped:  []string{"stop task-3"},
+		},
+		{
+			name:     "any fails once every branch failed",
+			policy:   models.JoinPolicyAny,
+			outcomes: []outcome{{"b1", failed}, {"b2", failed}, {"b3", failed}},
+			want:     map[string]models.StepInstanceStatus{"join": models.StepInstanceStatusFailed},
+			instance: models.WorkflowInstanceStatusFailed,
+		},
+		{
+			name:          "nOfM completes once enough branches completed",
+			policy:        models.JoinPolicyNOfM,
+			requiredCount: ptr(2),
+			outcomes:      []outcome{{"b1", completed}, {"b3", completed}},
+			want: map[string]models.StepInstanceStatus{
+				"b2":    models.StepInstanceStatusCancelled,
+				"join":  models.StepInstanceStatusCompleted,
+				"after": models.StepInstanceStatusRunning,
+			},
+			instance: models.WorkflowInstanceStatusRunning,
+			stopped:  []string{"stop task-2"},
+		},
+		{
+			name:          "nOfM fails once too few branches can complete",
+			policy:        models.JoinPolicyNOfM,
+			requiredCount: ptr(2),
+			outcomes:      []outcome{{"b1", failed}, {"b2", failed}},
+			want: map[string]models.StepInstanceStatus{
+				"b3":   models.StepInstanceStatusCancelled,
+				"join": models.StepInstanceStatusFailed,
+			},
+			instance: models.WorkflowInstanceStatusFailed,
+			stopped:  []string{"stop task-3"},
+		},
+		{
+			name:     "failFast fails with the first failed branch",
+			policy:   models.JoinPolicyFailFast,
+			outcomes: []outcome{{"b2", failed}},
+			want: map[string]models.StepInstanceStatus{
+				"b1":   models.StepInstanceStatusCancelled,
+				"b3":   models.StepInstanceStatusCancelled,
+				"join": models.StepInstanceStatusFailed,
+			},
+			instance: models.WorkflowInstanceStatusFailed,
+			stopped:  []string{"stop task-1", "stop task-3"},
+		},
+		{
+			name:     "failFast completes once every branch completed",
+			policy:   models.JoinPolicyFailFast,
+			outcomes: []outcome{{"b1", completed}, {"b2", completed}, {"b3", completed}},
+			want: map[string]models.StepInstanceStatus{
+				"join":  models.StepInstanceStatusCompleted,
+				"after": models.StepInstanceStatusRunning,
+			},
+			instance: models.WorkflowInstanceStatusRunning,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			instance := e.start(forkJoinDefinition(e, tt.policy, tt.requiredCount), nil)
+			e.reportActions()
+			e.expectSteps(instance, map[string]models.StepInstanceStatus{
+				"fork": models.StepInstanceStatusCompleted,
+				"b1":   models.StepInstanceStatusRunning,
+				"b2":   models.StepInstanceStatusRunning,
+				"b3":   models.StepInstanceStatusRunning,
+			})
+
+			for _, outcome := range tt.outcomes {
+				e.finishTask(instance, outcome.branch, outcome.status, map[string]interface{}{"branch": outcome.branch}, "")
+			}
+
+			e.expectSteps(instance, tt.want)
+			e.expectInstance(instance, tt.instance)
+			e.expectActions(tt.stopped...)
+		})
+	}
+}
+
+func TestJoinOutput(t *testing.T) {
+	e := newTestEnv(t)
+	instance := e.start(forkJoinDefinition(e, models.JoinPolicyAll, nil), nil)
+	for _, branch := range []string{"b1", "b2", "b3"} {
+		e.finishTask(instance, branch, proto.TaskStatus_COMPLETED, map[string]interface{}{"branch": branch}, "")
+	}
+
+	join := e.step(instance, "join")
+	if join.Output == nil || len(*join.Output) != 3 {
+		t.Fatalf("expected the join to hold the output of the 3 branches, got %v", join.Output)
+	}
+	for _, branch := range []string{"b1", "b2", "b3"} {
+		output, _ := (*join.Output)[branch].(map[string]interface{})
+		if output["branch"] != branch {
+			t.Fatalf("expected the output of %s under its step ID, got %v", branch, *join.Output)
+		}
+	}
+	for _, branch := range []string{"b1", "b2", "b3"} {
+		if step := e.step(instance, branch); step.ParentStepInstanceID == nil || *step.ParentStepInstanceID != e.step(instance, "fork").ID {
+			t.Fatalf("expected %s to belong to the fork scope, got %v", branch, step.ParentStepInstanceID)
+		}
+	}
+	if after := e.step(instance, "after"); after.ParentStepInstanceID != nil {
+		t.Fatalf("expected the step after the join to leave the fork scope, got %v", after.ParentStepInstanceID)
+	}
+}
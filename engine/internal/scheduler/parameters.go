@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/resolver"
+)
+
+// resolverContext exposes the workflow input and the output of every completed
+// step of the execution to the parameter resolver.
+func resolverContext(execution *StepExecution) *resolver.Context {
+	ctx := &resolver.Context{
+		WorkflowInput: make(map[string]interface{}),
+		StepOutputs:   make(map[string]map[string]interface{}),
+	}
+	if execution.Instance.Input != nil {
+		ctx.WorkflowInput = *execution.Instance.Input
+	}
+
+	for _, step := range execution.Steps {
+		if step.Status != models.StepInstanceStatusCompleted {
+			continue
+		}
+		output := make(map[string]interface{})
+		if step.Output != nil {
+			output = *step.Output
+		}
+		ctx.StepOutputs[step.StepDefinitionID] = output
+	}
+	return ctx
+}
+
+func resolveParameter(param models.StepDefinitionParameter, execution *StepExecution) (interface{}, error) {
+	return resolver.Resolve(param, resolverContext(execution))
+}
+
+func resolveParameters(params *models.StepDefinitionParameters, execution *StepExecution) (map[string]interface{}, error) {
+	return resolver.ResolveAll(params, resolverContext(execution))
+}
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"log"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/google/uuid"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+// shouldRetry returns when the next attempt of a failed or timed out step must
+// start, if the retry policy of the step allows another attempt.
+func shouldRetry(execution *StepExecution, cause error) (time.Time, bool) {
+	step := execution.Step
+	if step.Status != models.StepInstanceStatusRunning {
+		return time.Time{}, false
+	}
+
+	policy := execution.StepDefinition.GetRetryPolicy()
+	if policy == nil || step.Attempt >= policy.MaxAttempts || !policy.IsRetryable(errors.CodeOf(cause)) {
+		return time.Time{}, false
+	}
+
+	return time.Now().Add(retryInterval(policy, step.Attempt)), true
+}
+
+// retryInterval returns the delay between the given attempt and the next one.
+func retryInterval(policy *models.RetryPolicy, attempt int) time.Duration {
+	b := &backoff.ExponentialBackOff{
+		InitialInterval:     seconds(policy.GetInitialIntervalSeconds()),
+		RandomizationFactor: policy.GetJitter(),
+		Multiplier:          policy.GetMultiplier(),
+		MaxInterval:         seconds(policy.GetMaxIntervalSeconds()),
+		Clock:               backoff.SystemClock,
+	}
+	b.Reset()
+
+	interval := b.NextBackOff()
+	for i := 1; i < attempt; i++ {
+		interval = b.NextBackOff()
+	}
+	return interval
+}
+
+// retryStep records how the attempt ended and arms a timer for the next one.
+func (s *Scheduler) retryStep(execution *StepExecution, cause error, attemptStatus models.StepAttemptStatus, retryAt time.Time) error {
+	step := execution.Step
+	if err := s.finishAttempt(step, attemptStatus, cause, &retryAt); err != nil {
+		return err
+	}
+
+	if err := step.TransitionTo(models.StepInstanceStatusRetrying); err != nil {
+		return err
+	}
+	message := cause.Error()
+	step.Error = &message
+	if _, err := s.stepInstanceRepo.Update(step); err != nil {
+		return err
+	}
+
+	log.Printf("[scheduler] step %s of workflow instance %s failed on attempt %d, retrying at %s: %v", step.StepDefinitionID, step.WorkflowInstanceID, step.Attempt, retryAt.Format(time.RFC3339), cause)
+	return s.createTimer(step, models.TimerTypeRetry, retryAt)
+}
+
+// startNextAttempt moves a retrying step back to pending with a fresh attempt
+// and enqueues it. Anything linking the step to its previous attempt, such as
+// the agent task, is cleared so the handler starts over.
+func (s *Scheduler) startNextAttempt(stepInstanceID uuid.UUID) error {
+	return s.withExecution(stepInstanceID, func(execution *StepExecution) error {
+		step := execution.Step
+		if step.Status != models.StepInstanceStatusRetrying || execution.Instance.Status.IsTerminal() {
+			return nil
+		}
+
+		if err := step.TransitionTo(models.StepInstanceStatusPending); err != nil {
+			return err
+		}
+		step.Attempt++
+		step.Error = nil
+		step.Output = nil
+		step.AgentName = nil
+		step.AgentTaskID = nil
+		step.AgentTaskStatus = nil
+		step.Progress = nil
+		step.ProgressMessage = nil
+		step.PartialOutput = nil
+		if _, err := s.stepInstanceRepo.Update(step); err != nil {
+			return err
+		}
+
+		s.enqueue(step.ID)
+		return nil
+	})
+}
+
+// startAttempt records the start of the current attempt of a step.
+func (s *Scheduler) startAttempt(step *models.StepInstance) error {
+	existing, err := s.stepAttemptRepo.GetByAttempt(step.ID.String(), step.Attempt)
+	if err != nil || existing != nil {
+		return err
+	}
+
+	_, err = s.stepAttemptRepo.Create(&models.StepAttempt{
+		WorkflowInstanceID: step.WorkflowInstanceID,
+		StepInstanceID:     step.ID,
+		Attempt:            step.Attempt,
+		Status:             models.StepAttemptStatusRunning,
+		StartedAt:          *step.StartedAt,
+	})
+	return err
+}
+
+// finishAttempt records how the current attempt of a step ended. It is a
+// no-op if the attempt was never started.
+func (s *Scheduler) finishAttempt(step *models.StepInstance, status models.StepAttemptStatus, cause error, retryAt *time.Time) error {
+	attempt, err := s.stepAttemptRepo.GetByAttempt(step.ID.String(), step.Attempt)
+	if err != nil || attempt == nil || attempt.Status != models.StepAttemptStatusRunning {
+		return err
+	}
+
+	now := time.Now()
+	attempt.Status = status
+	attempt.CompletedAt = &now
+	attempt.RetryAt = retryAt
+	attempt.AgentTaskID = step.AgentTaskID
+	if cause != nil {
+		message := cause.Error()
+		attempt.Error = &message
+		if code := errors.CodeOf(cause); code != "" {
+			attempt.ErrorCode = &code
+		}
+	}
+
+	_, err = s.stepAttemptRepo.Update(attempt)
+	return err
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
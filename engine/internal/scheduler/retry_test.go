@@ -0,0 +1,196 @@
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+func TestRetryInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  models.RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{name: "default first interval", policy: models.RetryPolicy{MaxAttempts: 5}, attempt: 1, want: time.Second},
+		{name: "default backoff", policy: models.RetryPolicy{MaxAttempts: 5}, attempt: 3, want: 4 * time.Second},
+		{name: "default maximum", policy: models.RetryPolicy{MaxAttempts: 20}, attempt: 10, want: time.Minute},
+		{
+			name:    "custom backoff",
+			policy:  models.RetryPolicy{MaxAttempts: 5, InitialIntervalSeconds: ptr(0.5), Multiplier: ptr(3.0)},
+			attempt: 3,
+			want:    4500 * time.Millisecond,
+		},
+		{
+			name:    "custom maximum",
+			policy:  models.RetryPolicy{MaxAttempts: 5, InitialIntervalSeconds: ptr(10.0), MaxIntervalSeconds: ptr(15.0)},
+			attempt: 2,
+			want:    15 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryInterval(&tt.policy, tt.attempt); got != tt.want {
+				t.Fatalf("expected attempt %d to be retried after %s, got %s", tt.attempt, tt.want, got)
+			}
+		})
+	}
+
+	jittered := models.RetryPolicy{MaxAttempts: 5, InitialIntervalSeconds: ptr(10.0), Jitter: ptr(0.5)}
+	for i := 0; i < 20; i++ {
+		if got := retryInterval(&jittered, 1); got < 5*time.Second || got > 15*time.Second {
+			t.Fatalf("expected the jittered interval to stay within 50%% of 10s, got %s", got)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		step      func(step models.WorkflowStepDefinition) models.WorkflowStepDefinition
+		errorCode string
+		// failures is the number of attempts failing in a row.
+		failures int
+		want     models.StepInstanceStatus
+		attempts int
+	}{
+		{
+			name: "retried until an attempt completes",
+			step: func(step models.WorkflowStepDefinition) models.WorkflowStepDefinition {
+				step.RetryCount = ptr(2)
+				return step
+			},
+			failures: 2,
+			want:     models.StepInstanceStatusCompleted,
+			attempts: 3,
+		},
+		{
+			name: "failed once the attempts are exhausted",
+			step: func(step models.WorkflowStepDefinition) models.WorkflowStepDefinition {
+				step.RetryCount = ptr(2)
+				return step
+			},
+			failures: 3,
+			want:     models.StepInstanceStatusFailed,
+			attempts: 3,
+		},
+		{
+			name: "failed at once on a non-retryable error code",
+			step: func(step models.WorkflowStepDefinition) models.WorkflowStepDefinition {
+				step.RetryPolicy = &models.RetryPolicy{MaxAttempts: 3, NonRetryableErrorCodes: []string{"INVALID_INPUT"}}
+				return step
+			},
+			errorCode: "INVALID_INPUT",
+			failures:  1,
+			want:      models.StepInstanceStatusFailed,
+			attempts:  1,
+		},
+		{
+			name: "retried on another error code",
+			step: func(step models.WorkflowStepDefinition) models.WorkflowStepDefinition {
+				step.RetryPolicy = &models.RetryPolicy{MaxAttempts: 3, NonRetryableErrorCodes: []string{"INVALID_INPUT"}}
+				return step
+			},
+			errorCode: "UNAVAILABLE",
+			failures:  1,
+			want:      models.StepInstanceStatusCompleted,
+			attempts:  2,
+		},
+		{
+			name:     "never retried without a policy",
+			step:     func(step models.WorkflowStepDefinition) models.WorkflowStepDefinition { return step },
+			failures: 1,
+			want:     models.StepInstanceStatusFailed,
+			attempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			instance := e.start(e.define(tt.step(taskStep("a"))), nil)
+
+			for i := 0; i < tt.failures; i++ {
+				e.finishTask(instance, "a", proto.TaskStatus_FAILED, nil, tt.errorCode)
+				if step := e.step(instance, "a"); step.Status == models.StepInstanceStatusRetrying {
+					e.fireTimer(e.timer(step, models.TimerTypeRetry))
+				}
+			}
+			if step := e.step(instance, "a"); step.Status == models.StepInstanceStatusRunning {
+				e.finishTask(instance, "a", proto.TaskStatus_COMPLETED, nil, "")
+			}
+
+			step := e.step(instance, "a")
+			if step.Status != tt.want || step.Attempt != tt.attempts {
+				t.Fatalf("expected step to be %s on attempt %d, got %s on attempt %d", tt.want, tt.attempts, step.Status, step.Attempt)
+			}
+			if started := e.agent.startedTasks(); len(started) != tt.attempts || started[len(started)-1].GetAttempt() != int32(tt.attempts) {
+				t.Fatalf("expected a task to be started for each of the %d attempts, got %v", tt.attempts, started)
+			}
+
+			attempts, _ := e.attempts.GetByStepInstanceID(step.ID.String())
+			if len(attempts) != tt.attempts {
+				t.Fatalf("expected %d attempts to be recorded, got %d", tt.attempts, len(attempts))
+			}
+			for i, attempt := range attempts[:len(attempts)-1] {
+				if attempt.Status != models.StepAttemptStatusFailed || attempt.RetryAt == nil {
+					t.Fatalf("expected attempt %d to fail and be retried, got %+v", attempt.Attempt, attempt)
+				}
+				if taskID := fmt.Sprintf("task-%d", i+1); stringOf(attempt.AgentTaskID) != taskID {
+					t.Fatalf("expected attempt %d to record task %s, got %s", attempt.Attempt, taskID, stringOf(attempt.AgentTaskID))
+				}
+			}
+			last := attempts[len(attempts)-1]
+			if last.RetryAt != nil || last.CompletedAt == nil {
+				t.Fatalf("expected the last attempt to end without a retry, got %+v", last)
+			}
+			if tt.errorCode != "" && stringOf(attempts[0].ErrorCode) != tt.errorCode {
+				t.Fatalf("expected the error code of the attempt to be recorded, got %s", stringOf(attempts[0].ErrorCode))
+			}
+		})
+	}
+}
+
+func TestRetryTimer(t *testing.T) {
+	e := newTestEnv(t)
+	step := taskStep("a")
+	step.RetryPolicy = &models.RetryPolicy{MaxAttempts: 2, InitialIntervalSeconds: ptr(30.0)}
+	instance := e.start(e.define(step), nil)
+
+	failedAt := time.Now()
+	e.finishTask(instance, "a", proto.TaskStatus_FAILED, nil, "")
+	retrying := e.step(instance, "a")
+	if retrying.AgentTaskID == nil || stringOf(retrying.Error) != "task FAILED" {
+		t.Fatalf("expected the retrying step to keep its task and error until the next attempt, got %+v", retrying)
+	}
+	timer := e.timer(retrying, models.TimerTypeRetry)
+	if delay := timer.FireAt.Sub(failedAt); delay < 29*time.Second || delay > 31*time.Second {
+		t.Fatalf("expected the retry timer to fire after 30s, got %s", delay)
+	}
+
+	// Steps waiting for their retry timer are not started by the queue.
+	e.scheduler.enqueue(retrying.ID)
+	e.run()
+	if started := e.agent.startedTasks(); len(started) != 1 {
+		t.Fatalf("expected the retry to wait for its timer, got %d tasks", len(started))
+	}
+
+	e.fireTimer(timer)
+	next := e.step(instance, "a")
+	if next.Status != models.StepInstanceStatusRunning || next.Attempt != 2 || stringOf(next.AgentTaskID) != "task-2" || next.Error != nil {
+		t.Fatalf("expected the second attempt to start a new task, got %+v", next)
+	}
+
+	// The second attempt is the last one, so its failure fails the step.
+	e.finishTask(instance, "a", proto.TaskStatus_FAILED, nil, engineErrors.ErrorCodeTimedOut)
+	if got := e.step(instance, "a"); got.Status != models.StepInstanceStatusFailed {
+		t.Fatalf("expected the last attempt to fail the step, got %s", got.Status)
+	}
+	e.expectInstance(instance, models.WorkflowInstanceStatusFailed)
+}
@@ -0,0 +1,783 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
+)
+
+const instanceLockCount = 64
+
+type Config struct {
+	Workers   int
+	QueueSize int
+	// TimerPollInterval is how often due timers are claimed from the database.
+	TimerPollInterval time.Duration
+	// TimerBatchSize is the maximum number of timers claimed per poll.
+	TimerBatchSize int
+	// TimerClaimTimeout is how long a claimed timer may go without its effect
+	// being applied before another poll claims it again.
+	TimerClaimTimeout time.Duration
+}
+
+type Scheduler struct {
+	cfg      *Config
+	Registry *HandlerRegistry
+
+	workflowDefinitionRepo persistance.WorkflowDefinitionRepository
+	workflowInstanceRepo   persistance.WorkflowInstanceRepository
+	stepInstanceRepo       persistance.StepInstanceRepository
+	timerRepo              persistance.TimerRepository
+	stepAttemptRepo        persistance.StepAttemptRepository
+
+	queue chan uuid.UUID
+	locks [instanceLockCount]sync.Mutex
+}
+
+func NewScheduler(
+	cfg *Config,
+	workflowDefinitionRepo persistance.WorkflowDefinitionRepository,
+	workflowInstanceRepo persistance.WorkflowInstanceRepository,
+	stepInstanceRepo persistance.StepInstanceRepository,
+	timerRepo persistance.TimerRepository,
+	stepAttemptRepo persistance.StepAttemptRepository,
+) *Scheduler {
+	return &Scheduler{
+		cfg:                    cfg,
+		Registry:               NewHandlerRegistry(),
+		workflowDefinitionRepo: workflowDefinitionRepo,
+		workflowInstanceRepo:   workflowInstanceRepo,
+		stepInstanceRepo:       stepInstanceRepo,
+		timerRepo:              timerRepo,
+		stepAttemptRepo:        stepAttemptRepo,
+		queue:                  make(chan uuid.UUID, cfg.QueueSize),
+	}
+}
+
+// Start resumes the workflow instances left unfinished by a previous run and
+// processes scheduled steps and due timers until the context is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	if err := s.recover(); err != nil {
+		log.Printf("[scheduler] failed to recover workflow instances: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.pollTimers(ctx)
+	}()
+	for i := 0; i < s.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// StartWorkflow creates a workflow instance for the given definition and
+// schedules its first step.
+func (s *Scheduler) StartWorkflow(definition *models.WorkflowDefinition, input map[string]interface{}) (*models.WorkflowInstance, error) {
+	return s.startWorkflow(definition, input, nil)
+}
+
+// startWorkflow creates a workflow instance, as the child of the given
+// workflow step instance if any.
+func (s *Scheduler) startWorkflow(definition *models.WorkflowDefinition, input map[string]interface{}, parentStep *models.StepInstance) (*models.WorkflowInstance, error) {
+	firstStep, err := definition.GetFirstStep()
+	if err != nil {
+		return nil, err
+	}
+
+	inputMap := models.JsonMap(input)
+	currentSteps := models.StringList{firstStep.StepDefinitionID}
+	instance := &models.WorkflowInstance{
+		WorkflowDefinitionID:      definition.ID,
+		WorkflowDefinitionVersion: definition.Version,
+		Status:                    models.WorkflowInstanceStatusPending,
+		Input:                     &inputMap,
+		CurrentStepIDs:            &currentSteps,
+	}
+	if parentStep != nil {
+		instance.ParentWorkflowInstanceID = &parentStep.WorkflowInstanceID
+		instance.ParentStepInstanceID = &parentStep.ID
+	}
+
+	instance, err = s.workflowInstanceRepo.Create(instance)
+	if err != nil {
+		return nil, fmt.Errorf("create workflow instance: %w", err)
+	}
+	if err := s.armWorkflowTimeout(definition, instance); err != nil {
+		return nil, fmt.Errorf("create workflow timeout timer: %w", err)
+	}
+
+	step, err := s.stepInstanceRepo.Create(&models.StepInstance{
+		WorkflowInstanceID: instance.ID,
+		StepDefinitionID:   firstStep.StepDefinitionID,
+		StepType:           firstStep.Type,
+		Status:             models.StepInstanceStatusPending,
+		Attempt:            1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create first step instance: %w", err)
+	}
+
+	log.Printf("[scheduler] workflow instance %s created from definition %s@%s", instance.ID, definition.Name, definition.Version)
+	s.enqueue(step.ID)
+	return instance, nil
+}
+
+// CompleteStep completes a running step with the given output and schedules
+// the steps that follow it. Completing a step that is not running is a no-op.
+func (s *Scheduler) CompleteStep(stepInstanceID uuid.UUID, output map[string]interface{}) error {
+	return s.withExecution(stepInstanceID, func(execution *StepExecution) error {
+		if execution.Step.Status != models.StepInstanceStatusRunning || execution.Instance.Status.IsTerminal() {
+			return nil
+		}
+		return s.completeStep(execution, completed(output))
+	})
+}
+
+// FailStep fails a step and the workflow instance it belongs to, unless the
+// step runs in a fork branch, in which case the failure is handed to the join
+// step. Failing a step that already reached a terminal status is a no-op.
+func (s *Scheduler) FailStep(stepInstanceID uuid.UUID, cause error) error {
+	return s.withExecution(stepInstanceID, func(execution *StepExecution) error {
+		if execution.Step.Status.IsTerminal() || execution.Instance.Status.IsTerminal() {
+			return nil
+		}
+		return s.failStep(execution, cause)
+	})
+}
+
+// AbortStep fails a step like FailStep, without retrying it whatever its
+// retry policy.
+func (s *Scheduler) AbortStep(stepInstanceID uuid.UUID, cause error) error {
+	return s.withExecution(stepInstanceID, func(execution *StepExecution) error {
+		if execution.Step.Status.IsTerminal() || execution.Instance.Status.IsTerminal() {
+			return nil
+		}
+		if err := s.timerRepo.CancelByStepInstanceID(execution.Step.ID.String()); err != nil {
+			return err
+		}
+		return s.finishStep(execution, cause, models.StepInstanceStatusFailed, models.StepAttemptStatusFailed)
+	})
+}
+
+// CancelWorkflow cancels a workflow instance and every step still active in
+// it. Running child workflows and agent tasks are cancelled as well.
+func (s *Scheduler) CancelWorkflow(workflowInstanceID uuid.UUID, reason string) error {
+	return s.endWorkflow(workflowInstanceID, models.WorkflowInstanceStatusCancelled, reason)
+}
+
+// PauseWorkflow pauses an active workflow instance. No step of a paused
+// instance starts until it is resumed, and the agent tasks and child workflows
+// of its running steps are paused as well.
+func (s *Scheduler) PauseWorkflow(workflowInstanceID uuid.UUID) error {
+	return s.withInstanceExecution(workflowInstanceID, func(execution *StepExecution) error {
+		instance := execution.Instance
+		if instance.Status != models.WorkflowInstanceStatusPending && instance.Status != models.WorkflowInstanceStatusRunning {
+			return fmt.Errorf("%w: %s is %s", errors.ErrWorkflowInstanceNotRunning, instance.ID, instance.Status)
+		}
+
+		instance.Status = models.WorkflowInstanceStatusPaused
+		if _, err := s.workflowInstanceRepo.Update(instance); err != nil {
+			return err
+		}
+		log.Printf("[scheduler] workflow instance %s paused", instance.ID)
+
+		for _, step := range execution.Steps {
+			if step.Status == models.StepInstanceStatusRunning {
+				s.pauseStep(execution, step, StepPauser.Pause)
+			}
+		}
+		return nil
+	})
+}
+
+// ResumeWorkflow resumes a paused workflow instance, resuming the work paused
+// along with it and enqueuing every step still active in it.
+func (s *Scheduler) ResumeWorkflow(workflowInstanceID uuid.UUID) error {
+	return s.withInstanceExecution(workflowInstanceID, func(execution *StepExecution) error {
+		instance := execution.Instance
+		if instance.Status != models.WorkflowInstanceStatusPaused {
+			return fmt.Errorf("%w: %s is %s", errors.ErrWorkflowInstanceNotPaused, instance.ID, instance.Status)
+		}
+
+		// An instance paused before its first step ran is started by process.
+		instance.Status = models.WorkflowInstanceStatusRunning
+		if instance.StartedAt == nil {
+			instance.Status = models.WorkflowInstanceStatusPending
+		}
+		if _, err := s.workflowInstanceRepo.Update(instance); err != nil {
+			return err
+		}
+		log.Printf("[scheduler] workflow instance %s resumed", instance.ID)
+
+		for _, step := range execution.Steps {
+			if step.Status == models.StepInstanceStatusRunning {
+				s.pauseStep(execution, step, StepPauser.Resume)
+			}
+			// Retrying steps resume when their retry timer fires.
+			if !step.Status.IsTerminal() && step.Status != models.StepInstanceStatusRetrying {
+				s.enqueue(step.ID)
+			}
+		}
+		return nil
+	})
+}
+
+// endWorkflow moves an active workflow instance to the given terminal status
+// and cancels every step still active in it.
+func (s *Scheduler) endWorkflow(workflowInstanceID uuid.UUID, status models.WorkflowInstanceStatus, reason string) error {
+	return s.withInstanceExecution(workflowInstanceID, func(execution *StepExecution) error {
+		instance := execution.Instance
+		if instance.Status.IsTerminal() {
+			return fmt.Errorf("%w: %s is %s", errors.ErrWorkflowInstanceNotActive, instance.ID, instance.Status)
+		}
+
+		for _, step := range execution.Steps {
+			if step.Status.IsTerminal() {
+				continue
+			}
+			if err := s.cancelStep(execution, step, reason); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		instance.Status = status
+		instance.Error = &reason
+		instance.CompletedAt = &now
+		instance.CurrentStepIDs = activeStepIDs(execution)
+
+		log.Printf("[scheduler] workflow instance %s %s: %s", instance.ID, status, reason)
+		return s.finishInstance(instance)
+	})
+}
+
+func (s *Scheduler) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case stepInstanceID := <-s.queue:
+			err := s.withExecution(stepInstanceID, func(execution *StepExecution) error {
+				return s.process(ctx, execution)
+			})
+			if err != nil {
+				log.Printf("[scheduler] failed to process step instance %s: %v", stepInstanceID, err)
+			}
+		}
+	}
+}
+
+func (s *Scheduler) process(ctx context.Context, execution *StepExecution) error {
+	// Retrying steps resume when their retry timer fires.
+	if execution.Step.Status.IsTerminal() || execution.Step.Status == models.StepInstanceStatusRetrying {
+		return nil
+	}
+
+	instance := execution.Instance
+	if instance.Status == models.WorkflowInstanceStatusPending {
+		now := time.Now()
+		instance.Status = models.WorkflowInstanceStatusRunning
+		instance.StartedAt = &now
+		if _, err := s.workflowInstanceRepo.Update(instance); err != nil {
+			return err
+		}
+	}
+	// Steps of a paused instance are enqueued again once it is resumed.
+	if instance.Status != models.WorkflowInstanceStatusRunning {
+		return nil
+	}
+
+	handler, exists := s.Registry.GetHandler(execution.StepDefinition.Type)
+	if !exists {
+		return s.failStep(execution, fmt.Errorf("%w: %s", errors.ErrNoStepHandler, execution.StepDefinition.Type))
+	}
+
+	if execution.Step.Status == models.StepInstanceStatusPending {
+		if err := execution.Step.TransitionTo(models.StepInstanceStatusRunning); err != nil {
+			return err
+		}
+		if _, err := s.stepInstanceRepo.Update(execution.Step); err != nil {
+			return err
+		}
+		if err := s.startAttempt(execution.Step); err != nil {
+			return err
+		}
+	}
+	if err := s.armStepTimeout(execution); err != nil {
+		return err
+	}
+
+	result, err := handler.Handle(ctx, execution)
+	if err != nil {
+		return s.failStep(execution, err)
+	}
+
+	if !result.Completed {
+		_, err := s.stepInstanceRepo.Update(execution.Step)
+		return err
+	}
+
+	return s.completeStep(execution, result)
+}
+
+func (s *Scheduler) completeStep(execution *StepExecution, result *StepResult) error {
+	step := execution.Step
+	if err := step.TransitionTo(models.StepInstanceStatusCompleted); err != nil {
+		return err
+	}
+	if result.Output != nil {
+		output := models.JsonMap(result.Output)
+		step.Output = &output
+	}
+	if _, err := s.stepInstanceRepo.Update(step); err != nil {
+		return err
+	}
+	if err := s.finishAttempt(step, models.StepAttemptStatusCompleted, nil, nil); err != nil {
+		return err
+	}
+	if err := s.timerRepo.CancelByStepInstanceID(step.ID.String()); err != nil {
+		return err
+	}
+
+	nextStepIDs := result.NextStepIDs
+	if len(nextStepIDs) == 0 {
+		if next := execution.StepDefinition.GetNextStepID(); next != nil {
+			nextStepIDs = []string{*next}
+		}
+	}
+
+	scopeID := step.ParentStepInstanceID
+	if execution.StepDefinition.GetJoinStepID() != nil {
+		scopeID = &step.ID
+	}
+
+	// A step without successor inside a fork or decision ends its branch,
+	// which converges at the join step of the enclosing scope.
+	if len(nextStepIDs) == 0 && step.ParentStepInstanceID != nil {
+		if joinStepID := s.getScopeJoinStepID(execution, *step.ParentStepInstanceID); joinStepID != nil {
+			nextStepIDs = []string{*joinStepID}
+		}
+	}
+
+	for _, nextStepID := range nextStepIDs {
+		if err := s.scheduleStep(execution, nextStepID, scopeID); err != nil {
+			return s.failInstance(execution, err)
+		}
+	}
+
+	return s.refreshInstance(execution, step)
+}
+
+func (s *Scheduler) failStep(execution *StepExecution, cause error) error {
+	return s.endStep(execution, cause, models.StepInstanceStatusFailed, models.StepAttemptStatusFailed)
+}
+
+// endStep ends the current attempt of a step that failed or timed out. The
+// step is retried if its retry policy allows it, and otherwise ends with the
+// given status.
+func (s *Scheduler) endStep(execution *StepExecution, cause error, status models.StepInstanceStatus, attemptStatus models.StepAttemptStatus) error {
+	step := execution.Step
+	if err := s.timerRepo.CancelByStepInstanceID(step.ID.String()); err != nil {
+		return err
+	}
+	if retryAt, retry := shouldRetry(execution, cause); retry {
+		return s.retryStep(execution, cause, attemptStatus, retryAt)
+	}
+	return s.finishStep(execution, cause, status, attemptStatus)
+}
+
+// finishStep ends a step that failed or timed out for good with the given
+// status, failing the workflow instance unless the step runs in a fork branch.
+func (s *Scheduler) finishStep(execution *StepExecution, cause error, status models.StepInstanceStatus, attemptStatus models.StepAttemptStatus) error {
+	step := execution.Step
+	log.Printf("[scheduler] step %s of workflow instance %s %s: %v", step.StepDefinitionID, step.WorkflowInstanceID, status, cause)
+	if err := s.finishAttempt(step, attemptStatus, cause, nil); err != nil {
+		return err
+	}
+
+	if err := step.TransitionTo(status); err != nil {
+		return err
+	}
+	message := cause.Error()
+	step.Error = &message
+	if _, err := s.stepInstanceRepo.Update(step); err != nil {
+		return err
+	}
+
+	// A failure inside a fork only ends its branch: the join step decides
+	// whether the workflow can go on according to its policy.
+	if joinStepID := s.getBranchJoinStepID(execution, step); joinStepID != nil {
+		if err := s.scheduleStep(execution, *joinStepID, step.ParentStepInstanceID); err != nil {
+			return s.failInstance(execution, err)
+		}
+		return s.refreshInstance(execution, step)
+	}
+
+	return s.failInstance(execution, fmt.Errorf("step %s %s: %w", step.StepDefinitionID, status, cause))
+}
+
+// cancelScope cancels every step still active within a fork or decision scope,
+// including the steps of nested scopes.
+func (s *Scheduler) cancelScope(execution *StepExecution, scopeID uuid.UUID, reason string) error {
+	scopes := []uuid.UUID{scopeID}
+	for len(scopes) > 0 {
+		current := scopes[0]
+		scopes = scopes[1:]
+
+		for _, step := range execution.Steps {
+			if step.ParentStepInstanceID == nil || *step.ParentStepInstanceID != current {
+				continue
+			}
+			scopes = append(scopes, step.ID)
+			if step.Status.IsTerminal() {
+				continue
+			}
+			if err := s.cancelStep(execution, step, reason); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) cancelStep(execution *StepExecution, step *models.StepInstance, reason string) error {
+	wasRunning := step.Status == models.StepInstanceStatusRunning
+	if err := step.TransitionTo(models.StepInstanceStatusCancelled); err != nil {
+		return err
+	}
+	step.Error = &reason
+	if _, err := s.stepInstanceRepo.Update(step); err != nil {
+		return err
+	}
+	log.Printf("[scheduler] step %s of workflow instance %s cancelled: %s", step.StepDefinitionID, step.WorkflowInstanceID, reason)
+
+	if err := s.finishAttempt(step, models.StepAttemptStatusCancelled, fmt.Errorf("%s", reason), nil); err != nil {
+		return err
+	}
+	if err := s.timerRepo.CancelByStepInstanceID(step.ID.String()); err != nil {
+		return err
+	}
+
+	if wasRunning {
+		s.releaseStep(execution, step)
+	}
+	return nil
+}
+
+// releaseStep asks the handler of a step that stops running before it
+// completed to release what it started, such as an agent task. The handler is
+// called once the lock of the workflow instance is released.
+func (s *Scheduler) releaseStep(execution *StepExecution, step *models.StepInstance) {
+	handler, exists := s.Registry.GetHandler(step.StepType)
+	if !exists {
+		return
+	}
+	if canceller, ok := handler.(StepCanceller); ok {
+		released := *step
+		execution.afterUnlock(func() {
+			if err := canceller.Cancel(execution, &released); err != nil {
+				log.Printf("[scheduler] failed to release step %s: %v", released.ID, err)
+			}
+		})
+	}
+}
+
+// pauseStep pauses or resumes the work held by a running step outside the
+// engine, if its handler supports it. The handler is called once the lock of
+// the workflow instance is released.
+func (s *Scheduler) pauseStep(execution *StepExecution, step *models.StepInstance, action func(StepPauser, *StepExecution, *models.StepInstance) error) {
+	handler, exists := s.Registry.GetHandler(step.StepType)
+	if !exists {
+		return
+	}
+	if pauser, ok := handler.(StepPauser); ok {
+		paused := *step
+		execution.afterUnlock(func() {
+			if err := action(pauser, execution, &paused); err != nil {
+				log.Printf("[scheduler] failed to pause or resume step %s: %v", paused.ID, err)
+			}
+		})
+	}
+}
+
+func (s *Scheduler) failInstance(execution *StepExecution, cause error) error {
+	now := time.Now()
+	message := cause.Error()
+	instance := execution.Instance
+	instance.Status = models.WorkflowInstanceStatusFailed
+	instance.Error = &message
+	instance.CompletedAt = &now
+	instance.CurrentStepIDs = activeStepIDs(execution)
+
+	log.Printf("[scheduler] workflow instance %s failed: %v", instance.ID, cause)
+	return s.finishInstance(instance)
+}
+
+// refreshInstance records the steps that are still active on the workflow
+// instance and completes it once none are left.
+func (s *Scheduler) refreshInstance(execution *StepExecution, lastStep *models.StepInstance) error {
+	instance := execution.Instance
+	instance.CurrentStepIDs = activeStepIDs(execution)
+
+	if len(*instance.CurrentStepIDs) == 0 {
+		now := time.Now()
+		instance.Status = models.WorkflowInstanceStatusCompleted
+		instance.Output = lastStep.Output
+		instance.CompletedAt = &now
+		log.Printf("[scheduler] workflow instance %s completed", instance.ID)
+	}
+
+	if instance.Status.IsTerminal() {
+		return s.finishInstance(instance)
+	}
+	_, err := s.workflowInstanceRepo.Update(instance)
+	return err
+}
+
+// finishInstance stores a workflow instance that reached a terminal status,
+// cancels the timers still pending for it and notifies its parent.
+func (s *Scheduler) finishInstance(instance *models.WorkflowInstance) error {
+	if _, err := s.workflowInstanceRepo.Update(instance); err != nil {
+		return err
+	}
+	if err := s.timerRepo.CancelByWorkflowInstanceID(instance.ID.String()); err != nil {
+		return err
+	}
+	s.notifyParent(instance)
+	return nil
+}
+
+// notifyParent wakes up the workflow step that started a finished child
+// instance. The step is enqueued rather than completed directly, so the lock
+// of the child instance is never held together with the lock of its parent.
+func (s *Scheduler) notifyParent(instance *models.WorkflowInstance) {
+	if instance.ParentStepInstanceID != nil {
+		s.enqueue(*instance.ParentStepInstanceID)
+	}
+}
+
+// scheduleStep creates a pending instance of the given step within a scope and
+// enqueues it. If the step was already scheduled, it is enqueued again so that
+// join steps can re-evaluate their incoming branches.
+func (s *Scheduler) scheduleStep(execution *StepExecution, stepDefinitionID string, scopeID *uuid.UUID) error {
+	stepDefinition, exists := execution.Definition.GetStepByID(stepDefinitionID)
+	if !exists {
+		return fmt.Errorf("%w: %s", errors.ErrStepDefinitionNotFound, stepDefinitionID)
+	}
+
+	// Joining a scope leaves it: the join step belongs to the enclosing scope.
+	if scopeID != nil {
+		if joinStepID := s.getScopeJoinStepID(execution, *scopeID); joinStepID != nil && *joinStepID == stepDefinitionID {
+			scope, _ := execution.getStepInstanceByID(*scopeID)
+			scopeID = scope.ParentStepInstanceID
+		}
+	}
+
+	if existing, exists := execution.GetStepInstance(stepDefinitionID); exists {
+		if !existing.Status.IsTerminal() {
+			s.enqueue(existing.ID)
+		}
+		return nil
+	}
+
+	step, err := s.stepInstanceRepo.Create(&models.StepInstance{
+		WorkflowInstanceID:   execution.Instance.ID,
+		StepDefinitionID:     stepDefinition.StepDefinitionID,
+		StepType:             stepDefinition.Type,
+		Status:               models.StepInstanceStatusPending,
+		Attempt:              1,
+		ParentStepInstanceID: scopeID,
+	})
+	if err != nil {
+		return fmt.Errorf("create step instance %s: %w", stepDefinitionID, err)
+	}
+
+	execution.Steps = append(execution.Steps, step)
+	s.enqueue(step.ID)
+	return nil
+}
+
+// getBranchJoinStepID returns the join step of the fork whose branch the step
+// belongs to, if any.
+func (s *Scheduler) getBranchJoinStepID(execution *StepExecution, step *models.StepInstance) *string {
+	if step.ParentStepInstanceID == nil {
+		return nil
+	}
+	scope, exists := execution.getStepInstanceByID(*step.ParentStepInstanceID)
+	if !exists || scope.StepType != models.StepTypeFork {
+		return nil
+	}
+	return s.getScopeJoinStepID(execution, scope.ID)
+}
+
+func (s *Scheduler) getScopeJoinStepID(execution *StepExecution, scopeID uuid.UUID) *string {
+	scope, exists := execution.getStepInstanceByID(scopeID)
+	if !exists {
+		return nil
+	}
+	scopeDefinition, exists := execution.Definition.GetStepByID(scope.StepDefinitionID)
+	if !exists {
+		return nil
+	}
+	return scopeDefinition.GetJoinStepID()
+}
+
+// withExecution loads the execution context of a step instance while holding
+// the lock of its workflow instance.
+func (s *Scheduler) withExecution(stepInstanceID uuid.UUID, fn func(execution *StepExecution) error) error {
+	step, err := s.stepInstanceRepo.GetByID(stepInstanceID.String())
+	if err != nil {
+		return fmt.Errorf("load step instance: %w", err)
+	}
+
+	return s.withLock(step.WorkflowInstanceID, func() (*StepExecution, error) {
+		return s.loadExecution(step.WorkflowInstanceID, stepInstanceID)
+	}, fn)
+}
+
+// withInstanceExecution loads the execution context of a workflow instance,
+// without selecting a step, while holding its lock.
+func (s *Scheduler) withInstanceExecution(workflowInstanceID uuid.UUID, fn func(execution *StepExecution) error) error {
+	return s.withLock(workflowInstanceID, func() (*StepExecution, error) {
+		return s.loadInstanceExecution(workflowInstanceID)
+	}, fn)
+}
+
+// withLock runs fn on the loaded execution while holding the lock of the
+// workflow instance, then makes the calls deferred with afterUnlock once the
+// lock is released. They are made even if fn fails, since the changes stored
+// before the failure are not rolled back.
+func (s *Scheduler) withLock(workflowInstanceID uuid.UUID, load func() (*StepExecution, error), fn func(execution *StepExecution) error) error {
+	var execution *StepExecution
+	err := func() error {
+		unlock := s.lockInstance(workflowInstanceID)
+		defer unlock()
+
+		var err error
+		if execution, err = load(); err != nil {
+			return err
+		}
+		return fn(execution)
+	}()
+
+	if execution != nil {
+		for _, call := range execution.unlocked {
+			call()
+		}
+	}
+	return err
+}
+
+func (s *Scheduler) loadExecution(workflowInstanceID uuid.UUID, stepInstanceID uuid.UUID) (*StepExecution, error) {
+	execution, err := s.loadInstanceExecution(workflowInstanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	execution.Step, _ = execution.getStepInstanceByID(stepInstanceID)
+	if execution.Step == nil {
+		return nil, fmt.Errorf("step instance %s not found in workflow instance %s", stepInstanceID, workflowInstanceID)
+	}
+
+	stepDefinition, exists := execution.Definition.GetStepByID(execution.Step.StepDefinitionID)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", errors.ErrStepDefinitionNotFound, execution.Step.StepDefinitionID)
+	}
+	execution.StepDefinition = stepDefinition
+
+	return execution, nil
+}
+
+// loadInstanceExecution loads a workflow instance with its definition and step
+// instances, without selecting a step.
+func (s *Scheduler) loadInstanceExecution(workflowInstanceID uuid.UUID) (*StepExecution, error) {
+	instance, err := s.workflowInstanceRepo.GetByID(workflowInstanceID.String())
+	if err != nil {
+		return nil, fmt.Errorf("load workflow instance: %w", err)
+	}
+
+	definition, err := s.workflowDefinitionRepo.GetByID(instance.WorkflowDefinitionID.String())
+	if err != nil {
+		return nil, fmt.Errorf("load workflow definition: %w", err)
+	}
+
+	steps, err := s.stepInstanceRepo.GetByWorkflowInstanceID(workflowInstanceID.String())
+	if err != nil {
+		return nil, fmt.Errorf("load step instances: %w", err)
+	}
+
+	execution := &StepExecution{
+		Definition: definition,
+		Instance:   instance,
+		Steps:      make([]*models.StepInstance, 0, len(steps)),
+	}
+	for i := range steps {
+		execution.Steps = append(execution.Steps, &steps[i])
+	}
+
+	return execution, nil
+}
+
+// recover enqueues the unfinished steps of every workflow instance that was
+// still pending or running when the engine stopped.
+func (s *Scheduler) recover() error {
+	for _, status := range []models.WorkflowInstanceStatus{models.WorkflowInstanceStatusPending, models.WorkflowInstanceStatusRunning} {
+		instances, err := s.workflowInstanceRepo.GetByStatus(status)
+		if err != nil {
+			return err
+		}
+
+		for _, instance := range instances {
+			steps, err := s.stepInstanceRepo.GetByWorkflowInstanceID(instance.ID.String())
+			if err != nil {
+				return err
+			}
+			for _, step := range steps {
+				if !step.Status.IsTerminal() {
+					s.enqueue(step.ID)
+				}
+			}
+			log.Printf("[scheduler] recovered workflow instance %s", instance.ID)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) enqueue(stepInstanceID uuid.UUID) {
+	select {
+	case s.queue <- stepInstanceID:
+	default:
+		// Never block the caller, which may hold an instance lock a worker needs.
+		go func() { s.queue <- stepInstanceID }()
+	}
+}
+
+func (s *Scheduler) lockInstance(workflowInstanceID uuid.UUID) func() {
+	mu := &s.locks[int(workflowInstanceID[len(workflowInstanceID)-1])%instanceLockCount]
+	mu.Lock()
+	return mu.Unlock
+}
+
+func activeStepIDs(execution *StepExecution) *models.StringList {
+	active := make(models.StringList, 0)
+	for _, step := range execution.Steps {
+		if !step.Status.IsTerminal() {
+			active = append(active, step.StepDefinitionID)
+		}
+	}
+	return &active
+}
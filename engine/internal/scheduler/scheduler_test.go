@@ -0,0 +1,1148 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/registry"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+)
+
+// The repositories below keep their records in memory and mimic the queries
+// of the database repositories the scheduler relies on. Methods the scheduler
+// never calls are left to the embedded interface.
+
+type memoryWorkflowDefinitionRepository struct {
+	persistance.WorkflowDefinitionRepository
+	mu          sync.Mutex
+	definitions map[uuid.UUID]models.WorkflowDefinition
+}
+
+func (r *memoryWorkflowDefinitionRepository) GetByID(id string) (*models.WorkflowDefinition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	definition, found := r.definitions[uuid.MustParse(id)]
+	if !found {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &definition, nil
+}
+
+func (r *memoryWorkflowDefinitionRepository) Create(definition *models.WorkflowDefinition) (*models.WorkflowDefinition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if definition.ID == uuid.Nil {
+		definition.ID = uuid.New()
+	}
+	r.definitions[definition.ID] = *definition
+	return definition, nil
+}
+
+type memoryWorkflowInstanceRepository struct {
+	persistance.WorkflowInstanceRepository
+	mu        sync.Mutex
+	instances []models.WorkflowInstance
+}
+
+func (r *memoryWorkflowInstanceRepository) GetByID(id string) (*models.WorkflowInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, instance := range r.instances {
+		if instance.ID.String() == id {
+			return &instance, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryWorkflowInstanceRepository) GetByStatus(status models.WorkflowInstanceStatus) ([]models.WorkflowInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instances := make([]models.WorkflowInstance, 0)
+	for _, instance := range r.instances {
+		if instance.Status == status {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+func (r *memoryWorkflowInstanceRepository) GetByParentStepInstanceID(parentStepInstanceID string) (*models.WorkflowInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.instances) - 1; i >= 0; i-- {
+		instance := r.instances[i]
+		if instance.ParentStepInstanceID != nil && instance.ParentStepInstanceID.String() == parentStepInstanceID {
+			return &instance, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryWorkflowInstanceRepository) Create(instance *models.WorkflowInstance) (*models.WorkflowInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instance.ID = uuid.New()
+	instance.CreatedAt = time.Now()
+	instance.UpdatedAt = instance.CreatedAt
+	r.instances = append(r.instances, *instance)
+	return instance, nil
+}
+
+func (r *memoryWorkflowInstanceRepository) Update(instance *models.WorkflowInstance) (*models.WorkflowInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.instances {
+		if r.instances[i].ID == instance.ID {
+			instance.UpdatedAt = time.Now()
+			r.instances[i] = *instance
+			return instance, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type memoryStepInstanceRepository struct {
+	mu    sync.Mutex
+	steps []models.StepInstance
+}
+
+func (r *memoryStepInstanceRepository) find(match func(step models.StepInstance) bool) *models.StepInstance {
+	for _, step := range r.steps {
+		if match(step) {
+			return &step
+		}
+	}
+	return nil
+}
+
+func (r *memoryStepInstanceRepository) filter(match func(step models.StepInstance) bool) []models.StepInstance {
+	steps := make([]models.StepInstance, 0)
+	for _, step := range r.steps {
+		if match(step) {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+func (r *memoryStepInstanceRepository) GetByID(id string) (*models.StepInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	step := r.find(func(step models.StepInstance) bool { return step.ID.String() == id })
+	if step == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return step, nil
+}
+
+func (r *memoryStepInstanceRepository) GetByWorkflowInstanceID(workflowInstanceID string) ([]models.StepInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.filter(func(step models.StepInstance) bool {
+		return step.WorkflowInstanceID.String() == workflowInstanceID
+	}), nil
+}
+
+func (r *memoryStepInstanceRepository) GetByStepDefinitionID(workflowInstanceID string, stepDefinitionID string) (*models.StepInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.find(func(step models.StepInstance) bool {
+		return step.WorkflowInstanceID.String() == workflowInstanceID && step.StepDefinitionID == stepDefinitionID
+	}), nil
+}
+
+func (r *memoryStepInstanceRepository) GetByAgentTaskID(agentTaskID string) (*models.StepInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.find(func(step models.StepInstance) bool {
+		return step.AgentTaskID != nil && *step.AgentTaskID == agentTaskID
+	}), nil
+}
+
+func (r *memoryStepInstanceRepository) GetRunningByAgentName(agentName string) ([]models.StepInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.filter(func(step models.StepInstance) bool {
+		return step.Status == models.StepInstanceStatusRunning && step.AgentName != nil && *step.AgentName == agentName
+	}), nil
+}
+
+func (r *memoryStepInstanceRepository) Create(step *models.StepInstance) (*models.StepInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.steps {
+		if existing.WorkflowInstanceID == step.WorkflowInstanceID && existing.StepDefinitionID == step.StepDefinitionID {
+			return nil, fmt.Errorf("duplicate step instance %s", step.StepDefinitionID)
+		}
+	}
+	step.ID = uuid.New()
+	step.CreatedAt = time.Now()
+	step.UpdatedAt = step.CreatedAt
+	r.steps = append(r.steps, *step)
+	return step, nil
+}
+
+func (r *memoryStepInstanceRepository) Update(step *models.StepInstance) (*models.StepInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.steps {
+		if r.steps[i].ID == step.ID {
+			step.UpdatedAt = time.Now()
+			r.steps[i] = *step
+			return step, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type memoryTimerRepository struct {
+	persistance.TimerRepository
+	mu     sync.Mutex
+	timers []models.Timer
+}
+
+func (r *memoryTimerRepository) GetByID(id string) (*models.Timer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, timer := range r.timers {
+		if timer.ID.String() == id {
+			return &timer, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryTimerRepository) GetByStepInstanceID(stepInstanceID string, timerType models.TimerType) (*models.Timer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.timers) - 1; i >= 0; i-- {
+		timer := r.timers[i]
+		if timer.StepInstanceID != nil && timer.StepInstanceID.String() == stepInstanceID && timer.Type == timerType {
+			return &timer, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryTimerRepository) Create(timer *models.Timer) (*models.Timer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	timer.ID = uuid.New()
+	timer.CreatedAt = time.Now()
+	timer.UpdatedAt = timer.CreatedAt
+	r.timers = append(r.timers, *timer)
+	return timer, nil
+}
+
+func (r *memoryTimerRepository) ClaimDue(now time.Time, claimTimeout time.Duration, limit int) ([]models.Timer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	due := make([]int, 0)
+	for i, timer := range r.timers {
+		pendingDue := timer.Status == models.TimerStatusPending && !timer.FireAt.After(now)
+		claimExpired := timer.Status == models.TimerStatusClaimed && !timer.ClaimedAt.After(now.Add(-claimTimeout))
+		if pendingDue || claimExpired {
+			due = append(due, i)
+		}
+	}
+	sort.Slice(due, func(a, b int) bool { return r.timers[due[a]].FireAt.Before(r.timers[due[b]].FireAt) })
+
+	claimed := make([]models.Timer, 0)
+	for _, i := range due {
+		if len(claimed) == limit {
+			break
+		}
+		r.timers[i].Status = models.TimerStatusClaimed
+		r.timers[i].ClaimedAt = &now
+		claimed = append(claimed, r.timers[i])
+	}
+	return claimed, nil
+}
+
+func (r *memoryTimerRepository) Claim(id string) (*models.Timer, error) {
+	now := time.Now()
+	return r.transition(id, func(timer *models.Timer) {
+		timer.Status = models.TimerStatusClaimed
+		timer.ClaimedAt = &now
+	})
+}
+
+func (r *memoryTimerRepository) Cancel(id string) (*models.Timer, error) {
+	return r.transition(id, func(timer *models.Timer) {
+		timer.Status = models.TimerStatusCancelled
+	})
+}
+
+// transition updates a timer that is still pending, and returns nil if it
+// is not.
+func (r *memoryTimerRepository) transition(id string, update func(timer *models.Timer)) (*models.Timer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.timers {
+		if r.timers[i].ID.String() == id && r.timers[i].Status == models.TimerStatusPending {
+			update(&r.timers[i])
+			timer := r.timers[i]
+			return &timer, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryTimerRepository) MarkFired(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.timers {
+		if r.timers[i].ID.String() == id && r.timers[i].Status == models.TimerStatusClaimed {
+			now := time.Now()
+			r.timers[i].Status = models.TimerStatusFired
+			r.timers[i].FiredAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *memoryTimerRepository) CancelByStepInstanceID(stepInstanceID string) error {
+	r.cancelPending(func(timer models.Timer) bool {
+		return timer.StepInstanceID != nil && timer.StepInstanceID.String() == stepInstanceID
+	})
+	return nil
+}
+
+func (r *memoryTimerRepository) CancelByWorkflowInstanceID(workflowInstanceID string) error {
+	r.cancelPending(func(timer models.Timer) bool {
+		return timer.WorkflowInstanceID.String() == workflowInstanceID
+	})
+	return nil
+}
+
+func (r *memoryTimerRepository) cancelPending(match func(timer models.Timer) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.timers {
+		if r.timers[i].Status == models.TimerStatusPending && match(r.timers[i]) {
+			r.timers[i].Status = models.TimerStatusCancelled
+		}
+	}
+}
+
+type memoryStepAttemptRepository struct {
+	mu       sync.Mutex
+	attempts []models.StepAttempt
+}
+
+func (r *memoryStepAttemptRepository) GetByStepInstanceID(stepInstanceID string) ([]models.StepAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempts := make([]models.StepAttempt, 0)
+	for _, attempt := range r.attempts {
+		if attempt.StepInstanceID.String() == stepInstanceID {
+			attempts = append(attempts, attempt)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool { return attempts[i].Attempt < attempts[j].Attempt })
+	return attempts, nil
+}
+
+func (r *memoryStepAttemptRepository) GetByAttempt(stepInstanceID string, number int) (*models.StepAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, attempt := range r.attempts {
+		if attempt.StepInstanceID.String() == stepInstanceID && attempt.Attempt == number {
+			return &attempt, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryStepAttemptRepository) GetByAgentTaskID(agentTaskID string) (*models.StepAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, attempt := range r.attempts {
+		if attempt.AgentTaskID != nil && *attempt.AgentTaskID == agentTaskID {
+			return &attempt, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryStepAttemptRepository) Create(attempt *models.StepAttempt) (*models.StepAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempt.ID = uuid.New()
+	attempt.CreatedAt = time.Now()
+	attempt.UpdatedAt = attempt.CreatedAt
+	r.attempts = append(r.attempts, *attempt)
+	return attempt, nil
+}
+
+func (r *memoryStepAttemptRepository) Update(attempt *models.StepAttempt) (*models.StepAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.attempts {
+		if r.attempts[i].ID == attempt.ID {
+			attempt.UpdatedAt = time.Now()
+			r.attempts[i] = *attempt
+			return attempt, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type memoryAgentRepository struct {
+	mu     sync.Mutex
+	agents map[string]models.Agent
+}
+
+func (r *memoryAgentRepository) GetAll() ([]models.Agent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	agents := make([]models.Agent, 0, len(r.agents))
+	for _, agent := range r.agents {
+		agents = append(agents, agent)
+	}
+	return agents, nil
+}
+
+func (r *memoryAgentRepository) GetByName(name string) (*models.Agent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	agent, found := r.agents[name]
+	if !found {
+		return nil, nil
+	}
+	return &agent, nil
+}
+
+func (r *memoryAgentRepository) Save(agent *models.Agent) (*models.Agent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.agents[agent.Name] = *agent
+	return agent, nil
+}
+
+func (r *memoryAgentRepository) Delete(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.agents, name)
+	return nil
+}
+
+func (r *memoryAgentRepository) GetRegistrations(string) ([]models.AgentRegistration, error) {
+	return nil, nil
+}
+
+func (r *memoryAgentRepository) CreateRegistration(registration *models.AgentRegistration) (*models.AgentRegistration, error) {
+	return registration, nil
+}
+
+// fakeAgent is an agent served over gRPC that accepts every task and records
+// the requests it receives.
+type fakeAgent struct {
+	proto.UnimplementedAgentServiceServer
+	mu      sync.Mutex
+	started []*proto.StartTaskRequest
+	actions []string
+	// onAction is called before an action on a task is answered, as agents
+	// report the status of a task while handling an action on it.
+	onAction func(verb string, taskID string)
+}
+
+func (a *fakeAgent) StartTask(_ context.Context, req *proto.StartTaskRequest) (*proto.TaskActionResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.started = append(a.started, req)
+	return &proto.TaskActionResponse{TaskId: req.GetTaskId(), Success: true}, nil
+}
+
+func (a *fakeAgent) GetTaskStatus(_ context.Context, req *proto.TaskActionRequest) (*proto.GetTaskStatusResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, started := range a.started {
+		if started.GetTaskId() == req.TaskId {
+			return &proto.GetTaskStatusResponse{TaskId: req.TaskId, Status: proto.TaskStatus_PENDING}, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "task %s not found", req.TaskId)
+}
+
+func (a *fakeAgent) StopTask(_ context.Context, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	return a.record("stop", req)
+}
+
+func (a *fakeAgent) PauseTask(_ context.Context, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	return a.record("pause", req)
+}
+
+func (a *fakeAgent) ResumeTask(_ context.Context, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	return a.record("resume", req)
+}
+
+func (a *fakeAgent) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+func (a *fakeAgent) record(verb string, req *proto.TaskActionRequest) (*proto.TaskActionResponse, error) {
+	a.mu.Lock()
+	a.actions = append(a.actions, verb+" "+req.TaskId)
+	onAction := a.onAction
+	a.mu.Unlock()
+
+	if onAction != nil {
+		onAction(verb, req.TaskId)
+	}
+	return &proto.TaskActionResponse{TaskId: req.TaskId, Success: true}, nil
+}
+
+func (a *fakeAgent) startedTasks() []*proto.StartTaskRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*proto.StartTaskRequest(nil), a.started...)
+}
+
+func (a *fakeAgent) taskActions() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.actions...)
+}
+
+const testAgentName = "fake-agent"
+
+// testEnv is a scheduler backed by in-memory repositories and a fake agent.
+// Its steps are processed by run, on the test goroutine, rather than by
+// workers, so tests observe the state once every queued step was processed.
+type testEnv struct {
+	t             *testing.T
+	scheduler     *Scheduler
+	definitions   *memoryWorkflowDefinitionRepository
+	instances     *memoryWorkflowInstanceRepository
+	steps         *memoryStepInstanceRepository
+	timers        *memoryTimerRepository
+	attempts      *memoryStepAttemptRepository
+	agentRepo     *memoryAgentRepository
+	agentRegistry *registry.AgentRegistry
+	agent         *fakeAgent
+	// tasks numbers the IDs of the agent tasks in the order they are created.
+	tasks int
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	e := &testEnv{
+		t:           t,
+		definitions: &memoryWorkflowDefinitionRepository{definitions: make(map[uuid.UUID]models.WorkflowDefinition)},
+		instances:   &memoryWorkflowInstanceRepository{},
+		steps:       &memoryStepInstanceRepository{},
+		timers:      &memoryTimerRepository{},
+		attempts:    &memoryStepAttemptRepository{},
+		agentRepo:   &memoryAgentRepository{agents: make(map[string]models.Agent)},
+		agent:       &fakeAgent{},
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterAgentServiceServer(server, e.agent)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	e.agentRegistry = registry.NewAgentRegistry(registry.NewRoundRobinBalancer(), e.agentRepo)
+	address := "127.0.0.1"
+	err = e.agentRegistry.RegisterAgent(testAgentName, registry.RegisteredAgent{
+		Name:           testAgentName,
+		Version:        "v1.0.0",
+		Address:        &address,
+		Port:           fmt.Sprint(listener.Addr().(*net.TCPAddr).Port),
+		Protocol:       proto.AGENT_PROTOCOL_GRPC,
+		SupportedTasks: []*proto.TaskDefinition{{Id: "echo", Name: "Echo"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.restart()
+	return e
+}
+
+// restart replaces the scheduler with a new one over the same repositories
+// and agent registry, as if the engine restarted.
+func (e *testEnv) restart() {
+	e.scheduler = NewScheduler(&Config{
+		Workers:           1,
+		QueueSize:         1000,
+		TimerPollInterval: time.Hour,
+		TimerBatchSize:    100,
+		TimerClaimTimeout: time.Minute,
+	}, e.definitions, e.instances, e.steps, e.timers, e.attempts)
+	taskHandler := NewTaskStepHandler(e.agentRegistry, e.steps)
+	taskHandler.newTaskID = func() string {
+		e.tasks++
+		return fmt.Sprintf("task-%d", e.tasks)
+	}
+	e.scheduler.Registry.RegisterHandler(models.StepTypeTask, taskHandler)
+	e.scheduler.Registry.RegisterHandler(models.StepTypeFork, NewForkStepHandler())
+	e.scheduler.Registry.RegisterHandler(models.StepTypeJoin, NewJoinStepHandler(e.scheduler))
+	e.scheduler.Registry.RegisterHandler(models.StepTypeDecision, NewDecisionStepHandler())
+	e.scheduler.Registry.RegisterHandler(models.StepTypeWait, NewWaitStepHandler(e.scheduler))
+	e.scheduler.Registry.RegisterHandler(models.StepTypeWorkflow, NewWorkflowStepHandler(e.scheduler))
+}
+
+// run processes the queued steps until the queue is empty.
+func (e *testEnv) run() {
+	e.t.Helper()
+	for {
+		select {
+		case stepInstanceID := <-e.scheduler.queue:
+			err := e.scheduler.withExecution(stepInstanceID, func(execution *StepExecution) error {
+				return e.scheduler.process(context.Background(), execution)
+			})
+			if err != nil {
+				e.t.Fatalf("expected step instance %s to be processed, got %v", stepInstanceID, err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// define stores an enabled workflow definition with the given steps, the
+// first one being where the workflow starts.
+func (e *testEnv) define(steps ...models.WorkflowStepDefinition) *models.WorkflowDefinition {
+	e.t.Helper()
+	list := models.WorkflowStepDefinitionList(steps)
+	definition, err := e.definitions.Create(&models.WorkflowDefinition{
+		FamilyID:  uuid.New(),
+		Name:      "test",
+		Version:   "1.0.0",
+		IsEnabled: true,
+		Steps:     &list,
+	})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return definition
+}
+
+// start starts a workflow instance and processes its steps.
+func (e *testEnv) start(definition *models.WorkflowDefinition, input map[string]interface{}) *models.WorkflowInstance {
+	e.t.Helper()
+	instance, err := e.scheduler.StartWorkflow(definition, input)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	e.run()
+	return instance
+}
+
+func (e *testEnv) instance(id uuid.UUID) *models.WorkflowInstance {
+	e.t.Helper()
+	instance, err := e.instances.GetByID(id.String())
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return instance
+}
+
+// step returns the instance of a step definition, failing the test if the
+// step was never scheduled.
+func (e *testEnv) step(instance *models.WorkflowInstance, stepDefinitionID string) *models.StepInstance {
+	e.t.Helper()
+	step, _ := e.steps.GetByStepDefinitionID(instance.ID.String(), stepDefinitionID)
+	if step == nil {
+		e.t.Fatalf("expected step %s to be scheduled", stepDefinitionID)
+	}
+	return step
+}
+
+func (e *testEnv) timer(step *models.StepInstance, timerType models.TimerType) *models.Timer {
+	e.t.Helper()
+	timer, _ := e.timers.GetByStepInstanceID(step.ID.String(), timerType)
+	if timer == nil {
+		e.t.Fatalf("expected step %s to have a %s timer", step.StepDefinitionID, timerType)
+	}
+	return timer
+}
+
+// finishTask reports the task of a running step as finished by the agent and
+// processes the steps that follow.
+func (e *testEnv) finishTask(instance *models.WorkflowInstance, stepDefinitionID string, status proto.TaskStatus, output map[string]interface{}, errorCode string) {
+	e.t.Helper()
+	step := e.step(instance, stepDefinitionID)
+	if err := e.scheduler.HandleTaskStatus(step.ID, status, output, "task "+status.String(), errorCode); err != nil {
+		e.t.Fatal(err)
+	}
+	e.run()
+}
+
+func (e *testEnv) fireTimer(timer *models.Timer) {
+	e.t.Helper()
+	if _, err := e.scheduler.FireTimer(timer.ID); err != nil {
+		e.t.Fatal(err)
+	}
+	e.run()
+}
+
+func (e *testEnv) expectInstance(instance *models.WorkflowInstance, want models.WorkflowInstanceStatus) {
+	e.t.Helper()
+	if got := e.instance(instance.ID); got.Status != want {
+		e.t.Fatalf("expected workflow instance to be %s, got %s (error: %v)", want, got.Status, stringOf(got.Error))
+	}
+}
+
+func (e *testEnv) expectSteps(instance *models.WorkflowInstance, want map[string]models.StepInstanceStatus) {
+	e.t.Helper()
+	for stepDefinitionID, status := range want {
+		if step := e.step(instance, stepDefinitionID); step.Status != status {
+			e.t.Fatalf("expected step %s to be %s, got %s (error: %v)", stepDefinitionID, status, step.Status, stringOf(step.Error))
+		}
+	}
+}
+
+func (e *testEnv) expectActions(want ...string) {
+	e.t.Helper()
+	if got := e.agent.taskActions(); fmt.Sprint(got) != fmt.Sprint(want) {
+		e.t.Fatalf("expected the agent to receive %v, got %v", want, got)
+	}
+}
+
+// reportActions has the fake agent report the status of a task to the
+// scheduler while handling an action on it, as agents do. The report must not
+// wait for the scheduler to be done with the step that called the agent.
+func (e *testEnv) reportActions() {
+	statuses := map[string]proto.TaskStatus{
+		"stop":   proto.TaskStatus_STOPPED,
+		"pause":  proto.TaskStatus_PAUSED,
+		"resume": proto.TaskStatus_RUNNING,
+	}
+
+	e.agent.mu.Lock()
+	defer e.agent.mu.Unlock()
+	e.agent.onAction = func(verb string, taskID string) {
+		step, err := e.steps.GetByAgentTaskID(taskID)
+		if err != nil || step == nil {
+			e.t.Errorf("expected task %s to belong to a step, got %v", taskID, err)
+			return
+		}
+
+		reported := make(chan error, 1)
+		go func() { reported <- e.scheduler.HandleTaskStatus(step.ID, statuses[verb], nil, "", "") }()
+		select {
+		case err := <-reported:
+			if err != nil {
+				e.t.Errorf("expected the %s report of task %s to be applied, got %v", verb, taskID, err)
+			}
+		case <-time.After(time.Second):
+			e.t.Errorf("expected the %s report of task %s not to wait for the scheduler", verb, taskID)
+		}
+	}
+}
+
+func stringOf(value *string) string {
+	if value == nil {
+		return "<nil>"
+	}
+	return *value
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func taskStep(id string, next ...string) models.WorkflowStepDefinition {
+	step := models.WorkflowStepDefinition{
+		StepDefinitionID: id,
+		Name:             id,
+		Type:             models.StepTypeTask,
+		TaskConfig:       &models.TaskConfig{TaskDefinitionID: "echo"},
+	}
+	if len(next) > 0 {
+		step.TaskConfig.NextStepID = &next[0]
+	}
+	return step
+}
+
+func TestStepTransitions(t *testing.T) {
+	retried := func(step models.WorkflowStepDefinition) models.WorkflowStepDefinition {
+		step.RetryCount = ptr(1)
+		return step
+	}
+	timed := func(step models.WorkflowStepDefinition) models.WorkflowStepDefinition {
+		step.TimeoutSeconds = ptr(30)
+		return step
+	}
+
+	tests := []struct {
+		name     string
+		step     models.WorkflowStepDefinition
+		run      bool
+		drive    func(e *testEnv, instance *models.WorkflowInstance)
+		want     models.StepInstanceStatus
+		attempt  int
+		instance models.WorkflowInstanceStatus
+	}{
+		{
+			name:     "pending to running",
+			step:     taskStep("a"),
+			run:      true,
+			drive:    func(*testEnv, *models.WorkflowInstance) {},
+			want:     models.StepInstanceStatusRunning,
+			attempt:  1,
+			instance: models.WorkflowInstanceStatusRunning,
+		},
+		{
+			name: "pending to cancelled",
+			step: taskStep("a"),
+			drive: func(e *testEnv, instance *models.WorkflowInstance) {
+				if err := e.scheduler.CancelWorkflow(instance.ID, "cancelled by test"); err != nil {
+					e.t.Fatal(err)
+				}
+				e.run()
+			},
+			want:     models.StepInstanceStatusCancelled,
+			attempt:  1,
+			instance: models.WorkflowInstanceStatusCancelled,
+		},
+		{
+			name: "running to completed",
+			step: taskStep("a"),
+			run:  true,
+			drive: func(e *testEnv, instance *models.WorkflowInstance) {
+				e.finishTask(instance, "a", proto.TaskStatus_COMPLETED, map[string]interface{}{"ok": true}, "")
+			},
+			want:     models.StepInstanceStatusCompleted,
+			attempt:  1,
+			instance: models.WorkflowInstanceStatusCompleted,
+		},
+		{
+			name: "running to failed",
+			step: taskStep("a"),
+			run:  true,
+			drive: func(e *testEnv, instance *models.WorkflowInstance) {
+				e.finishTask(instance, "a", proto.TaskStatus_FAILED, nil, "")
+			},
+			want:     models.StepInstanceStatusFailed,
+			attempt:  1,
+			instance: models.WorkflowInstanceStatusFailed,
+		},
+		{
+			name: "running to cancelled",
+			step: taskStep("a"),
+			run:  true,
+			drive: func(e *testEnv, instance *models.WorkflowInstance) {
+				if err := e.scheduler.CancelWorkflow(instance.ID, "cancelled by test"); err != nil {
+					e.t.Fatal(err)
+				}
+				e.expectActions("stop task-1")
+			},
+			want:     models.StepInstanceStatusCancelled,
+			attempt:  1,
+			instance: models.WorkflowInstanceStatusCancelled,
+		},
+		{
+			name: "running to retrying",
+			step: retried(taskStep("a")),
+			run:  true,
+			drive: func(e *testEnv, instance *models.WorkflowInstance) {
+				e.finishTask(instance, "a", proto.TaskStatus_FAILED, nil, "")
+			},
+			want:     models.StepInstanceStatusRetrying,
+			attempt:  1,
+			instance: models.WorkflowInstanceStatusRunning,
+		},
+		{
+			name: "running to timed out",
+			step: timed(taskStep("a")),
+			run:  true,
+			drive: func(e *testEnv, instance *models.WorkflowInstance) {
+				e.fireTimer(e.timer(e.step(instance, "a"), models.TimerTypeStepTimeout))
+				e.expectActions("stop task-1")
+			},
+			want:     models.StepInstanceStatusTimedOut,
+			attempt:  1,
+			instance: models.WorkflowInstanceStatusFailed,
+		},
+		{
+			name: "retrying to pending, then running",
+			step: retried(taskStep("a")),
+			run:  true,
+			drive: func(e *testEnv, instance *models.WorkflowInstance) {
+				e.finishTask(instance, "a", proto.TaskStatus_FAILED, nil, "")
+				e.fireTimer(e.timer(e.step(instance, "a"), models.TimerTypeRetry))
+			},
+			want:     models.StepInstanceStatusRunning,
+			attempt:  2,
+			instance: models.WorkflowInstanceStatusRunning,
+		},
+		{
+			name: "retrying to failed",
+			step: retried(taskStep("a")),
+			run:  true,
+			drive: func(e *testEnv, instance *models.WorkflowInstance) {
+				e.finishTask(instance, "a", proto.TaskStatus_FAILED, nil, "")
+				if _, err := e.scheduler.CancelTimer(e.timer(e.step(instance, "a"), models.TimerTypeRetry).ID); err != nil {
+					e.t.Fatal(err)
+				}
+			},
+			want:     models.StepInstanceStatusFailed,
+			attempt:  1,
+			instance: models.WorkflowInstanceStatusFailed,
+		},
+		{
+			name: "retrying to cancelled",
+			step: retried(taskStep("a")),
+			run:  true,
+			drive: func(e *testEnv, instance *models.WorkflowInstance) {
+				e.finishTask(instance, "a", proto.TaskStatus_FAILED, nil, "")
+				if err := e.scheduler.CancelWorkflow(instance.ID, "cancelled by test"); err != nil {
+					e.t.Fatal(err)
+				}
+				if timer := e.timer(e.step(instance, "a"), models.TimerTypeRetry); timer.Status != models.TimerStatusCancelled {
+					e.t.Fatalf("expected the retry timer to be cancelled, got %s", timer.Status)
+				}
+			},
+			want:     models.StepInstanceStatusCancelled,
+			attempt:  1,
+			instance: models.WorkflowInstanceStatusCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			definition := e.define(tt.step)
+			instance, err := e.scheduler.StartWorkflow(definition, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.run {
+				e.run()
+			}
+
+			tt.drive(e, instance)
+
+			step := e.step(instance, "a")
+			if step.Status != tt.want || step.Attempt != tt.attempt {
+				t.Fatalf("expected step to be %s on attempt %d, got %s on attempt %d", tt.want, tt.attempt, step.Status, step.Attempt)
+			}
+			e.expectInstance(instance, tt.instance)
+		})
+	}
+}
+
+func TestSequentialSteps(t *testing.T) {
+	e := newTestEnv(t)
+	second := taskStep("second")
+	second.Parameters = &models.StepDefinitionParameters{
+		"orderId": {Type: models.StepParameterTypeWorkflow, Value: "orderId"},
+		"sku":     {Type: models.StepParameterTypeTaskOutput, Value: "first.items[0].sku"},
+		"mode":    {Type: models.StepParameterTypeConstant, Value: "fast"},
+	}
+	definition := e.define(taskStep("first", "second"), second)
+
+	instance := e.start(definition, map[string]interface{}{"orderId": "o-1"})
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{"first": models.StepInstanceStatusRunning})
+	if started := e.agent.startedTasks(); len(started) != 1 || started[0].TaskName != "echo" || started[0].GetWorkflowInstanceId() != instance.ID.String() || started[0].GetAttempt() != 1 {
+		t.Fatalf("expected the first task to be started on the agent, got %v", started)
+	}
+
+	e.finishTask(instance, "first", proto.TaskStatus_COMPLETED, map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{"sku": "sku-1"}},
+	}, "")
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{
+		"first":  models.StepInstanceStatusCompleted,
+		"second": models.StepInstanceStatusRunning,
+	})
+	started := e.agent.startedTasks()
+	if len(started) != 2 {
+		t.Fatalf("expected the second task to be started, got %v", started)
+	}
+	input := started[1].InputParameters.AsMap()
+	if input["orderId"] != "o-1" || input["sku"] != "sku-1" || input["mode"] != "fast" {
+		t.Fatalf("expected the parameters of the second task to be resolved, got %v", input)
+	}
+	if got := e.instance(instance.ID); got.CurrentStepIDs == nil || fmt.Sprint(*got.CurrentStepIDs) != "[second]" {
+		t.Fatalf("expected second to be the current step, got %v", got.CurrentStepIDs)
+	}
+
+	e.finishTask(instance, "second", proto.TaskStatus_COMPLETED, map[string]interface{}{"shipped": true}, "")
+	e.expectInstance(instance, models.WorkflowInstanceStatusCompleted)
+	if got := e.instance(instance.ID); got.Output == nil || (*got.Output)["shipped"] != true {
+		t.Fatalf("expected the instance to complete with the output of its last step, got %v", got.Output)
+	}
+}
+
+func TestDecision(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		taken   string
+		skipped string
+	}{
+		{name: "case matches", amount: 500, taken: "review", skipped: "approve"},
+		{name: "default branch", amount: 50, taken: "approve", skipped: "review"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			definition := e.define(
+				models.WorkflowStepDefinition{
+					StepDefinitionID: "route",
+					Name:             "route",
+					Type:             models.StepTypeDecision,
+					DecisionConfig: &models.DecisionConfig{
+						JoinStepID:        "done",
+						Cases:             []models.DecisionCase{{Condition: "input.amount > 100", NextStepID: "review"}},
+						DefaultNextStepID: ptr("approve"),
+					},
+				},
+				taskStep("review"),
+				taskStep("approve"),
+				models.WorkflowStepDefinition{
+					StepDefinitionID: "done",
+					Name:             "done",
+					Type:             models.StepTypeJoin,
+					JoinConfig:       &models.JoinConfig{IncomingStepIDs: []string{"review", "approve"}},
+				},
+			)
+
+			instance := e.start(definition, map[string]interface{}{"amount": tt.amount})
+			e.expectSteps(instance, map[string]models.StepInstanceStatus{
+				"route":  models.StepInstanceStatusCompleted,
+				tt.taken: models.StepInstanceStatusRunning,
+			})
+			if step, _ := e.steps.GetByStepDefinitionID(instance.ID.String(), tt.skipped); step != nil {
+				t.Fatalf("expected %s not to be scheduled, got %s", tt.skipped, step.Status)
+			}
+
+			e.finishTask(instance, tt.taken, proto.TaskStatus_COMPLETED, nil, "")
+			e.expectSteps(instance, map[string]models.StepInstanceStatus{"done": models.StepInstanceStatusCompleted})
+			e.expectInstance(instance, models.WorkflowInstanceStatusCompleted)
+		})
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	e := newTestEnv(t)
+	definition := e.define(taskStep("first", "second"), taskStep("second"))
+	instance := e.start(definition, nil)
+
+	if err := e.scheduler.ResumeWorkflow(instance.ID); !errors.Is(err, engineErrors.ErrWorkflowInstanceNotPaused) {
+		t.Fatalf("expected resuming a running instance to be refused, got %v", err)
+	}
+	if err := e.scheduler.PauseWorkflow(instance.ID); err != nil {
+		t.Fatal(err)
+	}
+	e.expectInstance(instance, models.WorkflowInstanceStatusPaused)
+	e.expectActions("pause task-1")
+	if err := e.scheduler.PauseWorkflow(instance.ID); !errors.Is(err, engineErrors.ErrWorkflowInstanceNotRunning) {
+		t.Fatalf("expected pausing a paused instance to be refused, got %v", err)
+	}
+
+	// A task finishing while its instance is paused completes, but the next
+	// step waits for the instance to be resumed.
+	e.finishTask(instance, "first", proto.TaskStatus_COMPLETED, nil, "")
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{
+		"first":  models.StepInstanceStatusCompleted,
+		"second": models.StepInstanceStatusPending,
+	})
+	if started := e.agent.startedTasks(); len(started) != 1 {
+		t.Fatalf("expected no task to start while the instance is paused, got %d", len(started))
+	}
+
+	if err := e.scheduler.ResumeWorkflow(instance.ID); err != nil {
+		t.Fatal(err)
+	}
+	e.run()
+	e.expectInstance(instance, models.WorkflowInstanceStatusRunning)
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{"second": models.StepInstanceStatusRunning})
+
+	if err := e.scheduler.PauseWorkflow(instance.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.scheduler.ResumeWorkflow(instance.ID); err != nil {
+		t.Fatal(err)
+	}
+	e.run()
+	e.expectActions("pause task-1", "pause task-2", "resume task-2")
+	if started := e.agent.startedTasks(); len(started) != 2 {
+		t.Fatalf("expected resuming not to start the running task again, got %d tasks", len(started))
+	}
+}
+
+func TestCancelWorkflow(t *testing.T) {
+	e := newTestEnv(t)
+	definition := e.define(taskStep("a"))
+	definition.TimeoutSeconds = ptr(3600)
+	if _, err := e.definitions.Create(definition); err != nil {
+		t.Fatal(err)
+	}
+	instance := e.start(definition, nil)
+
+	if err := e.scheduler.CancelWorkflow(instance.ID, "no longer needed"); err != nil {
+		t.Fatal(err)
+	}
+	got := e.instance(instance.ID)
+	if got.Status != models.WorkflowInstanceStatusCancelled || stringOf(got.Error) != "no longer needed" || got.CompletedAt == nil {
+		t.Fatalf("expected the instance to be cancelled with its reason, got %+v", got)
+	}
+	e.expectActions("stop task-1")
+	for _, timer := range e.timers.timers {
+		if timer.Status != models.TimerStatusCancelled {
+			t.Fatalf("expected the %s timer to be cancelled, got %s", timer.Type, timer.Status)
+		}
+	}
+
+	attempts, _ := e.attempts.GetByStepInstanceID(e.step(instance, "a").ID.String())
+	if len(attempts) != 1 || attempts[0].Status != models.StepAttemptStatusCancelled {
+		t.Fatalf("expected the attempt to be cancelled, got %+v", attempts)
+	}
+
+	if err := e.scheduler.CancelWorkflow(instance.ID, "again"); !errors.Is(err, engineErrors.ErrWorkflowInstanceNotActive) {
+		t.Fatalf("expected cancelling a cancelled instance to be refused, got %v", err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	e := newTestEnv(t)
+	definition := e.define(taskStep("first", "second"), taskStep("second"))
+	running := e.start(definition, nil)
+	pending, err := e.scheduler.StartWorkflow(definition, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The engine stops before the second instance is processed.
+	e.restart()
+	if err := e.scheduler.recover(); err != nil {
+		t.Fatal(err)
+	}
+	e.run()
+
+	e.expectSteps(running, map[string]models.StepInstanceStatus{"first": models.StepInstanceStatusRunning})
+	e.expectSteps(pending, map[string]models.StepInstanceStatus{"first": models.StepInstanceStatusRunning})
+	e.expectInstance(pending, models.WorkflowInstanceStatusRunning)
+	if started := e.agent.startedTasks(); len(started) != 2 {
+		t.Fatalf("expected only the task of the pending instance to start, got %d tasks", len(started))
+	}
+
+	e.finishTask(running, "first", proto.TaskStatus_COMPLETED, nil, "")
+	e.expectSteps(running, map[string]models.StepInstanceStatus{"second": models.StepInstanceStatusRunning})
+}
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+// StepExecution holds everything a step handler needs to run a step instance.
+type StepExecution struct {
+	Definition     *models.WorkflowDefinition
+	StepDefinition *models.WorkflowStepDefinition
+	Instance       *models.WorkflowInstance
+	Step           *models.StepInstance
+	Steps          []*models.StepInstance
+
+	// unlocked holds the calls deferred until the lock of the workflow
+	// instance is released.
+	unlocked []func()
+}
+
+// StepResult is returned by a step handler once it has run.
+type StepResult struct {
+	// Completed is false when the step keeps running after the handler
+	// returns and will be completed later through Scheduler.CompleteStep.
+	Completed bool
+	Output    map[string]interface{}
+	// NextStepIDs overrides the next step declared in the step definition.
+	NextStepIDs []string
+}
+
+// StepHandler runs a step instance of a given step type. Handlers are called
+// again for running steps after an engine restart, so they must be idempotent.
+type StepHandler interface {
+	Handle(ctx context.Context, execution *StepExecution) (*StepResult, error)
+}
+
+// StepCanceller is implemented by handlers that must release external
+// resources, such as agent tasks, when one of their steps is cancelled or times
+// out. Cancel is called once the lock of the workflow instance is released.
+type StepCanceller interface {
+	Cancel(execution *StepExecution, step *models.StepInstance) error
+}
+
+// StepPauser is implemented by handlers whose running steps hold work outside
+// the engine, such as agent tasks, that must be paused and resumed along with
+// their workflow instance. Pause and Resume are called once the lock of the
+// workflow instance is released.
+type StepPauser interface {
+	Pause(execution *StepExecution, step *models.StepInstance) error
+	Resume(execution *StepExecution, step *models.StepInstance) error
+}
+
+type HandlerRegistry struct {
+	mu       sync.RWMutex
+	handlers map[models.StepType]StepHandler
+}
+
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlers: make(map[models.StepType]StepHandler),
+	}
+}
+
+func (r *HandlerRegistry) RegisterHandler(stepType models.StepType, handler StepHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[stepType] = handler
+}
+
+func (r *HandlerRegistry) GetHandler(stepType models.StepType) (StepHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handler, exists := r.handlers[stepType]
+	return handler, exists
+}
+
+// GetStepInstance returns the instance of the given step definition within
+// the workflow instance being executed.
+func (e *StepExecution) GetStepInstance(stepDefinitionID string) (*models.StepInstance, bool) {
+	for _, step := range e.Steps {
+		if step.StepDefinitionID == stepDefinitionID {
+			return step, true
+		}
+	}
+	return nil, false
+}
+
+// afterUnlock defers a call until the lock of the workflow instance is
+// released. Calls reaching agents or other workflow instances go through it,
+// since agents report back to the engine while handling them.
+func (e *StepExecution) afterUnlock(fn func()) {
+	e.unlocked = append(e.unlocked, fn)
+}
+
+func (e *StepExecution) getStepInstanceByID(id uuid.UUID) (*models.StepInstance, bool) {
+	for _, step := range e.Steps {
+		if step.ID == id {
+			return step, true
+		}
+	}
+	return nil, false
+}
+
+func completed(output map[string]interface{}, nextStepIDs ...string) *StepResult {
+	return &StepResult{
+		Completed:   true,
+		Output:      output,
+		NextStepIDs: nextStepIDs,
+	}
+}
+
+func inProgress() *StepResult {
+	return &StepResult{
+		Completed: false,
+	}
+}
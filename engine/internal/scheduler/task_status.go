@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+// HandleTaskStatus applies a status reported by an agent to the step instance
+// running the task. Updates received once the step is no longer running are
+// ignored, so duplicated or out-of-order notifications are harmless. The error
+// code of a failed task is matched against the retry policy of the step.
+func (s *Scheduler) HandleTaskStatus(stepInstanceID uuid.UUID, status proto.TaskStatus, output map[string]interface{}, message string, errorCode string) error {
+	return s.withExecution(stepInstanceID, func(execution *StepExecution) error {
+		step := execution.Step
+		if step.Status != models.StepInstanceStatusRunning || execution.Instance.Status.IsTerminal() {
+			return nil
+		}
+
+		taskStatus := status.String()
+		step.AgentTaskStatus = &taskStatus
+
+		switch status {
+		case proto.TaskStatus_COMPLETED:
+			return s.completeStep(execution, completed(output))
+		case proto.TaskStatus_FAILED, proto.TaskStatus_STOPPED:
+			if message == "" {
+				message = "task " + taskStatus
+			}
+			return s.failStep(execution, engineErrors.WithCode(errorCode, errors.New(message)))
+		default:
+			_, err := s.stepInstanceRepo.Update(step)
+			return err
+		}
+	})
+}
+
+// HandleTaskProgress records the progress reported for a task, with its
+// optional message and partial output, and returns the updated step instance.
+// Progress never goes backwards, so a delayed update cannot hide a newer one:
+// such updates are ignored and nil is returned.
+func (s *Scheduler) HandleTaskProgress(stepInstanceID uuid.UUID, progress float32, message *string, partialOutput map[string]interface{}) (*models.StepInstance, error) {
+	var updated *models.StepInstance
+	err := s.withExecution(stepInstanceID, func(execution *StepExecution) error {
+		step := execution.Step
+		if step.Status.IsTerminal() {
+			return nil
+		}
+		if step.Progress != nil && *step.Progress > progress {
+			return nil
+		}
+
+		step.Progress = &progress
+		if message != nil {
+			step.ProgressMessage = message
+		}
+		if partialOutput != nil {
+			output := models.JsonMap(partialOutput)
+			step.PartialOutput = &output
+		}
+		if _, err := s.stepInstanceRepo.Update(step); err != nil {
+			return err
+		}
+		updated = step
+		return nil
+	})
+	return updated, err
+}
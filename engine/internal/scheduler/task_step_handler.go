@@ -0,0 +1,199 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/connector"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/persistance"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/registry"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type TaskStepHandler struct {
+	agentRegistry    *registry.AgentRegistry
+	stepInstanceRepo persistance.StepInstanceRepository
+	newTaskID        func() string
+}
+
+func NewTaskStepHandler(agentRegistry *registry.AgentRegistry, stepInstanceRepo persistance.StepInstanceRepository) *TaskStepHandler {
+	return &TaskStepHandler{
+		agentRegistry:    agentRegistry,
+		stepInstanceRepo: stepInstanceRepo,
+		newTaskID:        uuid.NewString,
+	}
+}
+
+// Handle starts the task on the agent that supports it. The step keeps running
+// until the agent reports the task status back to the engine. The task ID is
+// generated and stored with the step before the task starts, so the statuses
+// the agent reports right away find the step running the task.
+func (h *TaskStepHandler) Handle(_ context.Context, execution *StepExecution) (*StepResult, error) {
+	config := execution.StepDefinition.TaskConfig
+	if config == nil {
+		return nil, errors.New("task step has no task configuration")
+	}
+
+	// The task was already dispatched before the engine restarted. Agents
+	// restored offline never report going offline, so the step fails here as
+	// it would have when its agent went offline.
+	step := execution.Step
+	if step.AgentTaskID != nil && step.AgentName != nil {
+		agent, known := h.agentRegistry.GetAgent(*step.AgentName)
+		if !known || agent.Status == registry.AgentStatusOffline {
+			return nil, engineErrors.WithCode(engineErrors.ErrorCodeAgentOffline, fmt.Errorf("%w: %s", engineErrors.ErrAgentOffline, *step.AgentName))
+		}
+
+		// A task the agent never reported on may not have reached it, if the
+		// engine stopped while starting it. It is started again if the agent
+		// does not know it.
+		if step.AgentTaskStatus == nil && !h.knowsTask(step) {
+			if err := h.startTask(execution); err != nil {
+				return nil, err
+			}
+		}
+		h.agentRegistry.TrackTask(*step.AgentName, *step.AgentTaskID)
+		return inProgress(), nil
+	}
+
+	agent, exists := h.agentRegistry.GetAgentByTask(config.TaskDefinitionID)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", engineErrors.ErrNoAgentForTask, config.TaskDefinitionID)
+	}
+
+	input, err := resolveParameters(execution.StepDefinition.Parameters, execution)
+	if err != nil {
+		return nil, err
+	}
+
+	taskID := h.newTaskID()
+	inputMap := models.JsonMap(input)
+	step.Input = &inputMap
+	step.AgentName = &agent.Name
+	step.AgentTaskID = &taskID
+	if _, err := h.stepInstanceRepo.Update(step); err != nil {
+		return nil, err
+	}
+
+	if err := h.startTask(execution); err != nil {
+		return nil, err
+	}
+	h.agentRegistry.TrackTask(agent.Name, taskID)
+	return inProgress(), nil
+}
+
+// knowsTask tells whether the agent of a step knows the task of the step. The
+// task is assumed known unless the agent answers it was not found.
+func (h *TaskStepHandler) knowsTask(step *models.StepInstance) bool {
+	agentConnector, exists := h.agentRegistry.GetAgentConnector(*step.AgentName)
+	if !exists {
+		return true
+	}
+
+	_, err := (*agentConnector).GetTaskStatus(&proto.TaskActionRequest{TaskId: *step.AgentTaskID})
+	if status.Code(err) == codes.NotFound {
+		return false
+	}
+	if err != nil {
+		log.Printf("[scheduler] failed to get the status of task %s on agent %s: %v", *step.AgentTaskID, *step.AgentName, err)
+	}
+	return true
+}
+
+// startTask sends the task stored with a step to its agent.
+func (h *TaskStepHandler) startTask(execution *StepExecution) error {
+	config := execution.StepDefinition.TaskConfig
+	step := execution.Step
+
+	agentConnector, exists := h.agentRegistry.GetAgentConnector(*step.AgentName)
+	if !exists {
+		return fmt.Errorf("%w: %s", engineErrors.ErrNoAgentForTask, config.TaskDefinitionID)
+	}
+
+	var input map[string]interface{}
+	if step.Input != nil {
+		input = *step.Input
+	}
+	inputStruct, err := structpb.NewStruct(input)
+	if err != nil {
+		return fmt.Errorf("convert task input: %w", err)
+	}
+
+	workflowInstanceID := execution.Instance.ID.String()
+	attempt := int32(step.Attempt)
+	req := &proto.StartTaskRequest{
+		TaskName:           config.TaskDefinitionID,
+		InputParameters:    inputStruct,
+		TaskId:             step.AgentTaskID,
+		WorkflowInstanceId: &workflowInstanceID,
+		Attempt:            &attempt,
+	}
+	if execution.StepDefinition.TimeoutSeconds != nil {
+		timeout := int32(*execution.StepDefinition.TimeoutSeconds)
+		req.TimeoutSeconds = &timeout
+	}
+
+	res, err := (*agentConnector).StartTask(req)
+	if err != nil {
+		return fmt.Errorf("start task %s on agent %s: %w", config.TaskDefinitionID, *step.AgentName, err)
+	}
+	if !res.Success {
+		message := "unknown error"
+		if res.Message != nil {
+			message = *res.Message
+		}
+		return fmt.Errorf("agent %s rejected task %s: %s", *step.AgentName, config.TaskDefinitionID, message)
+	}
+	return nil
+}
+
+// Cancel stops the agent task of a cancelled step.
+func (h *TaskStepHandler) Cancel(_ *StepExecution, step *models.StepInstance) error {
+	return h.applyTaskAction(step, "stop", connector.AgentConnector.StopTask)
+}
+
+// Pause pauses the agent task of a step whose workflow instance is paused.
+func (h *TaskStepHandler) Pause(_ *StepExecution, step *models.StepInstance) error {
+	return h.applyTaskAction(step, "pause", connector.AgentConnector.PauseTask)
+}
+
+// Resume resumes the agent task of a step whose workflow instance is resumed.
+func (h *TaskStepHandler) Resume(_ *StepExecution, step *models.StepInstance) error {
+	return h.applyTaskAction(step, "resume", connector.AgentConnector.ResumeTask)
+}
+
+func (h *TaskStepHandler) applyTaskAction(
+	step *models.StepInstance,
+	verb string,
+	action func(connector.AgentConnector, *proto.TaskActionRequest) (*proto.TaskActionResponse, error),
+) error {
+	if step.AgentName == nil || step.AgentTaskID == nil {
+		return nil
+	}
+
+	agentConnector, exists := h.agentRegistry.GetAgentConnector(*step.AgentName)
+	if !exists {
+		return fmt.Errorf("agent %s is not connected", *step.AgentName)
+	}
+
+	res, err := action(*agentConnector, &proto.TaskActionRequest{TaskId: *step.AgentTaskID})
+	if err != nil {
+		return fmt.Errorf("%s task %s on agent %s: %w", verb, *step.AgentTaskID, *step.AgentName, err)
+	}
+	if !res.Success {
+		message := "unknown error"
+		if res.Message != nil {
+			message = *res.Message
+		}
+		return fmt.Errorf("agent %s refused to %s task %s: %s", *step.AgentName, verb, *step.AgentTaskID, message)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+func TestTaskStarted(t *testing.T) {
+	e := newTestEnv(t)
+	step := taskStep("a")
+	step.TimeoutSeconds = ptr(30)
+	step.Parameters = &models.StepDefinitionParameters{
+		"orderId": {Type: models.StepParameterTypeWorkflow, Value: "orderId"},
+		"mode":    {Type: models.StepParameterTypeConstant, Value: "fast"},
+	}
+	instance := e.start(e.define(step), map[string]interface{}{"orderId": "o-1"})
+
+	got := e.step(instance, "a")
+	if stringOf(got.AgentName) != testAgentName || stringOf(got.AgentTaskID) != "task-1" {
+		t.Fatalf("expected the step to record its agent task, got %v on %v", stringOf(got.AgentTaskID), stringOf(got.AgentName))
+	}
+	if got.Input == nil || (*got.Input)["orderId"] != "o-1" {
+		t.Fatalf("expected the step to record its input, got %v", got.Input)
+	}
+
+	started := e.agent.startedTasks()
+	if len(started) != 1 || started[0].GetTimeoutSeconds() != 30 {
+		t.Fatalf("expected the task to be started with its timeout, got %v", started)
+	}
+	if input := started[0].InputParameters.AsMap(); input["orderId"] != "o-1" || input["mode"] != "fast" {
+		t.Fatalf("expected the parameters of the task to be resolved, got %v", input)
+	}
+}
+
+func TestTaskStatusNotifications(t *testing.T) {
+	e := newTestEnv(t)
+	instance := e.start(e.define(taskStep("a", "b"), taskStep("b")), nil)
+	step := e.step(instance, "a")
+
+	if err := e.scheduler.HandleTaskStatus(step.ID, proto.TaskStatus_RUNNING, nil, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.step(instance, "a"); got.Status != models.StepInstanceStatusRunning || stringOf(got.AgentTaskStatus) != "RUNNING" {
+		t.Fatalf("expected the task status to be recorded, got %s", stringOf(got.AgentTaskStatus))
+	}
+
+	message := "halfway"
+	if _, err := e.scheduler.HandleTaskProgress(step.ID, 50, &message, map[string]interface{}{"rows": 3}); err != nil {
+		t.Fatal(err)
+	}
+	if updated, err := e.scheduler.HandleTaskProgress(step.ID, 20, nil, nil); err != nil || updated != nil {
+		t.Fatalf("expected an older progress to be ignored, got %v, %v", updated, err)
+	}
+	if got := e.step(instance, "a"); *got.Progress != 50 || stringOf(got.ProgressMessage) != message || (*got.PartialOutput)["rows"] != 3 {
+		t.Fatalf("expected the progress to be recorded, got %+v", got)
+	}
+
+	// Notifications delivered twice or out of order leave the step as the
+	// first terminal status left it.
+	e.finishTask(instance, "a", proto.TaskStatus_COMPLETED, map[string]interface{}{"n": 1}, "")
+	e.finishTask(instance, "a", proto.TaskStatus_COMPLETED, map[string]interface{}{"n": 2}, "")
+	e.finishTask(instance, "a", proto.TaskStatus_FAILED, nil, "")
+	got := e.step(instance, "a")
+	if got.Status != models.StepInstanceStatusCompleted || (*got.Output)["n"] != 1 {
+		t.Fatalf("expected the step to keep its first completion, got %s with %v", got.Status, got.Output)
+	}
+	if started := e.agent.startedTasks(); len(started) != 2 {
+		t.Fatalf("expected the next step to start once, got %d tasks", len(started))
+	}
+}
+
+func TestTaskStopped(t *testing.T) {
+	e := newTestEnv(t)
+	instance := e.start(e.define(taskStep("a")), nil)
+
+	e.finishTask(instance, "a", proto.TaskStatus_STOPPED, nil, "")
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{"a": models.StepInstanceStatusFailed})
+	e.expectInstance(instance, models.WorkflowInstanceStatusFailed)
+}
+
+func TestTaskActionReports(t *testing.T) {
+	e := newTestEnv(t)
+	instance := e.start(e.define(taskStep("a")), nil)
+	e.reportActions()
+
+	if err := e.scheduler.PauseWorkflow(instance.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.step(instance, "a"); stringOf(got.AgentTaskStatus) != "PAUSED" {
+		t.Fatalf("expected the agent to report the task paused, got %s", stringOf(got.AgentTaskStatus))
+	}
+
+	if err := e.scheduler.ResumeWorkflow(instance.ID); err != nil {
+		t.Fatal(err)
+	}
+	e.run()
+	if got := e.step(instance, "a"); stringOf(got.AgentTaskStatus) != "RUNNING" {
+		t.Fatalf("expected the agent to report the task running, got %s", stringOf(got.AgentTaskStatus))
+	}
+
+	// The task reported stopped once the step was cancelled leaves it
+	// cancelled.
+	if err := e.scheduler.CancelWorkflow(instance.ID, "cancelled by test"); err != nil {
+		t.Fatal(err)
+	}
+	e.expectActions("pause task-1", "resume task-1", "stop task-1")
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{"a": models.StepInstanceStatusCancelled})
+	e.expectInstance(instance, models.WorkflowInstanceStatusCancelled)
+}
+
+func TestNoAgentForTask(t *testing.T) {
+	e := newTestEnv(t)
+	step := taskStep("a")
+	step.TaskConfig.TaskDefinitionID = "missing"
+	instance := e.start(e.define(step), nil)
+
+	got := e.step(instance, "a")
+	if got.Status != models.StepInstanceStatusFailed || !strings.Contains(stringOf(got.Error), engineErrors.ErrNoAgentForTask.Error()) {
+		t.Fatalf("expected the step to fail without an agent, got %s: %s", got.Status, stringOf(got.Error))
+	}
+	e.expectInstance(instance, models.WorkflowInstanceStatusFailed)
+}
+
+func TestHandleAgentOffline(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryCount *int
+		want       models.StepInstanceStatus
+	}{
+		{name: "without retry", want: models.StepInstanceStatusFailed},
+		{name: "with retry", retryCount: ptr(1), want: models.StepInstanceStatusRetrying},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			step := taskStep("a")
+			step.RetryCount = tt.retryCount
+			instance := e.start(e.define(step), nil)
+
+			e.scheduler.HandleAgentOffline("other-agent")
+			e.expectSteps(instance, map[string]models.StepInstanceStatus{"a": models.StepInstanceStatusRunning})
+
+			e.scheduler.HandleAgentOffline(testAgentName)
+			e.run()
+			e.expectSteps(instance, map[string]models.StepInstanceStatus{"a": tt.want})
+
+			attempts, _ := e.attempts.GetByStepInstanceID(e.step(instance, "a").ID.String())
+			if stringOf(attempts[0].ErrorCode) != engineErrors.ErrorCodeAgentOffline {
+				t.Fatalf("expected the attempt to fail as its agent went offline, got %s", stringOf(attempts[0].ErrorCode))
+			}
+		})
+	}
+}
+
+func TestRecoverTaskOnUnknownAgent(t *testing.T) {
+	e := newTestEnv(t)
+	step := taskStep("a")
+	step.RetryCount = ptr(1)
+	instance := e.start(e.define(step), nil)
+
+	// The agent running the task is gone when the engine restarts.
+	e.agentRegistry.UnregisterAgent(testAgentName)
+	e.restart()
+	if err := e.scheduler.recover(); err != nil {
+		t.Fatal(err)
+	}
+	e.run()
+
+	got := e.step(instance, "a")
+	if got.Status != models.StepInstanceStatusRetrying || !strings.Contains(stringOf(got.Error), engineErrors.ErrAgentOffline.Error()) {
+		t.Fatalf("expected the step to be retried as its agent is offline, got %s: %s", got.Status, stringOf(got.Error))
+	}
+}
+
+func TestRecoverUndeliveredTask(t *testing.T) {
+	e := newTestEnv(t)
+	instance := e.start(e.define(taskStep("a")), nil)
+
+	// The engine stored the task of the step, then stopped before the agent
+	// got it.
+	e.agent.mu.Lock()
+	e.agent.started = nil
+	e.agent.mu.Unlock()
+	e.restart()
+	if err := e.scheduler.recover(); err != nil {
+		t.Fatal(err)
+	}
+	e.run()
+
+	started := e.agent.startedTasks()
+	if len(started) != 1 || started[0].GetTaskId() != "task-1" {
+		t.Fatalf("expected the task to be started again with its ID, got %v", started)
+	}
+
+	// Tasks the agent reported on are not started again.
+	if err := e.scheduler.HandleTaskStatus(e.step(instance, "a").ID, proto.TaskStatus_RUNNING, nil, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.agent.mu.Lock()
+	e.agent.started = nil
+	e.agent.mu.Unlock()
+	e.restart()
+	if err := e.scheduler.recover(); err != nil {
+		t.Fatal(err)
+	}
+	e.run()
+	if started := e.agent.startedTasks(); len(started) != 0 {
+		t.Fatalf("expected a reported task not to be started again, got %v", started)
+	}
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{"a": models.StepInstanceStatusRunning})
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+// armStepTimeout stores the timer that times out the current attempt of a
+// running step, if its definition has a timeout. It is a no-op if the attempt
+// already has one.
+func (s *Scheduler) armStepTimeout(execution *StepExecution) error {
+	step := execution.Step
+	timeout := execution.StepDefinition.TimeoutSeconds
+	if timeout == nil || step.Status != models.StepInstanceStatusRunning {
+		return nil
+	}
+
+	timer, err := s.timerRepo.GetByStepInstanceID(step.ID.String(), models.TimerTypeStepTimeout)
+	if err != nil {
+		return err
+	}
+	// Timers created before the current attempt started belong to a previous
+	// attempt.
+	if timer != nil && !timer.CreatedAt.Before(*step.StartedAt) {
+		return nil
+	}
+
+	fireAt := step.StartedAt.Add(time.Duration(*timeout) * time.Second)
+	return s.createTimer(step, models.TimerTypeStepTimeout, fireAt)
+}
+
+// timeoutStep times out the attempt of a step that is still running when its
+// timeout timer fires. The work started by the step is released, as for a
+// cancelled step, and the retry policy decides whether another attempt starts.
+func (s *Scheduler) timeoutStep(timer *models.Timer) error {
+	return s.withExecution(*timer.StepInstanceID, func(execution *StepExecution) error {
+		step := execution.Step
+		if step.Status != models.StepInstanceStatusRunning || execution.Instance.Status.IsTerminal() {
+			return nil
+		}
+		if timer.CreatedAt.Before(*step.StartedAt) {
+			return nil
+		}
+
+		s.releaseStep(execution, step)
+
+		timeout := timer.FireAt.Sub(*step.StartedAt).Round(time.Second)
+		cause := engineErrors.WithCode(engineErrors.ErrorCodeTimedOut, fmt.Errorf("%w after %s", engineErrors.ErrStepTimedOut, timeout))
+		return s.endStep(execution, cause, models.StepInstanceStatusTimedOut, models.StepAttemptStatusTimedOut)
+	})
+}
+
+// armWorkflowTimeout stores the timer that times out a workflow instance, if
+// its definition has a timeout.
+func (s *Scheduler) armWorkflowTimeout(definition *models.WorkflowDefinition, instance *models.WorkflowInstance) error {
+	if definition.TimeoutSeconds == nil {
+		return nil
+	}
+
+	_, err := s.timerRepo.Create(&models.Timer{
+		WorkflowInstanceID: instance.ID,
+		Type:               models.TimerTypeWorkflowTimeout,
+		Status:             models.TimerStatusPending,
+		FireAt:             instance.CreatedAt.Add(time.Duration(*definition.TimeoutSeconds) * time.Second),
+	})
+	return err
+}
+
+// timeoutWorkflow ends a workflow instance that is still active when its
+// timeout timer fires, cancelling the steps still active in it.
+func (s *Scheduler) timeoutWorkflow(timer *models.Timer) error {
+	reason := fmt.Sprintf("%s after %s", engineErrors.ErrWorkflowTimedOut, timer.FireAt.Sub(timer.CreatedAt).Round(time.Second))
+	err := s.endWorkflow(timer.WorkflowInstanceID, models.WorkflowInstanceStatusTimedOut, reason)
+	if errors.Is(err, engineErrors.ErrWorkflowInstanceNotActive) {
+		return nil
+	}
+	return err
+}
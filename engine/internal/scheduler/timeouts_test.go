@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+func TestStepTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryCount *int
+		want       models.StepInstanceStatus
+		attempt    int
+		instance   models.WorkflowInstanceStatus
+	}{
+		{name: "without retry", want: models.StepInstanceStatusTimedOut, attempt: 1, instance: models.WorkflowInstanceStatusFailed},
+		{name: "with retry", retryCount: ptr(1), want: models.StepInstanceStatusRunning, attempt: 2, instance: models.WorkflowInstanceStatusRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			step := taskStep("a")
+			step.TimeoutSeconds = ptr(30)
+			step.RetryCount = tt.retryCount
+			instance := e.start(e.define(step), nil)
+			e.reportActions()
+
+			running := e.step(instance, "a")
+			timer := e.timer(running, models.TimerTypeStepTimeout)
+			if !timer.FireAt.Equal(running.StartedAt.Add(30 * time.Second)) {
+				t.Fatalf("expected the timeout to fire 30s after the step started, got %s", timer.FireAt.Sub(*running.StartedAt))
+			}
+			if started := e.agent.startedTasks(); started[0].GetTimeoutSeconds() != 30 {
+				t.Fatalf("expected the timeout to be sent to the agent, got %v", started[0])
+			}
+
+			e.fireTimer(timer)
+			e.expectActions("stop task-1")
+			if tt.retryCount != nil {
+				e.fireTimer(e.timer(running, models.TimerTypeRetry))
+			}
+
+			got := e.step(instance, "a")
+			if got.Status != tt.want || got.Attempt != tt.attempt {
+				t.Fatalf("expected step to be %s on attempt %d, got %s on attempt %d", tt.want, tt.attempt, got.Status, got.Attempt)
+			}
+			e.expectInstance(instance, tt.instance)
+
+			attempts, _ := e.attempts.GetByStepInstanceID(running.ID.String())
+			if attempts[0].Status != models.StepAttemptStatusTimedOut || stringOf(attempts[0].ErrorCode) != engineErrors.ErrorCodeTimedOut {
+				t.Fatalf("expected the first attempt to time out, got %+v", attempts[0])
+			}
+
+			// Each attempt gets its own timeout, and the timer of the first
+			// attempt does not time out the second one.
+			if tt.retryCount != nil {
+				next := e.timer(got, models.TimerTypeStepTimeout)
+				if next.ID == timer.ID || next.Status != models.TimerStatusPending {
+					t.Fatalf("expected the second attempt to arm a new timeout, got %+v", next)
+				}
+				if err := e.scheduler.timeoutStep(timer); err != nil {
+					t.Fatal(err)
+				}
+				if got := e.step(instance, "a"); got.Status != models.StepInstanceStatusRunning {
+					t.Fatalf("expected a stale timeout to be ignored, got %s", got.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkflowTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		finish   bool
+		want     models.WorkflowInstanceStatus
+		stopped  []string
+		timerEnd models.TimerStatus
+	}{
+		{name: "times out an active instance", want: models.WorkflowInstanceStatusTimedOut, stopped: []string{"stop task-1"}, timerEnd: models.TimerStatusFired},
+		{name: "is cancelled once the instance completed", finish: true, want: models.WorkflowInstanceStatusCompleted, timerEnd: models.TimerStatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			definition := e.define(taskStep("a"))
+			definition.TimeoutSeconds = ptr(600)
+			if _, err := e.definitions.Create(definition); err != nil {
+				t.Fatal(err)
+			}
+			instance := e.start(definition, nil)
+			e.reportActions()
+
+			if len(e.timers.timers) != 1 || e.timers.timers[0].Type != models.TimerTypeWorkflowTimeout {
+				t.Fatalf("expected a workflow timeout timer, got %+v", e.timers.timers)
+			}
+			timer := e.timers.timers[0]
+			if !timer.FireAt.Equal(instance.CreatedAt.Add(10 * time.Minute)) {
+				t.Fatalf("expected the timeout to fire 10m after the instance was created, got %s", timer.FireAt.Sub(instance.CreatedAt))
+			}
+
+			if tt.finish {
+				e.finishTask(instance, "a", proto.TaskStatus_COMPLETED, nil, "")
+			} else {
+				e.fireTimer(&timer)
+				e.expectSteps(instance, map[string]models.StepInstanceStatus{"a": models.StepInstanceStatusCancelled})
+				if got := e.instance(instance.ID); !strings.Contains(stringOf(got.Error), engineErrors.ErrWorkflowTimedOut.Error()) {
+					t.Fatalf("expected the instance to record its timeout, got %s", stringOf(got.Error))
+				}
+			}
+
+			e.expectInstance(instance, tt.want)
+			e.expectActions(tt.stopped...)
+			if got, _ := e.timers.GetByID(timer.ID.String()); got.Status != tt.timerEnd {
+				t.Fatalf("expected the timer to be %s, got %s", tt.timerEnd, got.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"gorm.io/gorm"
+)
+
+// FireTimer fires a pending timer ahead of its deadline. If its effect cannot
+// be applied, the timer stays claimed and is fired again once its claim times
+// out.
+func (s *Scheduler) FireTimer(timerID uuid.UUID) (*models.Timer, error) {
+	timer, err := s.timerRepo.Claim(timerID.String())
+	if err != nil {
+		return nil, err
+	}
+	if timer == nil {
+		return nil, s.timerNotPending(timerID)
+	}
+
+	if err := s.fireTimer(timer); err != nil {
+		return nil, err
+	}
+	return timer, nil
+}
+
+// CancelTimer cancels a pending timer. The step waiting for a wait timer
+// fails without being retried, since the deadline it was waiting for will
+// never be reached, and cancelling a retry timer gives up on the step without
+// further attempts. Cancelling a timeout timer lifts the timeout.
+func (s *Scheduler) CancelTimer(timerID uuid.UUID) (*models.Timer, error) {
+	timer, err := s.timerRepo.Cancel(timerID.String())
+	if err != nil {
+		return nil, err
+	}
+	if timer == nil {
+		return nil, s.timerNotPending(timerID)
+	}
+
+	switch timer.Type {
+	case models.TimerTypeWait, models.TimerTypeRetry:
+		if err := s.AbortStep(*timer.StepInstanceID, engineErrors.ErrTimerCancelled); err != nil {
+			return nil, err
+		}
+	}
+	return timer, nil
+}
+
+// createTimer stores a timer for a step instance. It is a no-op if the step
+// already has a pending timer of the same type.
+func (s *Scheduler) createTimer(step *models.StepInstance, timerType models.TimerType, fireAt time.Time) error {
+	existing, err := s.timerRepo.GetByStepInstanceID(step.ID.String(), timerType)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Status == models.TimerStatusPending {
+		return nil
+	}
+
+	_, err = s.timerRepo.Create(&models.Timer{
+		WorkflowInstanceID: step.WorkflowInstanceID,
+		StepInstanceID:     &step.ID,
+		Type:               timerType,
+		Status:             models.TimerStatusPending,
+		FireAt:             fireAt,
+	})
+	return err
+}
+
+// pollTimers fires the due timers right away, then on every poll. Timers
+// claimed by a previous run that never applied their effect are fired again
+// once their claim times out.
+func (s *Scheduler) pollTimers(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.TimerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.fireDueTimers()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) fireDueTimers() {
+	timers, err := s.timerRepo.ClaimDue(time.Now(), s.cfg.TimerClaimTimeout, s.cfg.TimerBatchSize)
+	if err != nil {
+		log.Printf("[scheduler] failed to claim due timers: %v", err)
+		return
+	}
+	for i := range timers {
+		if err := s.fireTimer(&timers[i]); err != nil {
+			log.Printf("[scheduler] failed to fire %s timer %s, retrying once its claim times out: %v", timers[i].Type, timers[i].ID, err)
+		}
+	}
+}
+
+// fireTimer applies the effect of a claimed timer, then marks it fired. The
+// effects are no-ops once applied, so a timer fired again after a failure or a
+// crash between the two steps does no harm.
+func (s *Scheduler) fireTimer(timer *models.Timer) error {
+	var err error
+	switch timer.Type {
+	case models.TimerTypeWait:
+		err = s.CompleteStep(*timer.StepInstanceID, nil)
+	case models.TimerTypeRetry:
+		err = s.startNextAttempt(*timer.StepInstanceID)
+	case models.TimerTypeStepTimeout:
+		err = s.timeoutStep(timer)
+	case models.TimerTypeWorkflowTimeout:
+		err = s.timeoutWorkflow(timer)
+	default:
+		err = fmt.Errorf("unknown timer type %s", timer.Type)
+	}
+	if err != nil {
+		return err
+	}
+	return s.timerRepo.MarkFired(timer.ID.String())
+}
+
+func (s *Scheduler) timerNotPending(timerID uuid.UUID) error {
+	if _, err := s.timerRepo.GetByID(timerID.String()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %s", engineErrors.ErrTimerNotFound, timerID)
+		}
+		return err
+	}
+	return fmt.Errorf("%w: %s", engineErrors.ErrTimerNotPending, timerID)
+}
@@ -0,0 +1,175 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+func waitStep(id string, seconds float64, next string) models.WorkflowStepDefinition {
+	return models.WorkflowStepDefinition{
+		StepDefinitionID: id,
+		Name:             id,
+		Type:             models.StepTypeWait,
+		WaitConfig: &models.WaitConfig{
+			DurationSeconds: models.StepDefinitionParameter{Type: models.StepParameterTypeConstant, Value: seconds},
+			NextStepID:      &next,
+		},
+	}
+}
+
+func TestWaitStep(t *testing.T) {
+	e := newTestEnv(t)
+	instance := e.start(e.define(waitStep("wait", 60, "after"), taskStep("after")), nil)
+
+	wait := e.step(instance, "wait")
+	timer := e.timer(wait, models.TimerTypeWait)
+	if wait.Status != models.StepInstanceStatusRunning || timer.Status != models.TimerStatusPending {
+		t.Fatalf("expected the step to wait for a pending timer, got step %s and timer %s", wait.Status, timer.Status)
+	}
+	if !timer.FireAt.Equal(wait.StartedAt.Add(time.Minute)) {
+		t.Fatalf("expected the timer to fire a minute after the step started, got %s", timer.FireAt.Sub(*wait.StartedAt))
+	}
+
+	// Processing the step again does not arm a second timer.
+	e.scheduler.enqueue(wait.ID)
+	e.run()
+	if len(e.timers.timers) != 1 {
+		t.Fatalf("expected a single timer, got %d", len(e.timers.timers))
+	}
+
+	e.fireTimer(timer)
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{
+		"wait":  models.StepInstanceStatusCompleted,
+		"after": models.StepInstanceStatusRunning,
+	})
+	if fired := e.timer(wait, models.TimerTypeWait); fired.Status != models.TimerStatusFired || fired.FiredAt == nil {
+		t.Fatalf("expected the timer to be marked fired, got %+v", fired)
+	}
+
+	if _, err := e.scheduler.FireTimer(timer.ID); !errors.Is(err, engineErrors.ErrTimerNotPending) {
+		t.Fatalf("expected firing a fired timer to be refused, got %v", err)
+	}
+	if _, err := e.scheduler.CancelTimer(timer.ID); !errors.Is(err, engineErrors.ErrTimerNotPending) {
+		t.Fatalf("expected cancelling a fired timer to be refused, got %v", err)
+	}
+	if _, err := e.scheduler.FireTimer(uuid.New()); !errors.Is(err, engineErrors.ErrTimerNotFound) {
+		t.Fatalf("expected firing an unknown timer to be refused, got %v", err)
+	}
+}
+
+func TestCancelTimer(t *testing.T) {
+	tests := []struct {
+		name      string
+		timerType models.TimerType
+		step      models.WorkflowStepDefinition
+		prepare   func(e *testEnv, instance *models.WorkflowInstance)
+		want      models.StepInstanceStatus
+		instance  models.WorkflowInstanceStatus
+	}{
+		{
+			name:      "wait timer fails the step without retrying it",
+			timerType: models.TimerTypeWait,
+			step: func() models.WorkflowStepDefinition {
+				step := waitStep("a", 60, "after")
+				step.RetryCount = ptr(3)
+				return step
+			}(),
+			prepare:  func(*testEnv, *models.WorkflowInstance) {},
+			want:     models.StepInstanceStatusFailed,
+			instance: models.WorkflowInstanceStatusFailed,
+		},
+		{
+			name:      "retry timer gives up on the step",
+			timerType: models.TimerTypeRetry,
+			step: func() models.WorkflowStepDefinition {
+				step := taskStep("a")
+				step.RetryCount = ptr(3)
+				return step
+			}(),
+			prepare: func(e *testEnv, instance *models.WorkflowInstance) {
+				e.finishTask(instance, "a", proto.TaskStatus_FAILED, nil, "")
+			},
+			want:     models.StepInstanceStatusFailed,
+			instance: models.WorkflowInstanceStatusFailed,
+		},
+		{
+			name:      "step timeout timer lifts the timeout",
+			timerType: models.TimerTypeStepTimeout,
+			step: func() models.WorkflowStepDefinition {
+				step := taskStep("a")
+				step.TimeoutSeconds = ptr(30)
+				return step
+			}(),
+			prepare:  func(*testEnv, *models.WorkflowInstance) {},
+			want:     models.StepInstanceStatusRunning,
+			instance: models.WorkflowInstanceStatusRunning,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			instance := e.start(e.define(tt.step, taskStep("after")), nil)
+			tt.prepare(e, instance)
+
+			timer := e.timer(e.step(instance, "a"), tt.timerType)
+			cancelled, err := e.scheduler.CancelTimer(timer.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cancelled.Status != models.TimerStatusCancelled {
+				t.Fatalf("expected the timer to be cancelled, got %s", cancelled.Status)
+			}
+			e.run()
+
+			step := e.step(instance, "a")
+			if step.Status != tt.want || step.Attempt != 1 {
+				t.Fatalf("expected step to be %s on its first attempt, got %s on attempt %d", tt.want, step.Status, step.Attempt)
+			}
+			if tt.want == models.StepInstanceStatusFailed && stringOf(step.Error) != engineErrors.ErrTimerCancelled.Error() {
+				t.Fatalf("expected the step to fail as its timer was cancelled, got %s", stringOf(step.Error))
+			}
+			e.expectInstance(instance, tt.instance)
+		})
+	}
+}
+
+func TestFireDueTimers(t *testing.T) {
+	e := newTestEnv(t)
+	e.scheduler.cfg.TimerClaimTimeout = 10 * time.Millisecond
+	instance := e.start(e.define(waitStep("a", 0, "b"), waitStep("b", 3600, "c"), taskStep("c")), nil)
+
+	e.scheduler.fireDueTimers()
+	e.run()
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{
+		"a": models.StepInstanceStatusCompleted,
+		"b": models.StepInstanceStatusRunning,
+	})
+
+	// The engine claims the timer of b, then crashes before applying it. The
+	// timer is fired again once its claim times out, although it is not due.
+	timer := e.timer(e.step(instance, "b"), models.TimerTypeWait)
+	if claimed, _ := e.timers.Claim(timer.ID.String()); claimed == nil {
+		t.Fatal("expected the timer to be claimed")
+	}
+	e.scheduler.fireDueTimers()
+	e.run()
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{"b": models.StepInstanceStatusRunning})
+
+	time.Sleep(2 * e.scheduler.cfg.TimerClaimTimeout)
+	e.scheduler.fireDueTimers()
+	e.run()
+	e.expectSteps(instance, map[string]models.StepInstanceStatus{
+		"b": models.StepInstanceStatusCompleted,
+		"c": models.StepInstanceStatusRunning,
+	})
+	if fired := e.timer(e.step(instance, "b"), models.TimerTypeWait); fired.Status != models.TimerStatusFired {
+		t.Fatalf("expected the timer to be marked fired, got %s", fired.Status)
+	}
+}
@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+type WaitStepHandler struct {
+	scheduler *Scheduler
+}
+
+func NewWaitStepHandler(scheduler *Scheduler) *WaitStepHandler {
+	return &WaitStepHandler{
+		scheduler: scheduler,
+	}
+}
+
+// Handle stores a durable timer that completes the step once the wait
+// duration elapsed since the step started. A step whose timer already fired
+// while the engine was down completes right away.
+func (h *WaitStepHandler) Handle(_ context.Context, execution *StepExecution) (*StepResult, error) {
+	config := execution.StepDefinition.WaitConfig
+	if config == nil {
+		return nil, errors.New("wait step has no wait configuration")
+	}
+
+	timer, err := h.scheduler.timerRepo.GetByStepInstanceID(execution.Step.ID.String(), models.TimerTypeWait)
+	if err != nil {
+		return nil, err
+	}
+	// Timers created before the current attempt started belong to a previous
+	// attempt.
+	if timer != nil && !timer.CreatedAt.Before(*execution.Step.StartedAt) {
+		switch timer.Status {
+		case models.TimerStatusFired:
+			return completed(nil), nil
+		case models.TimerStatusCancelled:
+			return nil, engineErrors.ErrTimerCancelled
+		default:
+			return inProgress(), nil
+		}
+	}
+
+	value, err := resolveParameter(config.DurationSeconds, execution)
+	if err != nil {
+		return nil, fmt.Errorf("resolve wait duration: %w", err)
+	}
+
+	seconds, ok := value.(float64)
+	if !ok || seconds < 0 {
+		return nil, fmt.Errorf("wait duration must be a non-negative number of seconds, got %v", value)
+	}
+
+	fireAt := execution.Step.StartedAt.Add(time.Duration(seconds * float64(time.Second)))
+	if err := h.scheduler.createTimer(execution.Step, models.TimerTypeWait, fireAt); err != nil {
+		return nil, fmt.Errorf("create wait timer: %w", err)
+	}
+
+	return inProgress(), nil
+}
@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"gorm.io/gorm"
+)
+
+type WorkflowStepHandler struct {
+	scheduler *Scheduler
+}
+
+func NewWorkflowStepHandler(scheduler *Scheduler) *WorkflowStepHandler {
+	return &WorkflowStepHandler{
+		scheduler: scheduler,
+	}
+}
+
+// Handle starts a child workflow instance with the resolved step parameters as
+// its input. The child enqueues this step again once it finished, and the step
+// then completes with the child's output or fails with its error.
+func (h *WorkflowStepHandler) Handle(_ context.Context, execution *StepExecution) (*StepResult, error) {
+	config := execution.StepDefinition.WorkflowConfig
+	if config == nil {
+		return nil, errors.New("workflow step has no workflow configuration")
+	}
+
+	child, err := h.scheduler.workflowInstanceRepo.GetByParentStepInstanceID(execution.Step.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	// A child created before the current attempt started belongs to a
+	// previous attempt.
+	if child != nil && !child.CreatedAt.Before(*execution.Step.StartedAt) {
+		return childResult(child)
+	}
+
+	definition, err := h.scheduler.workflowDefinitionRepo.GetByID(config.WorkflowDefinitionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", engineErrors.ErrWorkflowDefinitionNotFound, config.WorkflowDefinitionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !definition.IsEnabled {
+		return nil, fmt.Errorf("%w: %s", engineErrors.ErrWorkflowDefinitionDisabled, config.WorkflowDefinitionID)
+	}
+	if definition.IsDraft() {
+		return nil, fmt.Errorf("%w: %s", engineErrors.ErrWorkflowDefinitionDraft, config.WorkflowDefinitionID)
+	}
+
+	input, err := resolveParameters(execution.StepDefinition.Parameters, execution)
+	if err != nil {
+		return nil, err
+	}
+
+	var parameterDefinitions models.WorkflowParameterDefinitionList
+	if definition.InputParameters != nil {
+		parameterDefinitions = *definition.InputParameters
+	}
+	childInput, err := parameterDefinitions.ValidateInput(input)
+	if err != nil {
+		return nil, fmt.Errorf("child workflow input: %w", err)
+	}
+
+	child, err = h.scheduler.startWorkflow(definition, childInput, execution.Step)
+	if err != nil {
+		return nil, fmt.Errorf("start child workflow: %w", err)
+	}
+
+	inputMap := models.JsonMap(childInput)
+	execution.Step.Input = &inputMap
+	log.Printf("[scheduler] step %s of workflow instance %s started child workflow instance %s", execution.Step.StepDefinitionID, execution.Instance.ID, child.ID)
+
+	return inProgress(), nil
+}
+
+// Cancel cancels the child workflow of a cancelled workflow step.
+func (h *WorkflowStepHandler) Cancel(_ *StepExecution, step *models.StepInstance) error {
+	reason := "parent workflow instance " + step.WorkflowInstanceID.String() + " cancelled"
+	return h.applyToChild(step, "cancel", func(childID uuid.UUID) error {
+		return h.scheduler.CancelWorkflow(childID, reason)
+	}, engineErrors.ErrWorkflowInstanceNotActive)
+}
+
+// Pause pauses the child workflow of a step whose workflow instance is paused.
+func (h *WorkflowStepHandler) Pause(_ *StepExecution, step *models.StepInstance) error {
+	return h.applyToChild(step, "pause", h.scheduler.PauseWorkflow, engineErrors.ErrWorkflowInstanceNotRunning)
+}
+
+// Resume resumes the child workflow of a step whose workflow instance is
+// resumed.
+func (h *WorkflowStepHandler) Resume(_ *StepExecution, step *models.StepInstance) error {
+	return h.applyToChild(step, "resume", h.scheduler.ResumeWorkflow, engineErrors.ErrWorkflowInstanceNotPaused)
+}
+
+// applyToChild applies an action to the child workflow of a step. Errors
+// telling the child is already in the expected state are ignored.
+func (h *WorkflowStepHandler) applyToChild(step *models.StepInstance, verb string, action func(uuid.UUID) error, ignored error) error {
+	child, err := h.scheduler.workflowInstanceRepo.GetByParentStepInstanceID(step.ID.String())
+	if err != nil {
+		return err
+	}
+	if child == nil || child.Status.IsTerminal() {
+		return nil
+	}
+
+	if err := action(child.ID); err != nil && !errors.Is(err, ignored) {
+		return fmt.Errorf("%s child workflow instance %s: %w", verb, child.ID, err)
+	}
+	return nil
+}
+
+func childResult(child *models.WorkflowInstance) (*StepResult, error) {
+	switch child.Status {
+	case models.WorkflowInstanceStatusCompleted:
+		var output map[string]interface{}
+		if child.Output != nil {
+			output = *child.Output
+		}
+		return completed(output), nil
+	case models.WorkflowInstanceStatusFailed, models.WorkflowInstanceStatusCancelled, models.WorkflowInstanceStatusTimedOut:
+		message := string(child.Status)
+		if child.Error != nil {
+			message += ": " + *child.Error
+		}
+		return nil, fmt.Errorf("child workflow instance %s %s", child.ID, message)
+	default:
+		return inProgress(), nil
+	}
+}
@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+)
+
+func workflowStep(id string, child *models.WorkflowDefinition) models.WorkflowStepDefinition {
+	return models.WorkflowStepDefinition{
+		StepDefinitionID: id,
+		Name:             id,
+		Type:             models.StepTypeWorkflow,
+		WorkflowConfig:   &models.WorkflowConfig{WorkflowDefinitionID: child.ID.String()},
+		Parameters: &models.StepDefinitionParameters{
+			"orderId": {Type: models.StepParameterTypeWorkflow, Value: "orderId"},
+		},
+	}
+}
+
+// childDefinition stores a workflow taking an order ID as input and running
+// a single task.
+func childDefinition(e *testEnv) *models.WorkflowDefinition {
+	e.t.Helper()
+	definition := e.define(taskStep("work"))
+	definition.InputParameters = &models.WorkflowParameterDefinitionList{{Name: "orderId", Type: "string", Required: true}}
+	if _, err := e.definitions.Create(definition); err != nil {
+		e.t.Fatal(err)
+	}
+	return definition
+}
+
+// startParent starts a parent workflow whose only step runs the child
+// definition, and returns both instances.
+func startParent(e *testEnv, child *models.WorkflowDefinition) (parent, childInstance *models.WorkflowInstance) {
+	e.t.Helper()
+	parent = e.start(e.define(workflowStep("child", child)), map[string]interface{}{"orderId": "o-1"})
+	childInstance, _ = e.instances.GetByParentStepInstanceID(e.step(parent, "child").ID.String())
+	if childInstance == nil {
+		e.t.Fatal("expected the child workflow to be started")
+	}
+	return parent, childInstance
+}
+
+func TestChildWorkflow(t *testing.T) {
+	tests := []struct {
+		name   string
+		status proto.TaskStatus
+		want   models.StepInstanceStatus
+		parent models.WorkflowInstanceStatus
+	}{
+		{name: "completes the parent step", status: proto.TaskStatus_COMPLETED, want: models.StepInstanceStatusCompleted, parent: models.WorkflowInstanceStatusCompleted},
+		{name: "fails the parent step", status: proto.TaskStatus_FAILED, want: models.StepInstanceStatusFailed, parent: models.WorkflowInstanceStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			parent, child := startParent(e, childDefinition(e))
+
+			if child.ParentWorkflowInstanceID == nil || *child.ParentWorkflowInstanceID != parent.ID {
+				t.Fatalf("expected the child to reference its parent, got %v", child.ParentWorkflowInstanceID)
+			}
+			if child.Input == nil || (*child.Input)["orderId"] != "o-1" {
+				t.Fatalf("expected the child to get the step parameters as input, got %v", child.Input)
+			}
+			e.expectSteps(parent, map[string]models.StepInstanceStatus{"child": models.StepInstanceStatusRunning})
+
+			e.finishTask(child, "work", tt.status, map[string]interface{}{"done": true}, "")
+			e.expectSteps(parent, map[string]models.StepInstanceStatus{"child": tt.want})
+			e.expectInstance(parent, tt.parent)
+
+			step := e.step(parent, "child")
+			if tt.want == models.StepInstanceStatusCompleted && (step.Output == nil || (*step.Output)["done"] != true) {
+				t.Fatalf("expected the step to complete with the output of the child, got %v", step.Output)
+			}
+			if tt.want == models.StepInstanceStatusFailed && !strings.Contains(stringOf(step.Error), child.ID.String()) {
+				t.Fatalf("expected the step to fail with the error of the child, got %s", stringOf(step.Error))
+			}
+		})
+	}
+}
+
+func TestChildWorkflowDefinitionRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(definition *models.WorkflowDefinition)
+		want    error
+	}{
+		{name: "disabled", prepare: func(definition *models.WorkflowDefinition) { definition.IsEnabled = false }, want: engineErrors.ErrWorkflowDefinitionDisabled},
+		{name: "draft", prepare: func(definition *models.WorkflowDefinition) { definition.Version = "1.1.0-draft" }, want: engineErrors.ErrWorkflowDefinitionDraft},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			child := childDefinition(e)
+			tt.prepare(child)
+			if _, err := e.definitions.Create(child); err != nil {
+				t.Fatal(err)
+			}
+
+			parent := e.start(e.define(workflowStep("child", child)), map[string]interface{}{"orderId": "o-1"})
+			step := e.step(parent, "child")
+			if step.Status != models.StepInstanceStatusFailed || !strings.Contains(stringOf(step.Error), tt.want.Error()) {
+				t.Fatalf("expected the step to fail with %v, got %s: %s", tt.want, step.Status, stringOf(step.Error))
+			}
+			if child, _ := e.instances.GetByParentStepInstanceID(step.ID.String()); child != nil {
+				t.Fatalf("expected no child workflow to start, got %s", child.ID)
+			}
+		})
+	}
+}
+
+func TestCancelParentWorkflow(t *testing.T) {
+	e := newTestEnv(t)
+	parent, child := startParent(e, childDefinition(e))
+
+	if err := e.scheduler.CancelWorkflow(parent.ID, "cancelled by test"); err != nil {
+		t.Fatal(err)
+	}
+	e.expectInstance(child, models.WorkflowInstanceStatusCancelled)
+	e.expectSteps(child, map[string]models.StepInstanceStatus{"work": models.StepInstanceStatusCancelled})
+	e.expectActions("stop task-1")
+	e.expectInstance(parent, models.WorkflowInstanceStatusCancelled)
+}
+
+func TestPauseParentWorkflow(t *testing.T) {
+	e := newTestEnv(t)
+	parent, child := startParent(e, childDefinition(e))
+
+	if err := e.scheduler.PauseWorkflow(parent.ID); err != nil {
+		t.Fatal(err)
+	}
+	e.expectInstance(child, models.WorkflowInstanceStatusPaused)
+	e.expectActions("pause task-1")
+
+	if err := e.scheduler.ResumeWorkflow(parent.ID); err != nil {
+		t.Fatal(err)
+	}
+	e.run()
+	e.expectInstance(child, models.WorkflowInstanceStatusRunning)
+	e.expectActions("pause task-1", "resume task-1")
+
+	e.finishTask(child, "work", proto.TaskStatus_COMPLETED, nil, "")
+	e.expectInstance(parent, models.WorkflowInstanceStatusCompleted)
+}
@@ -0,0 +1,229 @@
+package validation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+// validateGraph checks the shape of the step graph: every step is reachable
+// from the first one, no step leads back to itself, and the branches of forks
+// and decisions converge at their join step.
+//
+// The engine has no loop construct: a step runs at most once per workflow
+// instance, so every cycle is reported.
+func validateGraph(definition *models.WorkflowDefinition, found *violations) {
+	steps := *definition.Steps
+	byID := make(map[string]models.WorkflowStepDefinition, len(steps))
+	for _, step := range steps {
+		byID[step.StepDefinitionID] = step
+	}
+
+	hasCycle := validateCycles(steps, byID, found)
+
+	reachable := reachableFrom(byID, steps[0].StepDefinitionID, "")
+	for _, step := range steps {
+		if _, ok := reachable[step.StepDefinitionID]; !ok {
+			found.add(step.StepDefinitionID, "", "step is unreachable from the first step %s", steps[0].StepDefinitionID)
+		}
+	}
+
+	// Scopes can only be delimited in an acyclic graph.
+	if !hasCycle {
+		validateScopes(steps, byID, found)
+	}
+}
+
+// successors returns the steps that can run right after a step. The join step
+// of a fork or decision is one of them, since branches without a next step
+// converge there.
+func successors(step models.WorkflowStepDefinition) []models.StepReference {
+	refs := make([]models.StepReference, 0)
+	for _, ref := range step.GetStepReferences() {
+		if !strings.HasPrefix(ref.Field, "joinConfig.incomingStepIds") {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// reachableFrom returns the steps reachable from a step, the step included.
+// The traversal does not go past the step named stop.
+func reachableFrom(byID map[string]models.WorkflowStepDefinition, start, stop string) map[string]struct{} {
+	reachable := make(map[string]struct{})
+	queue := []string{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if _, seen := reachable[current]; seen || current == stop {
+			continue
+		}
+		reachable[current] = struct{}{}
+		for _, ref := range successors(byID[current]) {
+			queue = append(queue, ref.StepID)
+		}
+	}
+	return reachable
+}
+
+// validateCycles reports the references closing a cycle, and whether there is
+// any.
+func validateCycles(steps []models.WorkflowStepDefinition, byID map[string]models.WorkflowStepDefinition, found *violations) bool {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(steps))
+	hasCycle := false
+
+	var path []string
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		path = append(path, id)
+		for _, ref := range successors(byID[id]) {
+			switch state[ref.StepID] {
+			case unvisited:
+				visit(ref.StepID)
+			case visiting:
+				hasCycle = true
+				cycle := append(slices.Clone(path[slices.Index(path, ref.StepID):]), ref.StepID)
+				found.add(id, ref.Field, "creates a cycle: %s", strings.Join(cycle, " -> "))
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+	}
+
+	for _, step := range steps {
+		if state[step.StepDefinitionID] == unvisited {
+			visit(step.StepDefinitionID)
+		}
+	}
+	return hasCycle
+}
+
+// validateScopes checks that each fork and decision owns its join step, that
+// the steps of its branches do not lead past the join step, and that the
+// incoming steps of a fork join are the steps of its branches.
+func validateScopes(steps []models.WorkflowStepDefinition, byID map[string]models.WorkflowStepDefinition, found *violations) {
+	owners := make(map[string]string)
+	for _, step := range steps {
+		joinStepID := step.GetJoinStepID()
+		if joinStepID == nil {
+			continue
+		}
+		field := configField(step.Type) + ".joinStepId"
+		if owner, owned := owners[*joinStepID]; owned {
+			found.add(step.StepDefinitionID, field, "step %s is already the join step of step %s", *joinStepID, owner)
+			continue
+		}
+		owners[*joinStepID] = step.StepDefinitionID
+
+		join := byID[*joinStepID]
+		if step.Type == models.StepTypeFork && join.Type != models.StepTypeJoin {
+			found.add(step.StepDefinitionID, field, "the join step %s of a fork must be a join step", *joinStepID)
+			continue
+		}
+		validateBranches(step, join, byID, found)
+	}
+
+	for _, step := range steps {
+		if _, owned := owners[step.StepDefinitionID]; step.Type == models.StepTypeJoin && !owned {
+			found.add(step.StepDefinitionID, "", "join step is not the join step of any fork or decision")
+		}
+	}
+}
+
+// branch is a branch of a fork or decision, with the field holding its first
+// step.
+type branch struct {
+	field    string
+	nextStep string
+}
+
+func branchesOf(step models.WorkflowStepDefinition) []branch {
+	branches := make([]branch, 0)
+	if step.ForkConfig != nil {
+		for i, forkBranch := range step.ForkConfig.Branches {
+			branches = append(branches, branch{field: fmt.Sprintf("forkConfig.branches[%d].nextStepId", i), nextStep: forkBranch.NextStepID})
+		}
+	}
+	if step.DecisionConfig != nil {
+		for i, decisionCase := range step.DecisionConfig.Cases {
+			branches = append(branches, branch{field: fmt.Sprintf("decisionConfig.cases[%d].nextStepId", i), nextStep: decisionCase.NextStepID})
+		}
+		if step.DecisionConfig.DefaultNextStepID != nil {
+			branches = append(branches, branch{field: "decisionConfig.defaultNextStepId", nextStep: *step.DecisionConfig.DefaultNextStepID})
+		}
+	}
+	return branches
+}
+
+func validateBranches(scope, join models.WorkflowStepDefinition, byID map[string]models.WorkflowStepDefinition, found *violations) {
+	isFork := scope.Type == models.StepTypeFork
+	after := reachableFrom(byID, join.StepDefinitionID, "")
+	owner := make(map[string]int)
+	covered := make(map[string]struct{})
+
+	incoming := make(map[string]struct{})
+	if isFork && join.JoinConfig != nil {
+		for _, stepID := range join.JoinConfig.IncomingStepIDs {
+			incoming[stepID] = struct{}{}
+		}
+	}
+
+	for i, b := range branchesOf(scope) {
+		if b.nextStep == join.StepDefinitionID {
+			if isFork {
+				found.add(scope.StepDefinitionID, b.field, "branch leads straight to its join step %s", join.StepDefinitionID)
+			}
+			continue
+		}
+
+		body := reachableFrom(byID, b.nextStep, join.StepDefinitionID)
+		stepIDs := make([]string, 0, len(body))
+		for stepID := range body {
+			stepIDs = append(stepIDs, stepID)
+		}
+		slices.Sort(stepIDs)
+
+		hasIncoming := false
+		for _, stepID := range stepIDs {
+			if _, past := after[stepID]; past {
+				found.add(scope.StepDefinitionID, b.field, "branch does not converge at its join step %s: it leads to step %s after it", join.StepDefinitionID, stepID)
+				break
+			}
+		}
+		for _, stepID := range stepIDs {
+			if other, shared := owner[stepID]; isFork && shared {
+				found.add(scope.StepDefinitionID, b.field, "branch shares step %s with branch %d", stepID, other)
+				break
+			}
+		}
+		for _, stepID := range stepIDs {
+			if _, seen := owner[stepID]; !seen {
+				owner[stepID] = i
+			}
+			covered[stepID] = struct{}{}
+			if _, ok := incoming[stepID]; ok {
+				hasIncoming = true
+			}
+		}
+		if isFork && !hasIncoming {
+			found.add(scope.StepDefinitionID, b.field, "branch has no step among the incoming steps of its join step %s", join.StepDefinitionID)
+		}
+	}
+
+	if !isFork || join.JoinConfig == nil {
+		return
+	}
+	for i, stepID := range join.JoinConfig.IncomingStepIDs {
+		if _, ok := covered[stepID]; !ok {
+			found.add(join.StepDefinitionID, fmt.Sprintf("joinConfig.incomingStepIds[%d]", i), "step %s is not in a branch of fork %s", stepID, scope.StepDefinitionID)
+		}
+	}
+}
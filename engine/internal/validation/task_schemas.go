@@ -0,0 +1,264 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/resolver"
+)
+
+// TaskSchemas are the JSON Schemas of the input and output parameters of a
+// task, as published by the agents supporting it. A nil schema accepts
+// anything.
+type TaskSchemas struct {
+	Input  map[string]interface{}
+	Output map[string]interface{}
+}
+
+// TaskSchemaLookup returns the schemas of a task, and whether any agent
+// supports it.
+type TaskSchemaLookup func(taskID string) (*TaskSchemas, bool)
+
+// ValidateTaskSchemas type-checks the parameters of the task steps against the
+// schemas of their tasks: every required input is set, and every parameter has
+// a type the task accepts. workflowInput references are typed by the input
+// parameters of the definition, and taskOutput references by the output schema
+// of the referenced task, which must declare the referenced field.
+//
+// It expects a definition that passed ValidateWorkflowDefinition.
+func ValidateTaskSchemas(definition *models.WorkflowDefinition, lookup TaskSchemaLookup) error {
+	if definition.Steps == nil {
+		return nil
+	}
+
+	var found violations
+	for _, step := range *definition.Steps {
+		if step.Type != models.StepTypeTask || step.TaskConfig == nil {
+			continue
+		}
+
+		taskID := step.TaskConfig.TaskDefinitionID
+		schemas, exists := lookup(taskID)
+		if !exists {
+			found.add(step.StepDefinitionID, "taskConfig.taskDefinitionId", "no agent supports task %s", taskID)
+			continue
+		}
+		input := jsonSchema{root: schemas.Input, node: schemas.Input}
+
+		params := models.StepDefinitionParameters{}
+		if step.Parameters != nil {
+			params = *step.Parameters
+		}
+
+		for _, name := range input.required() {
+			if _, set := params[name]; !set {
+				found.add(step.StepDefinitionID, "parameters."+name, "missing required input %s of task %s", name, taskID)
+			}
+		}
+
+		names := make([]string, 0, len(params))
+		for name := range params {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			field := "parameters." + name
+			target, declared := input.property(name)
+			if !declared {
+				found.add(step.StepDefinitionID, field, "task %s has no input %s", taskID, name)
+				continue
+			}
+
+			source, err := parameterSchema(definition, params[name], lookup)
+			if err != nil {
+				found.add(step.StepDefinitionID, field, "%v", err)
+				continue
+			}
+			if !compatibleTypes(source, target.types()) {
+				found.add(step.StepDefinitionID, field, "expected %s for input %s of task %s, got %s",
+					strings.Join(target.types(), " or "), name, taskID, strings.Join(source, " or "))
+			}
+		}
+	}
+	return found.err()
+}
+
+// parameterSchema returns the types a parameter can take, or nil if they are
+// unknown.
+func parameterSchema(definition *models.WorkflowDefinition, param models.StepDefinitionParameter, lookup TaskSchemaLookup) ([]string, error) {
+	switch param.Type {
+	case models.StepParameterTypeConstant:
+		return []string{valueType(param.Value)}, nil
+	case models.StepParameterTypeWorkflow:
+		raw, _ := param.Value.(string)
+		path, err := resolver.ParsePath(raw)
+		if err != nil || len(path) != 1 || definition.InputParameters == nil {
+			return nil, err
+		}
+		for _, input := range *definition.InputParameters {
+			if input.Name == path[0].Key && input.Type != "" {
+				return []string{input.Type}, nil
+			}
+		}
+		return nil, nil
+	case models.StepParameterTypeTaskOutput:
+		ref, err := resolver.ParseTaskOutputReference(param.Value)
+		if err != nil {
+			return nil, err
+		}
+		upstream, exists := definition.GetStepByID(ref.StepID)
+		if !exists || upstream.Type != models.StepTypeTask || upstream.TaskConfig == nil {
+			return nil, nil
+		}
+		schemas, exists := lookup(upstream.TaskConfig.TaskDefinitionID)
+		if !exists {
+			return nil, nil
+		}
+
+		output := jsonSchema{root: schemas.Output, node: schemas.Output}
+		for i, segment := range ref.Path {
+			var declared bool
+			if segment.IsIndex {
+				output, declared = output.items()
+			} else {
+				output, declared = output.property(segment.Key)
+			}
+			if !declared {
+				return nil, fmt.Errorf("output of task %s has no field %s", upstream.TaskConfig.TaskDefinitionID, ref.Path[:i+1])
+			}
+		}
+		return output.types(), nil
+	default:
+		return nil, nil
+	}
+}
+
+// compatibleTypes reports whether a value of one of the source types can be
+// accepted by one of the target types. Unknown types are compatible with any
+// other, and integers and numbers with each other, since JSON does not tell
+// them apart.
+func compatibleTypes(source, target []string) bool {
+	if len(source) == 0 || len(target) == 0 {
+		return true
+	}
+	for _, s := range source {
+		for _, t := range target {
+			if s == t || (s == "integer" && t == "number") || (s == "number" && t == "integer") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func valueType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// jsonSchema is a node of a JSON Schema, with the root it belongs to so local
+// references can be followed. A nil node accepts anything.
+type jsonSchema struct {
+	root map[string]interface{}
+	node map[string]interface{}
+}
+
+// resolved follows the local reference of the node, if any.
+func (s jsonSchema) resolved() jsonSchema {
+	for range 32 {
+		ref, ok := s.node["$ref"].(string)
+		if !ok {
+			return s
+		}
+		var target interface{} = s.root
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			object, _ := target.(map[string]interface{})
+			target = object[key]
+		}
+		node, _ := target.(map[string]interface{})
+		s = jsonSchema{root: s.root, node: node}
+	}
+	return jsonSchema{root: s.root}
+}
+
+// types returns the types the node accepts, or nil if it accepts any.
+func (s jsonSchema) types() []string {
+	switch t := s.resolved().node["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+func (s jsonSchema) required() []string {
+	required, _ := s.resolved().node["required"].([]interface{})
+	names := make([]string, 0, len(required))
+	for _, name := range required {
+		if name, ok := name.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// property returns the schema of an object property, and whether the node
+// accepts it. Objects declaring their properties only accept those unless they
+// explicitly allow additional ones, so misspelled names are caught.
+func (s jsonSchema) property(name string) (jsonSchema, bool) {
+	node := s.resolved()
+	if node.node == nil {
+		return jsonSchema{root: s.root}, true
+	}
+	properties, _ := node.node["properties"].(map[string]interface{})
+	if property, ok := properties[name].(map[string]interface{}); ok {
+		return jsonSchema{root: s.root, node: property}, true
+	}
+	switch additional := node.node["additionalProperties"].(type) {
+	case bool:
+		return jsonSchema{root: s.root}, additional
+	case map[string]interface{}:
+		return jsonSchema{root: s.root, node: additional}, true
+	}
+	return jsonSchema{root: s.root}, properties == nil
+}
+
+// items returns the schema of the elements of an array node.
+func (s jsonSchema) items() (jsonSchema, bool) {
+	node := s.resolved()
+	if node.node == nil {
+		return jsonSchema{root: s.root}, true
+	}
+	if types := node.types(); len(types) > 0 && !slices.Contains(types, "array") {
+		return jsonSchema{root: s.root}, false
+	}
+	items, _ := node.node["items"].(map[string]interface{})
+	return jsonSchema{root: s.root, node: items}, true
+}
@@ -0,0 +1,113 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+func schemaOf(t *testing.T, source string) map[string]interface{} {
+	t.Helper()
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(source), &schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func testTaskSchemas(t *testing.T) TaskSchemaLookup {
+	schemas := map[string]*TaskSchemas{
+		"fetch-order": {
+			Input: schemaOf(t, `{"type": "object", "properties": {"orderId": {"type": "string"}}, "required": ["orderId"]}`),
+			Output: schemaOf(t, `{
+				"type": "object",
+				"definitions": {"Item": {"type": "object", "properties": {"sku": {"type": "string"}, "quantity": {"type": "integer"}}}},
+				"properties": {"items": {"type": ["array", "null"], "items": {"$ref": "#/definitions/Item"}}}
+			}`),
+		},
+		"reserve": {
+			Input: schemaOf(t, `{
+				"type": "object",
+				"properties": {"sku": {"type": "string"}, "quantity": {"type": "number"}, "express": {"type": "boolean"}},
+				"required": ["sku", "quantity"]
+			}`),
+		},
+	}
+	return func(taskID string) (*TaskSchemas, bool) {
+		taskSchemas, found := schemas[taskID]
+		return taskSchemas, found
+	}
+}
+
+func taskStepWith(id, taskID string, params models.StepDefinitionParameters) models.WorkflowStepDefinition {
+	step := taskStep(id, nil)
+	step.TaskConfig.TaskDefinitionID = taskID
+	step.Parameters = &params
+	return step
+}
+
+func expectSchemaViolations(t *testing.T, definition *models.WorkflowDefinition, expected ...Violation) {
+	t.Helper()
+	err := ValidateTaskSchemas(definition, testTaskSchemas(t))
+	if len(expected) == 0 {
+		if err != nil {
+			t.Fatalf("expected the parameters to type-check, got %v", err)
+		}
+		return
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Violations) != len(expected) {
+		t.Fatalf("expected %d violations, got %v", len(expected), err)
+	}
+	for i, want := range expected {
+		if got := validationErr.Violations[i]; got.StepID != want.StepID || got.Field != want.Field {
+			t.Fatalf("expected violation %d on step %q field %q, got %+v", i, want.StepID, want.Field, got)
+		}
+	}
+}
+
+func TestValidateTaskSchemas(t *testing.T) {
+	inputs := &models.WorkflowParameterDefinitionList{
+		{Name: "orderId", Type: "string"},
+		{Name: "express", Type: "string"},
+	}
+
+	t.Run("valid wiring", func(t *testing.T) {
+		definition := definitionOf(
+			taskStepWith("fetch", "fetch-order", models.StepDefinitionParameters{
+				"orderId": {Type: models.StepParameterTypeWorkflow, Value: "orderId"},
+			}),
+			taskStepWith("reserve", "reserve", models.StepDefinitionParameters{
+				"sku":      {Type: models.StepParameterTypeTaskOutput, Value: "fetch.items[0].sku"},
+				"quantity": {Type: models.StepParameterTypeTaskOutput, Value: "fetch.items[0].quantity"},
+			}),
+		)
+		definition.InputParameters = inputs
+		expectSchemaViolations(t, definition)
+	})
+
+	t.Run("wiring mistakes", func(t *testing.T) {
+		definition := definitionOf(
+			taskStepWith("fetch", "fetch-order", models.StepDefinitionParameters{}),
+			taskStepWith("reserve", "reserve", models.StepDefinitionParameters{
+				"sku":      {Type: models.StepParameterTypeTaskOutput, Value: "fetch.items[0].name"},
+				"quantity": {Type: models.StepParameterTypeConstant, Value: "two"},
+				"express":  {Type: models.StepParameterTypeWorkflow, Value: "express"},
+				"priority": {Type: models.StepParameterTypeConstant, Value: 1.0},
+			}),
+			taskStepWith("notify", "notify", nil),
+		)
+		definition.InputParameters = inputs
+		expectSchemaViolations(t, definition,
+			Violation{StepID: "fetch", Field: "parameters.orderId"},
+			Violation{StepID: "reserve", Field: "parameters.express"},
+			Violation{StepID: "reserve", Field: "parameters.priority"},
+			Violation{StepID: "reserve", Field: "parameters.quantity"},
+			Violation{StepID: "reserve", Field: "parameters.sku"},
+			Violation{StepID: "notify", Field: "taskConfig.taskDefinitionId"},
+		)
+	})
+}
@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+)
+
+// Violation is a problem found in a workflow definition. StepID names the
+// failing step, and Field the JSON path of the failing field within the step,
+// or within the definition when StepID is empty.
+type Violation struct {
+	StepID  string `json:"stepId,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+} // @name ValidationViolation
+
+func (v Violation) String() string {
+	var location []string
+	if v.StepID != "" {
+		location = append(location, "step "+v.StepID)
+	}
+	if v.Field != "" {
+		location = append(location, v.Field)
+	}
+	if len(location) == 0 {
+		return v.Message
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(location, ": "), v.Message)
+}
+
+// ValidationError lists every violation found in a workflow definition. It
+// matches errors.ErrInvalidWorkflowDefinition.
+type ValidationError struct {
+	Violations []Violation `json:"violations"`
+} // @name ValidationError
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.String())
+	}
+	return fmt.Sprintf("%s: %s", errors.ErrInvalidWorkflowDefinition, strings.Join(messages, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return errors.ErrInvalidWorkflowDefinition
+}
+
+// violations collects the violations of a workflow definition.
+type violations []Violation
+
+func (v *violations) add(stepID, field, format string, args ...interface{}) {
+	*v = append(*v, Violation{
+		StepID:  stepID,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// err returns the collected violations as a ValidationError, or nil if there
+// is none.
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v}
+}
@@ -0,0 +1,254 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/condition"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/resolver"
+)
+
+// ValidateWorkflowDefinition checks that a workflow definition can be
+// executed: timeouts are positive, step IDs are unique, every referenced step
+// exists, the step graph is well-formed, parameter references are well-formed
+// and decision conditions parse and type-check. Every problem found is
+// reported in the returned ValidationError.
+func ValidateWorkflowDefinition(definition *models.WorkflowDefinition) error {
+	if definition.Steps == nil || len(*definition.Steps) == 0 {
+		return (violations{{Field: "steps", Message: errors.ErrWorkflowDefinitionNoSteps.Error()}}).err()
+	}
+
+	var found violations
+	if definition.TimeoutSeconds != nil && *definition.TimeoutSeconds <= 0 {
+		found.add("", "timeoutSeconds", "timeout must be a positive number of seconds")
+	}
+
+	seen := make(map[string]struct{}, len(*definition.Steps))
+	for _, step := range *definition.Steps {
+		if _, exists := seen[step.StepDefinitionID]; exists {
+			found.add(step.StepDefinitionID, "stepDefinitionId", "duplicate step ID")
+		}
+		seen[step.StepDefinitionID] = struct{}{}
+	}
+
+	for _, step := range *definition.Steps {
+		validateStep(definition, step, &found)
+	}
+
+	// The graph checks assume every referenced step exists.
+	if len(found) == 0 {
+		validateGraph(definition, &found)
+	}
+	return found.err()
+}
+
+// DefinitionLookup returns the workflow definition with the given ID, or nil
+// if it does not exist.
+type DefinitionLookup func(id string) (*models.WorkflowDefinition, error)
+
+// ValidateWorkflowReferences checks that the workflows started by workflow
+// steps exist and that no chain of child workflows leads back to the
+// definition itself. Problems found in child workflows are reported on the
+// workflow step of the definition that leads to them.
+func ValidateWorkflowReferences(definition *models.WorkflowDefinition, lookup DefinitionLookup) error {
+	if definition.Steps == nil {
+		return nil
+	}
+
+	var found violations
+	visited := make(map[string]struct{})
+
+	var visit func(root models.WorkflowStepDefinition, steps []models.WorkflowStepDefinition, path []string) error
+	visit = func(root models.WorkflowStepDefinition, steps []models.WorkflowStepDefinition, path []string) error {
+		for _, step := range steps {
+			if step.Type != models.StepTypeWorkflow || step.WorkflowConfig == nil {
+				continue
+			}
+
+			childID := step.WorkflowConfig.WorkflowDefinitionID
+			childPath := append(path[:len(path):len(path)], childID)
+			if childID == definition.ID.String() {
+				found.add(root.StepDefinitionID, "workflowConfig.workflowDefinitionId", "%s: %s", errors.ErrRecursiveWorkflow, strings.Join(childPath, " -> "))
+				continue
+			}
+			if _, seen := visited[childID]; seen {
+				continue
+			}
+			visited[childID] = struct{}{}
+
+			child, err := lookup(childID)
+			if err != nil {
+				return err
+			}
+			if child == nil {
+				found.add(root.StepDefinitionID, "workflowConfig.workflowDefinitionId", "references unknown workflow definition %s", childID)
+				continue
+			}
+			if child.Steps == nil {
+				continue
+			}
+			if err := visit(root, *child.Steps, childPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, step := range *definition.Steps {
+		if step.Type != models.StepTypeWorkflow || step.WorkflowConfig == nil {
+			continue
+		}
+		if err := visit(step, []models.WorkflowStepDefinition{step}, []string{definition.ID.String()}); err != nil {
+			return err
+		}
+	}
+	return found.err()
+}
+
+func validateStep(definition *models.WorkflowDefinition, step models.WorkflowStepDefinition, found *violations) {
+	id := step.StepDefinitionID
+	for _, ref := range step.GetStepReferences() {
+		if _, exists := definition.GetStepByID(ref.StepID); !exists {
+			found.add(id, ref.Field, "references unknown step %s", ref.StepID)
+		}
+	}
+
+	if step.TimeoutSeconds != nil && *step.TimeoutSeconds <= 0 {
+		found.add(id, "timeoutSeconds", "timeout must be a positive number of seconds")
+	}
+
+	if policy := step.RetryPolicy; policy != nil {
+		switch {
+		case policy.MaxAttempts < 1:
+			found.add(id, "retryPolicy.maxAttempts", "retry policy max attempts must be at least 1")
+		case policy.GetInitialIntervalSeconds() < 0 || policy.GetMaxIntervalSeconds() < 0:
+			found.add(id, "retryPolicy", "retry policy intervals must not be negative")
+		case policy.GetMultiplier() < 1:
+			found.add(id, "retryPolicy.multiplier", "retry policy multiplier must be at least 1")
+		case policy.GetJitter() < 0 || policy.GetJitter() > 1:
+			found.add(id, "retryPolicy.jitter", "retry policy jitter must be between 0 and 1")
+		}
+	}
+
+	if step.Parameters != nil {
+		for name, param := range *step.Parameters {
+			if err := validateParameter(definition, param); err != nil {
+				found.add(id, "parameters."+name, "%v", err)
+			}
+		}
+	}
+
+	switch step.Type {
+	case models.StepTypeTask:
+		if step.TaskConfig == nil {
+			found.add(id, "taskConfig", "missing task configuration")
+		}
+	case models.StepTypeWait:
+		if step.WaitConfig == nil {
+			found.add(id, "waitConfig", "missing wait configuration")
+			break
+		}
+		if err := validateParameter(definition, step.WaitConfig.DurationSeconds); err != nil {
+			found.add(id, "waitConfig.durationSeconds", "%v", err)
+		}
+	case models.StepTypeWorkflow:
+		if step.WorkflowConfig == nil {
+			found.add(id, "workflowConfig", "missing workflow configuration")
+			break
+		}
+		if _, err := uuid.Parse(step.WorkflowConfig.WorkflowDefinitionID); err != nil {
+			found.add(id, "workflowConfig.workflowDefinitionId", "invalid workflow definition ID %q", step.WorkflowConfig.WorkflowDefinitionID)
+		}
+	case models.StepTypeFork:
+		if step.ForkConfig == nil {
+			found.add(id, "forkConfig", "missing fork configuration")
+			break
+		}
+		if len(step.ForkConfig.Branches) == 0 {
+			found.add(id, "forkConfig.branches", "fork must have at least one branch")
+		}
+	case models.StepTypeJoin:
+		if step.JoinConfig == nil {
+			found.add(id, "joinConfig", "missing join configuration")
+			break
+		}
+		switch step.JoinConfig.GetPolicy() {
+		case models.JoinPolicyAll, models.JoinPolicyAny, models.JoinPolicyFailFast:
+		case models.JoinPolicyNOfM:
+			count := step.JoinConfig.RequiredCount
+			if count == nil || *count < 1 || *count > len(step.JoinConfig.IncomingStepIDs) {
+				found.add(id, "joinConfig.requiredCount", "required count must be between 1 and the number of incoming steps")
+			}
+		default:
+			found.add(id, "joinConfig.policy", "unknown join policy %q", step.JoinConfig.Policy)
+		}
+	case models.StepTypeDecision:
+		if step.DecisionConfig == nil {
+			found.add(id, "decisionConfig", "missing decision configuration")
+			break
+		}
+		for i, decisionCase := range step.DecisionConfig.Cases {
+			field := fmt.Sprintf("decisionConfig.cases[%d].condition", i)
+			expression, err := condition.Parse(decisionCase.Condition)
+			if err != nil {
+				found.add(id, field, "%v", err)
+				continue
+			}
+			if err := expression.Check(definition); err != nil {
+				found.add(id, field, "%v", err)
+			}
+		}
+	default:
+		found.add(id, "type", "unknown step type %q", step.Type)
+	}
+}
+
+func validateParameter(definition *models.WorkflowDefinition, param models.StepDefinitionParameter) error {
+	switch param.Type {
+	case models.StepParameterTypeConstant:
+		return nil
+	case models.StepParameterTypeWorkflow:
+		raw, ok := param.Value.(string)
+		if !ok {
+			return fmt.Errorf("%w: workflow input reference must be a string", errors.ErrInvalidParameterReference)
+		}
+		path, err := resolver.ParsePath(raw)
+		if err != nil {
+			return err
+		}
+		if len(path) == 0 || path[0].IsIndex || !hasInputParameter(definition, path[0].Key) {
+			return fmt.Errorf("%w: unknown input parameter %q", errors.ErrInvalidParameterReference, raw)
+		}
+		return nil
+	case models.StepParameterTypeTaskOutput:
+		ref, err := resolver.ParseTaskOutputReference(param.Value)
+		if err != nil {
+			return err
+		}
+		if _, exists := definition.GetStepByID(ref.StepID); !exists {
+			return fmt.Errorf("%w: unknown step %s", errors.ErrInvalidParameterReference, ref.StepID)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported parameter type %q", errors.ErrInvalidParameterReference, param.Type)
+	}
+}
+
+func configField(stepType models.StepType) string {
+	return string(stepType) + "Config"
+}
+
+func hasInputParameter(definition *models.WorkflowDefinition, name string) bool {
+	if definition.InputParameters == nil {
+		return false
+	}
+	for _, param := range *definition.InputParameters {
+		if param.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	engineErrors "github.com/paulhalleux/workflow-engine-go/engine-new/internal/errors"
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/models"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func taskStep(id string, next *string) models.WorkflowStepDefinition {
+	return models.WorkflowStepDefinition{
+		StepDefinitionID: id,
+		Name:             id,
+		Type:             models.StepTypeTask,
+		TaskConfig:       &models.TaskConfig{TaskDefinitionID: "echo", NextStepID: next},
+	}
+}
+
+func forkStep(id, join string, branches ...string) models.WorkflowStepDefinition {
+	config := &models.ForkConfig{JoinStepID: join}
+	for _, next := range branches {
+		config.Branches = append(config.Branches, models.ForkBranch{NextStepID: next})
+	}
+	return models.WorkflowStepDefinition{StepDefinitionID: id, Name: id, Type: models.StepTypeFork, ForkConfig: config}
+}
+
+func joinStep(id string, next *string, incoming ...string) models.WorkflowStepDefinition {
+	return models.WorkflowStepDefinition{
+		StepDefinitionID: id,
+		Name:             id,
+		Type:             models.StepTypeJoin,
+		JoinConfig:       &models.JoinConfig{IncomingStepIDs: incoming, NextStepID: next},
+	}
+}
+
+func decisionStep(id, join string, defaultNext *string, cases ...string) models.WorkflowStepDefinition {
+	config := &models.DecisionConfig{JoinStepID: join, DefaultNextStepID: defaultNext}
+	for _, next := range cases {
+		config.Cases = append(config.Cases, models.DecisionCase{Condition: "true", NextStepID: next})
+	}
+	return models.WorkflowStepDefinition{StepDefinitionID: id, Name: id, Type: models.StepTypeDecision, DecisionConfig: config}
+}
+
+func definitionOf(steps ...models.WorkflowStepDefinition) *models.WorkflowDefinition {
+	list := models.WorkflowStepDefinitionList(steps)
+	return &models.WorkflowDefinition{Steps: &list}
+}
+
+// expectViolations checks that validating the definition reports exactly the
+// given violations, compared by step and field.
+func expectViolations(t *testing.T, definition *models.WorkflowDefinition, expected ...Violation) {
+	t.Helper()
+	err := ValidateWorkflowDefinition(definition)
+	if len(expected) == 0 {
+		if err != nil {
+			t.Fatalf("expected the definition to be valid, got %v", err)
+		}
+		return
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !errors.Is(err, engineErrors.ErrInvalidWorkflowDefinition) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if len(validationErr.Violations) != len(expected) {
+		t.Fatalf("expected %d violations, got %v", len(expected), validationErr.Violations)
+	}
+	for i, want := range expected {
+		got := validationErr.Violations[i]
+		if got.StepID != want.StepID || got.Field != want.Field {
+			t.Fatalf("expected violation %d on step %q field %q, got %+v", i, want.StepID, want.Field, got)
+		}
+	}
+}
+
+func TestValidateValidGraphs(t *testing.T) {
+	expectViolations(t, definitionOf(
+		taskStep("start", ptr("fork")),
+		forkStep("fork", "join", "a", "b"),
+		taskStep("a", ptr("a2")),
+		taskStep("a2", nil),
+		taskStep("b", nil),
+		joinStep("join", ptr("decide"), "a2", "b"),
+		decisionStep("decide", "end", ptr("fallback"), "end", "c"),
+		taskStep("c", nil),
+		taskStep("fallback", ptr("end")),
+		taskStep("end", nil),
+	))
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	expectViolations(t, definitionOf(
+		taskStep("a", ptr("missing")),
+		taskStep("a", nil),
+		joinStep("join", nil, "other"),
+	),
+		Violation{StepID: "a", Field: "stepDefinitionId"},
+		Violation{StepID: "a", Field: "taskConfig.nextStepId"},
+		Violation{StepID: "join", Field: "joinConfig.incomingStepIds[0]"},
+	)
+}
+
+func TestValidateGraphShape(t *testing.T) {
+	t.Run("cycle", func(t *testing.T) {
+		expectViolations(t, definitionOf(
+			taskStep("a", ptr("b")),
+			taskStep("b", ptr("a")),
+		), Violation{StepID: "b", Field: "taskConfig.nextStepId"})
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		expectViolations(t, definitionOf(
+			taskStep("a", nil),
+			taskStep("orphan", nil),
+		), Violation{StepID: "orphan"})
+	})
+
+	t.Run("fork without join step", func(t *testing.T) {
+		expectViolations(t, definitionOf(
+			forkStep("fork", "end", "a"),
+			taskStep("a", nil),
+			taskStep("end", nil),
+		), Violation{StepID: "fork", Field: "forkConfig.joinStepId"})
+	})
+
+	t.Run("fork branch leaving its scope", func(t *testing.T) {
+		expectViolations(t, definitionOf(
+			forkStep("fork", "join", "a", "b"),
+			taskStep("a", ptr("end")),
+			taskStep("b", nil),
+			joinStep("join", ptr("end"), "a", "b"),
+			taskStep("end", nil),
+		), Violation{StepID: "fork", Field: "forkConfig.branches[0].nextStepId"})
+	})
+
+	t.Run("join waiting for a step outside the fork", func(t *testing.T) {
+		expectViolations(t, definitionOf(
+			taskStep("start", ptr("fork")),
+			forkStep("fork", "join", "a", "b"),
+			taskStep("a", nil),
+			taskStep("b", nil),
+			joinStep("join", nil, "a", "start"),
+		),
+			Violation{StepID: "fork", Field: "forkConfig.branches[1].nextStepId"},
+			Violation{StepID: "join", Field: "joinConfig.incomingStepIds[1]"},
+		)
+	})
+
+	t.Run("decision case not converging", func(t *testing.T) {
+		expectViolations(t, definitionOf(
+			decisionStep("decide", "join", nil, "a"),
+			taskStep("a", ptr("after")),
+			taskStep("join", ptr("after")),
+			taskStep("after", nil),
+		), Violation{StepID: "decide", Field: "decisionConfig.cases[0].nextStepId"})
+	})
+
+	t.Run("orphan join step", func(t *testing.T) {
+		expectViolations(t, definitionOf(
+			taskStep("a", ptr("join")),
+			joinStep("join", nil, "a"),
+		), Violation{StepID: "join"})
+	})
+}
@@ -68,7 +68,22 @@ func (c *Connection) SendMessage(msg *proto.WebsocketMessage) error {
 	}
 }
 
+// Done is closed once the connection is closing.
+func (c *Connection) Done() <-chan struct{} {
+	return c.ctx.Done()
+}
+
+func (c *Connection) trySend(msg *proto.WebsocketMessage) {
+	select {
+	case c.send <- msg:
+	default:
+		log.Printf("websocket connection %s is too slow, dropping message", c.id)
+	}
+}
+
 func (c *Connection) ResetScopes() {
+	c.scopesMu.Lock()
+	defer c.scopesMu.Unlock()
 	c.scopes = make(map[proto.WebsocketScopeType][]string)
 }
 
@@ -109,6 +124,7 @@ func (c *Connection) IsSubscribedTo(scopeType proto.WebsocketScopeType, scopeID
 
 func (c *Connection) readPump() {
 	defer c.wg.Done()
+	defer c.cancelFunc()
 	for {
 		select {
 		case <-c.ctx.Done():
@@ -153,6 +169,7 @@ func (c *Connection) readPump() {
 
 func (c *Connection) writePump() {
 	defer c.wg.Done()
+	defer c.cancelFunc()
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
@@ -0,0 +1,87 @@
+package ws
+
+import (
+	"context"
+
+	"github.com/paulhalleux/workflow-engine-go/engine-new/internal/registry"
+	"github.com/paulhalleux/workflow-engine-go/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// NewTaskInstanceProgressMessage builds the event sent to the clients
+// subscribed to a task instance when its task reports progress.
+func NewTaskInstanceProgressMessage(taskInstanceID string, progress float32, message *string, partialOutput map[string]interface{}) (*proto.WebsocketMessage, error) {
+	details := &proto.TaskInstanceProgressDetails{
+		Progress: progress,
+		Message:  message,
+	}
+	if partialOutput != nil {
+		output, err := structpb.NewStruct(partialOutput)
+		if err != nil {
+			return nil, err
+		}
+		details.PartialOutput = output
+	}
+
+	return &proto.WebsocketMessage{
+		Type: proto.WEBSOCKET_MESSAGE_TYPE_TASK_INSTANCE_EVENT,
+		Scope: &proto.WebsocketScope{
+			Type: proto.WEBSOCKET_SCOPE_TYPE_TASK_INSTANCE,
+			Id:   &taskInstanceID,
+		},
+		Payload: &proto.WebsocketMessage_TaskInstanceEvent{
+			TaskInstanceEvent: &proto.TaskInstanceEvent{
+				TaskInstanceId: taskInstanceID,
+				EventType:      proto.TASK_INSTANCE_EVENT_TYPE_PROGRESS,
+				Details: &proto.TaskInstanceEvent_ProgressDetails{
+					ProgressDetails: details,
+				},
+			},
+		},
+	}, nil
+}
+
+// NewAgentMessage builds the event sent to the clients subscribed to agents
+// when an agent registers, unregisters or changes status.
+func NewAgentMessage(event registry.AgentEvent) *proto.WebsocketMessage {
+	eventType := proto.AGENT_EVENT_TYPE_UNSPECIFIED
+	switch event.Type {
+	case registry.AgentEventRegistered:
+		eventType = proto.AGENT_EVENT_TYPE_REGISTERED
+	case registry.AgentEventUnregistered:
+		eventType = proto.AGENT_EVENT_TYPE_UNREGISTERED
+	case registry.AgentEventStatusChanged:
+		eventType = proto.AGENT_EVENT_TYPE_STATUS_CHANGED
+	}
+
+	return &proto.WebsocketMessage{
+		Type: proto.WEBSOCKET_MESSAGE_TYPE_AGENT_EVENT,
+		Scope: &proto.WebsocketScope{
+			Type: proto.WEBSOCKET_SCOPE_TYPE_AGENT,
+		},
+		Payload: &proto.WebsocketMessage_AgentEvent{
+			AgentEvent: &proto.AgentEvent{
+				AgentName: event.Agent.Name,
+				EventType: eventType,
+				Status:    string(event.Agent.Status),
+			},
+		},
+	}
+}
+
+// BroadcastAgentEvents forwards the changes published by the agent registry
+// to the clients subscribed to agents, until the context is done or the
+// subscription ends.
+func (s *Server) BroadcastAgentEvents(ctx context.Context, events <-chan registry.AgentEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			s.Broadcast(NewAgentMessage(event))
+		}
+	}
+}
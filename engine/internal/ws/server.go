@@ -2,17 +2,27 @@ package ws
 
 import (
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/websocket"
+	"github.com/paulhalleux/workflow-engine-go/proto"
 )
 
 type WebsocketServer interface {
 	HandleWebSocket(w http.ResponseWriter, r *http.Request)
 }
 
+// Broadcaster sends a message to every client subscribed to its scope.
+type Broadcaster interface {
+	Broadcast(msg *proto.WebsocketMessage)
+}
+
 type Server struct {
 	Upgrader websocket.Upgrader
 	Registry *Registry
+
+	mu          sync.RWMutex
+	connections map[*Connection]struct{}
 }
 
 func NewServer() *Server {
@@ -22,7 +32,8 @@ func NewServer() *Server {
 			WriteBufferSize: 1024,
 			CheckOrigin:     func(r *http.Request) bool { return true },
 		},
-		Registry: NewRegistry(),
+		Registry:    NewRegistry(),
+		connections: make(map[*Connection]struct{}),
 	}
 }
 
@@ -34,5 +45,30 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	conn := NewConnection(r.RemoteAddr, wsConn, s.Registry)
+	s.mu.Lock()
+	s.connections[conn] = struct{}{}
+	s.mu.Unlock()
 	conn.Start()
+
+	go func() {
+		<-conn.Done()
+		s.mu.Lock()
+		delete(s.connections, conn)
+		s.mu.Unlock()
+		conn.Stop()
+	}()
+}
+
+// Broadcast sends a message to the connections subscribed to its scope. A
+// connection too slow to keep up misses the message rather than blocking the
+// caller.
+func (s *Server) Broadcast(msg *proto.WebsocketMessage) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for conn := range s.connections {
+		if conn.IsSubscribedTo(msg.Scope.GetType(), msg.Scope.Id) {
+			conn.trySend(msg)
+		}
+	}
 }
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS workflow_instances;
+DROP INDEX IF EXISTS idx_workflow_instances_workflow_definition_id;
+DROP INDEX IF EXISTS idx_workflow_instances_status;
+DROP INDEX IF EXISTS idx_workflow_instances_created_at;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS workflow_instances (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workflow_definition_id UUID NOT NULL REFERENCES workflow_definitions (id) ON DELETE CASCADE,
+    workflow_definition_version VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    input JSONB,
+    output JSONB,
+    current_step_ids JSONB,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_instances_workflow_definition_id ON workflow_instances (workflow_definition_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_instances_status ON workflow_instances (status);
+CREATE INDEX IF NOT EXISTS idx_workflow_instances_created_at ON workflow_instances (created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS step_instances;
+DROP INDEX IF EXISTS idx_step_instances_status;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS step_instances (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workflow_instance_id UUID NOT NULL REFERENCES workflow_instances (id) ON DELETE CASCADE,
+    step_definition_id VARCHAR(255) NOT NULL,
+    step_type VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    parent_step_instance_id UUID REFERENCES step_instances (id) ON DELETE CASCADE,
+    input JSONB,
+    output JSONB,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    UNIQUE (workflow_instance_id, step_definition_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_step_instances_status ON step_instances (status);
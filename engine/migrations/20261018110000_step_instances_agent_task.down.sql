@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_step_instances_agent_task_id;
+
+ALTER TABLE step_instances DROP COLUMN IF EXISTS agent_task_id;
+ALTER TABLE step_instances DROP COLUMN IF EXISTS agent_name;
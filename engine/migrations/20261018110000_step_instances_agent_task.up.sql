@@ -0,0 +1,4 @@
+ALTER TABLE step_instances ADD COLUMN IF NOT EXISTS agent_name VARCHAR(255);
+ALTER TABLE step_instances ADD COLUMN IF NOT EXISTS agent_task_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_step_instances_agent_task_id ON step_instances (agent_task_id);
@@ -0,0 +1,2 @@
+ALTER TABLE step_instances DROP COLUMN IF EXISTS progress;
+ALTER TABLE step_instances DROP COLUMN IF EXISTS agent_task_status;
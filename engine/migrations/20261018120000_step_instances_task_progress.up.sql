@@ -0,0 +1,2 @@
+ALTER TABLE step_instances ADD COLUMN IF NOT EXISTS agent_task_status VARCHAR(50);
+ALTER TABLE step_instances ADD COLUMN IF NOT EXISTS progress REAL;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS timers;
+DROP INDEX IF EXISTS idx_timers_workflow_instance_id;
+DROP INDEX IF EXISTS idx_timers_step_instance_id;
+DROP INDEX IF EXISTS idx_timers_pending_fire_at;
+DROP INDEX IF EXISTS idx_timers_claimed_claimed_at;
+DROP INDEX IF EXISTS idx_timers_pending_step_type;
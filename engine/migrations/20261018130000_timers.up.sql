@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS timers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workflow_instance_id UUID NOT NULL REFERENCES workflow_instances (id) ON DELETE CASCADE,
+    step_instance_id UUID NOT NULL REFERENCES step_instances (id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    fire_at TIMESTAMPTZ NOT NULL,
+    claimed_at TIMESTAMPTZ,
+    fired_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_timers_workflow_instance_id ON timers (workflow_instance_id);
+CREATE INDEX IF NOT EXISTS idx_timers_step_instance_id ON timers (step_instance_id);
+CREATE INDEX IF NOT EXISTS idx_timers_pending_fire_at ON timers (fire_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_timers_claimed_claimed_at ON timers (claimed_at) WHERE status = 'claimed';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_timers_pending_step_type ON timers (step_instance_id, type) WHERE status = 'pending';
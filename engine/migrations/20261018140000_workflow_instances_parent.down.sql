@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_workflow_instances_parent_step_instance_id;
+DROP INDEX IF EXISTS idx_workflow_instances_parent_workflow_instance_id;
+
+ALTER TABLE workflow_instances DROP COLUMN IF EXISTS parent_step_instance_id;
+ALTER TABLE workflow_instances DROP COLUMN IF EXISTS parent_workflow_instance_id;
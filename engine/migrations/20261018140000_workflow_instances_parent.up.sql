@@ -0,0 +1,6 @@
+ALTER TABLE workflow_instances
+    ADD COLUMN IF NOT EXISTS parent_workflow_instance_id UUID REFERENCES workflow_instances (id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS parent_step_instance_id UUID REFERENCES step_instances (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_workflow_instances_parent_workflow_instance_id ON workflow_instances (parent_workflow_instance_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_instances_parent_step_instance_id ON workflow_instances (parent_step_instance_id);
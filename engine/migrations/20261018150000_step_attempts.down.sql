@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS step_attempts;
+DROP INDEX IF EXISTS idx_step_attempts_workflow_instance_id;
+DROP INDEX IF EXISTS idx_step_attempts_agent_task_id;
+
+ALTER TABLE step_instances DROP COLUMN IF EXISTS attempt;
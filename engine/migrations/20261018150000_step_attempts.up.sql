@@ -0,0 +1,21 @@
+ALTER TABLE step_instances ADD COLUMN IF NOT EXISTS attempt INT NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS step_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workflow_instance_id UUID NOT NULL REFERENCES workflow_instances (id) ON DELETE CASCADE,
+    step_instance_id UUID NOT NULL REFERENCES step_instances (id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    error TEXT,
+    error_code VARCHAR(255),
+    agent_task_id VARCHAR(255),
+    retry_at TIMESTAMPTZ,
+    started_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (step_instance_id, attempt)
+);
+
+CREATE INDEX IF NOT EXISTS idx_step_attempts_workflow_instance_id ON step_attempts (workflow_instance_id);
+CREATE INDEX IF NOT EXISTS idx_step_attempts_agent_task_id ON step_attempts (agent_task_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_timers_pending_workflow_timeout;
+
+DELETE FROM timers WHERE step_instance_id IS NULL;
+ALTER TABLE timers ALTER COLUMN step_instance_id SET NOT NULL;
+
+ALTER TABLE workflow_definitions DROP COLUMN IF EXISTS timeout_seconds;
@@ -0,0 +1,5 @@
+ALTER TABLE workflow_definitions ADD COLUMN IF NOT EXISTS timeout_seconds INTEGER;
+
+ALTER TABLE timers ALTER COLUMN step_instance_id DROP NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_timers_pending_workflow_timeout ON timers (workflow_instance_id) WHERE status = 'pending' AND type = 'workflowTimeout';
@@ -0,0 +1,2 @@
+ALTER TABLE step_instances DROP COLUMN IF EXISTS partial_output;
+ALTER TABLE step_instances DROP COLUMN IF EXISTS progress_message;
@@ -0,0 +1,2 @@
+ALTER TABLE step_instances ADD COLUMN IF NOT EXISTS progress_message TEXT;
+ALTER TABLE step_instances ADD COLUMN IF NOT EXISTS partial_output JSONB;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS agent_registrations;
+DROP TABLE IF EXISTS agents;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS agents (
+    name VARCHAR(255) PRIMARY KEY,
+    version VARCHAR(50) NOT NULL,
+    address VARCHAR(255),
+    port VARCHAR(50) NOT NULL,
+    protocol VARCHAR(50) NOT NULL,
+    weight INTEGER NOT NULL DEFAULT 1,
+    supported_tasks JSONB NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS agent_registrations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_name VARCHAR(255) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    previous_version VARCHAR(50),
+    supported_tasks JSONB NOT NULL,
+    contract_changed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_registrations_agent_name ON agent_registrations (agent_name, created_at);
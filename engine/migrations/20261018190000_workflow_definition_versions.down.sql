@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_workflow_definitions_family_version;
+
+ALTER TABLE workflow_definitions DROP COLUMN IF EXISTS family_id;
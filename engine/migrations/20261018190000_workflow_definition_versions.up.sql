@@ -0,0 +1,7 @@
+ALTER TABLE workflow_definitions ADD COLUMN IF NOT EXISTS family_id UUID;
+
+UPDATE workflow_definitions SET family_id = id WHERE family_id IS NULL;
+
+ALTER TABLE workflow_definitions ALTER COLUMN family_id SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workflow_definitions_family_version ON workflow_definitions (family_id, version);
@@ -161,12 +161,15 @@ func (x *TaskDefinition) GetOutputParameters() *structpb.Struct {
 }
 
 type StartTaskRequest struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	TaskName        string                 `protobuf:"bytes,1,opt,name=task_name,json=taskName,proto3" json:"task_name,omitempty"`
-	InputParameters *structpb.Struct       `protobuf:"bytes,2,opt,name=input_parameters,json=inputParameters,proto3" json:"input_parameters,omitempty"`
-	TimeoutSeconds  *int32                 `protobuf:"varint,3,opt,name=timeout_seconds,json=timeoutSeconds,proto3,oneof" json:"timeout_seconds,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	TaskName           string                 `protobuf:"bytes,1,opt,name=task_name,json=taskName,proto3" json:"task_name,omitempty"`
+	InputParameters    *structpb.Struct       `protobuf:"bytes,2,opt,name=input_parameters,json=inputParameters,proto3" json:"input_parameters,omitempty"`
+	TimeoutSeconds     *int32                 `protobuf:"varint,3,opt,name=timeout_seconds,json=timeoutSeconds,proto3,oneof" json:"timeout_seconds,omitempty"`
+	TaskId             *string                `protobuf:"bytes,4,opt,name=task_id,json=taskId,proto3,oneof" json:"task_id,omitempty"`
+	WorkflowInstanceId *string                `protobuf:"bytes,5,opt,name=workflow_instance_id,json=workflowInstanceId,proto3,oneof" json:"workflow_instance_id,omitempty"`
+	Attempt            *int32                 `protobuf:"varint,6,opt,name=attempt,proto3,oneof" json:"attempt,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *StartTaskRequest) Reset() {
@@ -220,6 +223,27 @@ func (x *StartTaskRequest) GetTimeoutSeconds() int32 {
 	return 0
 }
 
+func (x *StartTaskRequest) GetTaskId() string {
+	if x != nil && x.TaskId != nil {
+		return *x.TaskId
+	}
+	return ""
+}
+
+func (x *StartTaskRequest) GetWorkflowInstanceId() string {
+	if x != nil && x.WorkflowInstanceId != nil {
+		return *x.WorkflowInstanceId
+	}
+	return ""
+}
+
+func (x *StartTaskRequest) GetAttempt() int32 {
+	if x != nil && x.Attempt != nil {
+		return *x.Attempt
+	}
+	return 0
+}
+
 type TaskActionRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
@@ -410,12 +434,20 @@ const file_definition_agent_proto_rawDesc = "" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
 	"\vdescription\x18\x03 \x01(\tR\vdescription\x12B\n" +
 	"\x10input_parameters\x18\x04 \x01(\v2\x17.google.protobuf.StructR\x0finputParameters\x12D\n" +
-	"\x11output_parameters\x18\x05 \x01(\v2\x17.google.protobuf.StructR\x10outputParameters\"\xb5\x01\n" +
+	"\x11output_parameters\x18\x05 \x01(\v2\x17.google.protobuf.StructR\x10outputParameters\"\xda\x02\n" +
 	"\x10StartTaskRequest\x12\x1b\n" +
 	"\ttask_name\x18\x01 \x01(\tR\btaskName\x12B\n" +
 	"\x10input_parameters\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x0finputParameters\x12,\n" +
-	"\x0ftimeout_seconds\x18\x03 \x01(\x05H\x00R\x0etimeoutSeconds\x88\x01\x01B\x12\n" +
-	"\x10_timeout_seconds\",\n" +
+	"\x0ftimeout_seconds\x18\x03 \x01(\x05H\x00R\x0etimeoutSeconds\x88\x01\x01\x12\x1c\n" +
+	"\atask_id\x18\x04 \x01(\tH\x01R\x06taskId\x88\x01\x01\x125\n" +
+	"\x14workflow_instance_id\x18\x05 \x01(\tH\x02R\x12workflowInstanceId\x88\x01\x01\x12\x1d\n" +
+	"\aattempt\x18\x06 \x01(\x05H\x03R\aattempt\x88\x01\x01B\x12\n" +
+	"\x10_timeout_secondsB\n" +
+	"\n" +
+	"\b_task_idB\x17\n" +
+	"\x15_workflow_instance_idB\n" +
+	"\n" +
+	"\b_attempt\",\n" +
 	"\x11TaskActionRequest\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\"r\n" +
 	"\x12TaskActionResponse\x12\x17\n" +
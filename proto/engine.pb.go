@@ -168,8 +168,11 @@ type RegisterAgentRequest struct {
 	Port           string                 `protobuf:"bytes,4,opt,name=port,proto3" json:"port,omitempty"`
 	Protocol       AgentProtocol          `protobuf:"varint,5,opt,name=protocol,proto3,enum=engine.AgentProtocol" json:"protocol,omitempty"`
 	SupportedTasks []*TaskDefinition      `protobuf:"bytes,6,rep,name=supported_tasks,json=supportedTasks,proto3" json:"supported_tasks,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	// Share of the tasks the agent receives relative to the other agents
+	// supporting the same tasks, when the engine balances tasks by weight.
+	Weight        *int32 `protobuf:"varint,7,opt,name=weight,proto3,oneof" json:"weight,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *RegisterAgentRequest) Reset() {
@@ -244,6 +247,13 @@ func (x *RegisterAgentRequest) GetSupportedTasks() []*TaskDefinition {
 	return nil
 }
 
+func (x *RegisterAgentRequest) GetWeight() int32 {
+	if x != nil && x.Weight != nil {
+		return *x.Weight
+	}
+	return 0
+}
+
 type RegisterAgentResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -302,6 +312,7 @@ type NotifyTaskStatusRequest struct {
 	Status           TaskStatus             `protobuf:"varint,2,opt,name=status,proto3,enum=agent.TaskStatus" json:"status,omitempty"`
 	OutputParameters *structpb.Struct       `protobuf:"bytes,3,opt,name=output_parameters,json=outputParameters,proto3" json:"output_parameters,omitempty"`
 	Message          string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	ErrorCode        *string                `protobuf:"bytes,5,opt,name=error_code,json=errorCode,proto3,oneof" json:"error_code,omitempty"`
 	unknownFields    protoimpl.UnknownFields
 	sizeCache        protoimpl.SizeCache
 }
@@ -364,10 +375,19 @@ func (x *NotifyTaskStatusRequest) GetMessage() string {
 	return ""
 }
 
+func (x *NotifyTaskStatusRequest) GetErrorCode() string {
+	if x != nil && x.ErrorCode != nil {
+		return *x.ErrorCode
+	}
+	return ""
+}
+
 type NotifyTaskProgressRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
 	Progress      float32                `protobuf:"fixed32,2,opt,name=progress,proto3" json:"progress,omitempty"`
+	Message       *string                `protobuf:"bytes,3,opt,name=message,proto3,oneof" json:"message,omitempty"`
+	PartialOutput *structpb.Struct       `protobuf:"bytes,4,opt,name=partial_output,json=partialOutput,proto3" json:"partial_output,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -416,6 +436,20 @@ func (x *NotifyTaskProgressRequest) GetProgress() float32 {
 	return 0
 }
 
+func (x *NotifyTaskProgressRequest) GetMessage() string {
+	if x != nil && x.Message != nil {
+		return *x.Message
+	}
+	return ""
+}
+
+func (x *NotifyTaskProgressRequest) GetPartialOutput() *structpb.Struct {
+	if x != nil {
+		return x.PartialOutput
+	}
+	return nil
+}
+
 type StartWorkflowRequest struct {
 	state                protoimpl.MessageState `protogen:"open.v1"`
 	WorkflowDefinitionId string                 `protobuf:"bytes,1,opt,name=workflow_definition_id,json=workflowDefinitionId,proto3" json:"workflow_definition_id,omitempty"`
@@ -528,6 +562,110 @@ func (x *StartWorkflowResponse) GetMessage() string {
 	return ""
 }
 
+type WorkflowInstanceActionRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	WorkflowInstanceId string                 `protobuf:"bytes,1,opt,name=workflow_instance_id,json=workflowInstanceId,proto3" json:"workflow_instance_id,omitempty"`
+	Reason             *string                `protobuf:"bytes,2,opt,name=reason,proto3,oneof" json:"reason,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *WorkflowInstanceActionRequest) Reset() {
+	*x = WorkflowInstanceActionRequest{}
+	mi := &file_definition_engine_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WorkflowInstanceActionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WorkflowInstanceActionRequest) ProtoMessage() {}
+
+func (x *WorkflowInstanceActionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_definition_engine_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WorkflowInstanceActionRequest.ProtoReflect.Descriptor instead.
+func (*WorkflowInstanceActionRequest) Descriptor() ([]byte, []int) {
+	return file_definition_engine_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WorkflowInstanceActionRequest) GetWorkflowInstanceId() string {
+	if x != nil {
+		return x.WorkflowInstanceId
+	}
+	return ""
+}
+
+func (x *WorkflowInstanceActionRequest) GetReason() string {
+	if x != nil && x.Reason != nil {
+		return *x.Reason
+	}
+	return ""
+}
+
+type WorkflowInstanceActionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       *string                `protobuf:"bytes,2,opt,name=message,proto3,oneof" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WorkflowInstanceActionResponse) Reset() {
+	*x = WorkflowInstanceActionResponse{}
+	mi := &file_definition_engine_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WorkflowInstanceActionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WorkflowInstanceActionResponse) ProtoMessage() {}
+
+func (x *WorkflowInstanceActionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_definition_engine_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WorkflowInstanceActionResponse.ProtoReflect.Descriptor instead.
+func (*WorkflowInstanceActionResponse) Descriptor() ([]byte, []int) {
+	return file_definition_engine_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WorkflowInstanceActionResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *WorkflowInstanceActionResponse) GetMessage() string {
+	if x != nil && x.Message != nil {
+		return *x.Message
+	}
+	return ""
+}
+
 var File_definition_engine_proto protoreflect.FileDescriptor
 
 const file_definition_engine_proto_rawDesc = "" +
@@ -537,29 +675,38 @@ const file_definition_engine_proto_rawDesc = "" +
 	"\x04name\x18\x01 \x01(\tR\x04name\"3\n" +
 	"\x12EnginePingResponse\x12\x1d\n" +
 	"\n" +
-	"know_agent\x18\x01 \x01(\bR\tknowAgent\"\xf6\x01\n" +
+	"know_agent\x18\x01 \x01(\bR\tknowAgent\"\x9e\x02\n" +
 	"\x14RegisterAgentRequest\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
 	"\aversion\x18\x02 \x01(\tR\aversion\x12\x1d\n" +
 	"\aaddress\x18\x03 \x01(\tH\x00R\aaddress\x88\x01\x01\x12\x12\n" +
 	"\x04port\x18\x04 \x01(\tR\x04port\x121\n" +
 	"\bprotocol\x18\x05 \x01(\x0e2\x15.engine.AgentProtocolR\bprotocol\x12>\n" +
-	"\x0fsupported_tasks\x18\x06 \x03(\v2\x15.agent.TaskDefinitionR\x0esupportedTasksB\n" +
+	"\x0fsupported_tasks\x18\x06 \x03(\v2\x15.agent.TaskDefinitionR\x0esupportedTasks\x12\x1b\n" +
+	"\x06weight\x18\a \x01(\x05H\x01R\x06weight\x88\x01\x01B\n" +
 	"\n" +
-	"\b_address\"\\\n" +
+	"\b_addressB\t\n" +
+	"\a_weight\"\\\n" +
 	"\x15RegisterAgentResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1d\n" +
 	"\amessage\x18\x02 \x01(\tH\x00R\amessage\x88\x01\x01B\n" +
 	"\n" +
-	"\b_message\"\xbd\x01\n" +
+	"\b_message\"\xf0\x01\n" +
 	"\x17NotifyTaskStatusRequest\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12)\n" +
 	"\x06status\x18\x02 \x01(\x0e2\x11.agent.TaskStatusR\x06status\x12D\n" +
 	"\x11output_parameters\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x10outputParameters\x12\x18\n" +
-	"\amessage\x18\x04 \x01(\tR\amessage\"P\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12\"\n" +
+	"\n" +
+	"error_code\x18\x05 \x01(\tH\x00R\terrorCode\x88\x01\x01B\r\n" +
+	"\v_error_code\"\xbb\x01\n" +
 	"\x19NotifyTaskProgressRequest\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x1a\n" +
-	"\bprogress\x18\x02 \x01(\x02R\bprogress\"\x90\x01\n" +
+	"\bprogress\x18\x02 \x01(\x02R\bprogress\x12\x1d\n" +
+	"\amessage\x18\x03 \x01(\tH\x00R\amessage\x88\x01\x01\x12>\n" +
+	"\x0epartial_output\x18\x04 \x01(\v2\x17.google.protobuf.StructR\rpartialOutputB\n" +
+	"\n" +
+	"\b_message\"\x90\x01\n" +
 	"\x14StartWorkflowRequest\x124\n" +
 	"\x16workflow_definition_id\x18\x01 \x01(\tR\x14workflowDefinitionId\x12B\n" +
 	"\x10input_parameters\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x0finputParameters\"\xac\x01\n" +
@@ -569,6 +716,15 @@ const file_definition_engine_proto_rawDesc = "" +
 	"\amessage\x18\x03 \x01(\tH\x01R\amessage\x88\x01\x01B\x17\n" +
 	"\x15_workflow_instance_idB\n" +
 	"\n" +
+	"\b_message\"y\n" +
+	"\x1dWorkflowInstanceActionRequest\x120\n" +
+	"\x14workflow_instance_id\x18\x01 \x01(\tR\x12workflowInstanceId\x12\x1b\n" +
+	"\x06reason\x18\x02 \x01(\tH\x00R\x06reason\x88\x01\x01B\t\n" +
+	"\a_reason\"e\n" +
+	"\x1eWorkflowInstanceActionResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1d\n" +
+	"\amessage\x18\x02 \x01(\tH\x00R\amessage\x88\x01\x01B\n" +
+	"\n" +
 	"\b_message*]\n" +
 	"\rAgentProtocol\x12\x1a\n" +
 	"\x16AGENT_PROTOCOL_UNKNOWN\x10\x00\x12\x17\n" +
@@ -576,11 +732,14 @@ const file_definition_engine_proto_rawDesc = "" +
 	"\x13AGENT_PROTOCOL_HTTP\x10\x022\xab\x01\n" +
 	"\vTaskService\x12K\n" +
 	"\x10NotifyTaskStatus\x12\x1f.engine.NotifyTaskStatusRequest\x1a\x16.google.protobuf.Empty\x12O\n" +
-	"\x12NotifyTaskProgress\x12!.engine.NotifyTaskProgressRequest\x1a\x16.google.protobuf.Empty2\xea\x01\n" +
+	"\x12NotifyTaskProgress\x12!.engine.NotifyTaskProgressRequest\x1a\x16.google.protobuf.Empty2\x8c\x04\n" +
 	"\rEngineService\x12L\n" +
 	"\rRegisterAgent\x12\x1c.engine.RegisterAgentRequest\x1a\x1d.engine.RegisterAgentResponse\x12=\n" +
 	"\x04Ping\x12\x19.engine.EnginePingRequest\x1a\x1a.engine.EnginePingResponse\x12L\n" +
-	"\rStartWorkflow\x12\x1c.engine.StartWorkflowRequest\x1a\x1d.engine.StartWorkflowResponseB\tZ\a./protob\x06proto3"
+	"\rStartWorkflow\x12\x1c.engine.StartWorkflowRequest\x1a\x1d.engine.StartWorkflowResponse\x12_\n" +
+	"\x0eCancelWorkflow\x12%.engine.WorkflowInstanceActionRequest\x1a&.engine.WorkflowInstanceActionResponse\x12^\n" +
+	"\rPauseWorkflow\x12%.engine.WorkflowInstanceActionRequest\x1a&.engine.WorkflowInstanceActionResponse\x12_\n" +
+	"\x0eResumeWorkflow\x12%.engine.WorkflowInstanceActionRequest\x1a&.engine.WorkflowInstanceActionResponseB\tZ\a./protob\x06proto3"
 
 var (
 	file_definition_engine_proto_rawDescOnce sync.Once
@@ -595,43 +754,52 @@ func file_definition_engine_proto_rawDescGZIP() []byte {
 }
 
 var file_definition_engine_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_definition_engine_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_definition_engine_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_definition_engine_proto_goTypes = []any{
-	(AgentProtocol)(0),                // 0: engine.AgentProtocol
-	(*EnginePingRequest)(nil),         // 1: engine.EnginePingRequest
-	(*EnginePingResponse)(nil),        // 2: engine.EnginePingResponse
-	(*RegisterAgentRequest)(nil),      // 3: engine.RegisterAgentRequest
-	(*RegisterAgentResponse)(nil),     // 4: engine.RegisterAgentResponse
-	(*NotifyTaskStatusRequest)(nil),   // 5: engine.NotifyTaskStatusRequest
-	(*NotifyTaskProgressRequest)(nil), // 6: engine.NotifyTaskProgressRequest
-	(*StartWorkflowRequest)(nil),      // 7: engine.StartWorkflowRequest
-	(*StartWorkflowResponse)(nil),     // 8: engine.StartWorkflowResponse
-	(*TaskDefinition)(nil),            // 9: agent.TaskDefinition
-	(TaskStatus)(0),                   // 10: agent.TaskStatus
-	(*structpb.Struct)(nil),           // 11: google.protobuf.Struct
-	(*emptypb.Empty)(nil),             // 12: google.protobuf.Empty
+	(AgentProtocol)(0),                     // 0: engine.AgentProtocol
+	(*EnginePingRequest)(nil),              // 1: engine.EnginePingRequest
+	(*EnginePingResponse)(nil),             // 2: engine.EnginePingResponse
+	(*RegisterAgentRequest)(nil),           // 3: engine.RegisterAgentRequest
+	(*RegisterAgentResponse)(nil),          // 4: engine.RegisterAgentResponse
+	(*NotifyTaskStatusRequest)(nil),        // 5: engine.NotifyTaskStatusRequest
+	(*NotifyTaskProgressRequest)(nil),      // 6: engine.NotifyTaskProgressRequest
+	(*StartWorkflowRequest)(nil),           // 7: engine.StartWorkflowRequest
+	(*StartWorkflowResponse)(nil),          // 8: engine.StartWorkflowResponse
+	(*WorkflowInstanceActionRequest)(nil),  // 9: engine.WorkflowInstanceActionRequest
+	(*WorkflowInstanceActionResponse)(nil), // 10: engine.WorkflowInstanceActionResponse
+	(*TaskDefinition)(nil),                 // 11: agent.TaskDefinition
+	(TaskStatus)(0),                        // 12: agent.TaskStatus
+	(*structpb.Struct)(nil),                // 13: google.protobuf.Struct
+	(*emptypb.Empty)(nil),                  // 14: google.protobuf.Empty
 }
 var file_definition_engine_proto_depIdxs = []int32{
 	0,  // 0: engine.RegisterAgentRequest.protocol:type_name -> engine.AgentProtocol
-	9,  // 1: engine.RegisterAgentRequest.supported_tasks:type_name -> agent.TaskDefinition
-	10, // 2: engine.NotifyTaskStatusRequest.status:type_name -> agent.TaskStatus
-	11, // 3: engine.NotifyTaskStatusRequest.output_parameters:type_name -> google.protobuf.Struct
-	11, // 4: engine.StartWorkflowRequest.input_parameters:type_name -> google.protobuf.Struct
-	5,  // 5: engine.TaskService.NotifyTaskStatus:input_type -> engine.NotifyTaskStatusRequest
-	6,  // 6: engine.TaskService.NotifyTaskProgress:input_type -> engine.NotifyTaskProgressRequest
-	3,  // 7: engine.EngineService.RegisterAgent:input_type -> engine.RegisterAgentRequest
-	1,  // 8: engine.EngineService.Ping:input_type -> engine.EnginePingRequest
-	7,  // 9: engine.EngineService.StartWorkflow:input_type -> engine.StartWorkflowRequest
-	12, // 10: engine.TaskService.NotifyTaskStatus:output_type -> google.protobuf.Empty
-	12, // 11: engine.TaskService.NotifyTaskProgress:output_type -> google.protobuf.Empty
-	4,  // 12: engine.EngineService.RegisterAgent:output_type -> engine.RegisterAgentResponse
-	2,  // 13: engine.EngineService.Ping:output_type -> engine.EnginePingResponse
-	8,  // 14: engine.EngineService.StartWorkflow:output_type -> engine.StartWorkflowResponse
-	10, // [10:15] is the sub-list for method output_type
-	5,  // [5:10] is the sub-list for method input_type
-	5,  // [5:5] is the sub-list for extension type_name
-	5,  // [5:5] is the sub-list for extension extendee
-	0,  // [0:5] is the sub-list for field type_name
+	11, // 1: engine.RegisterAgentRequest.supported_tasks:type_name -> agent.TaskDefinition
+	12, // 2: engine.NotifyTaskStatusRequest.status:type_name -> agent.TaskStatus
+	13, // 3: engine.NotifyTaskStatusRequest.output_parameters:type_name -> google.protobuf.Struct
+	13, // 4: engine.NotifyTaskProgressRequest.partial_output:type_name -> google.protobuf.Struct
+	13, // 5: engine.StartWorkflowRequest.input_parameters:type_name -> google.protobuf.Struct
+	5,  // 6: engine.TaskService.NotifyTaskStatus:input_type -> engine.NotifyTaskStatusRequest
+	6,  // 7: engine.TaskService.NotifyTaskProgress:input_type -> engine.NotifyTaskProgressRequest
+	3,  // 8: engine.EngineService.RegisterAgent:input_type -> engine.RegisterAgentRequest
+	1,  // 9: engine.EngineService.Ping:input_type -> engine.EnginePingRequest
+	7,  // 10: engine.EngineService.StartWorkflow:input_type -> engine.StartWorkflowRequest
+	9,  // 11: engine.EngineService.CancelWorkflow:input_type -> engine.WorkflowInstanceActionRequest
+	9,  // 12: engine.EngineService.PauseWorkflow:input_type -> engine.WorkflowInstanceActionRequest
+	9,  // 13: engine.EngineService.ResumeWorkflow:input_type -> engine.WorkflowInstanceActionRequest
+	14, // 14: engine.TaskService.NotifyTaskStatus:output_type -> google.protobuf.Empty
+	14, // 15: engine.TaskService.NotifyTaskProgress:output_type -> google.protobuf.Empty
+	4,  // 16: engine.EngineService.RegisterAgent:output_type -> engine.RegisterAgentResponse
+	2,  // 17: engine.EngineService.Ping:output_type -> engine.EnginePingResponse
+	8,  // 18: engine.EngineService.StartWorkflow:output_type -> engine.StartWorkflowResponse
+	10, // 19: engine.EngineService.CancelWorkflow:output_type -> engine.WorkflowInstanceActionResponse
+	10, // 20: engine.EngineService.PauseWorkflow:output_type -> engine.WorkflowInstanceActionResponse
+	10, // 21: engine.EngineService.ResumeWorkflow:output_type -> engine.WorkflowInstanceActionResponse
+	14, // [14:22] is the sub-list for method output_type
+	6,  // [6:14] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_definition_engine_proto_init() }
@@ -642,14 +810,18 @@ func file_definition_engine_proto_init() {
 	file_definition_agent_proto_init()
 	file_definition_engine_proto_msgTypes[2].OneofWrappers = []any{}
 	file_definition_engine_proto_msgTypes[3].OneofWrappers = []any{}
+	file_definition_engine_proto_msgTypes[4].OneofWrappers = []any{}
+	file_definition_engine_proto_msgTypes[5].OneofWrappers = []any{}
 	file_definition_engine_proto_msgTypes[7].OneofWrappers = []any{}
+	file_definition_engine_proto_msgTypes[8].OneofWrappers = []any{}
+	file_definition_engine_proto_msgTypes[9].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_definition_engine_proto_rawDesc), len(file_definition_engine_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   8,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
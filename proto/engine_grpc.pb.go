@@ -160,9 +160,12 @@ var TaskService_ServiceDesc = grpc.ServiceDesc{
 }
 
 const (
-	EngineService_RegisterAgent_FullMethodName = "/engine.EngineService/RegisterAgent"
-	EngineService_Ping_FullMethodName          = "/engine.EngineService/Ping"
-	EngineService_StartWorkflow_FullMethodName = "/engine.EngineService/StartWorkflow"
+	EngineService_RegisterAgent_FullMethodName  = "/engine.EngineService/RegisterAgent"
+	EngineService_Ping_FullMethodName           = "/engine.EngineService/Ping"
+	EngineService_StartWorkflow_FullMethodName  = "/engine.EngineService/StartWorkflow"
+	EngineService_CancelWorkflow_FullMethodName = "/engine.EngineService/CancelWorkflow"
+	EngineService_PauseWorkflow_FullMethodName  = "/engine.EngineService/PauseWorkflow"
+	EngineService_ResumeWorkflow_FullMethodName = "/engine.EngineService/ResumeWorkflow"
 )
 
 // EngineServiceClient is the client API for EngineService service.
@@ -172,6 +175,9 @@ type EngineServiceClient interface {
 	RegisterAgent(ctx context.Context, in *RegisterAgentRequest, opts ...grpc.CallOption) (*RegisterAgentResponse, error)
 	Ping(ctx context.Context, in *EnginePingRequest, opts ...grpc.CallOption) (*EnginePingResponse, error)
 	StartWorkflow(ctx context.Context, in *StartWorkflowRequest, opts ...grpc.CallOption) (*StartWorkflowResponse, error)
+	CancelWorkflow(ctx context.Context, in *WorkflowInstanceActionRequest, opts ...grpc.CallOption) (*WorkflowInstanceActionResponse, error)
+	PauseWorkflow(ctx context.Context, in *WorkflowInstanceActionRequest, opts ...grpc.CallOption) (*WorkflowInstanceActionResponse, error)
+	ResumeWorkflow(ctx context.Context, in *WorkflowInstanceActionRequest, opts ...grpc.CallOption) (*WorkflowInstanceActionResponse, error)
 }
 
 type engineServiceClient struct {
@@ -212,6 +218,36 @@ func (c *engineServiceClient) StartWorkflow(ctx context.Context, in *StartWorkfl
 	return out, nil
 }
 
+func (c *engineServiceClient) CancelWorkflow(ctx context.Context, in *WorkflowInstanceActionRequest, opts ...grpc.CallOption) (*WorkflowInstanceActionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WorkflowInstanceActionResponse)
+	err := c.cc.Invoke(ctx, EngineService_CancelWorkflow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *engineServiceClient) PauseWorkflow(ctx context.Context, in *WorkflowInstanceActionRequest, opts ...grpc.CallOption) (*WorkflowInstanceActionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WorkflowInstanceActionResponse)
+	err := c.cc.Invoke(ctx, EngineService_PauseWorkflow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *engineServiceClient) ResumeWorkflow(ctx context.Context, in *WorkflowInstanceActionRequest, opts ...grpc.CallOption) (*WorkflowInstanceActionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WorkflowInstanceActionResponse)
+	err := c.cc.Invoke(ctx, EngineService_ResumeWorkflow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // EngineServiceServer is the server API for EngineService service.
 // All implementations must embed UnimplementedEngineServiceServer
 // for forward compatibility.
@@ -219,6 +255,9 @@ type EngineServiceServer interface {
 	RegisterAgent(context.Context, *RegisterAgentRequest) (*RegisterAgentResponse, error)
 	Ping(context.Context, *EnginePingRequest) (*EnginePingResponse, error)
 	StartWorkflow(context.Context, *StartWorkflowRequest) (*StartWorkflowResponse, error)
+	CancelWorkflow(context.Context, *WorkflowInstanceActionRequest) (*WorkflowInstanceActionResponse, error)
+	PauseWorkflow(context.Context, *WorkflowInstanceActionRequest) (*WorkflowInstanceActionResponse, error)
+	ResumeWorkflow(context.Context, *WorkflowInstanceActionRequest) (*WorkflowInstanceActionResponse, error)
 	mustEmbedUnimplementedEngineServiceServer()
 }
 
@@ -238,6 +277,15 @@ func (UnimplementedEngineServiceServer) Ping(context.Context, *EnginePingRequest
 func (UnimplementedEngineServiceServer) StartWorkflow(context.Context, *StartWorkflowRequest) (*StartWorkflowResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StartWorkflow not implemented")
 }
+func (UnimplementedEngineServiceServer) CancelWorkflow(context.Context, *WorkflowInstanceActionRequest) (*WorkflowInstanceActionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelWorkflow not implemented")
+}
+func (UnimplementedEngineServiceServer) PauseWorkflow(context.Context, *WorkflowInstanceActionRequest) (*WorkflowInstanceActionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseWorkflow not implemented")
+}
+func (UnimplementedEngineServiceServer) ResumeWorkflow(context.Context, *WorkflowInstanceActionRequest) (*WorkflowInstanceActionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeWorkflow not implemented")
+}
 func (UnimplementedEngineServiceServer) mustEmbedUnimplementedEngineServiceServer() {}
 func (UnimplementedEngineServiceServer) testEmbeddedByValue()                       {}
 
@@ -313,6 +361,60 @@ func _EngineService_StartWorkflow_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _EngineService_CancelWorkflow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkflowInstanceActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EngineServiceServer).CancelWorkflow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EngineService_CancelWorkflow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EngineServiceServer).CancelWorkflow(ctx, req.(*WorkflowInstanceActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EngineService_PauseWorkflow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkflowInstanceActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EngineServiceServer).PauseWorkflow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EngineService_PauseWorkflow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EngineServiceServer).PauseWorkflow(ctx, req.(*WorkflowInstanceActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EngineService_ResumeWorkflow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkflowInstanceActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EngineServiceServer).ResumeWorkflow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EngineService_ResumeWorkflow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EngineServiceServer).ResumeWorkflow(ctx, req.(*WorkflowInstanceActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // EngineService_ServiceDesc is the grpc.ServiceDesc for EngineService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -332,6 +434,18 @@ var EngineService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "StartWorkflow",
 			Handler:    _EngineService_StartWorkflow_Handler,
 		},
+		{
+			MethodName: "CancelWorkflow",
+			Handler:    _EngineService_CancelWorkflow_Handler,
+		},
+		{
+			MethodName: "PauseWorkflow",
+			Handler:    _EngineService_PauseWorkflow_Handler,
+		},
+		{
+			MethodName: "ResumeWorkflow",
+			Handler:    _EngineService_ResumeWorkflow_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "definition/engine.proto",
@@ -0,0 +1,204 @@
+package proto
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// The HTTP transport exposes the services as JSON over HTTP, for agents
+// written in languages without good gRPC tooling. Every method is served by a
+// POST on its gRPC full method name, e.g. /agent.AgentService/StartTask. The
+// request and response bodies are the JSON mapping of the proto messages, and
+// errors are answered with the HTTP status matching their gRPC code and a JSON
+// google.rpc.Status body.
+
+const jsonContentType = "application/json"
+
+// httpClientTimeout bounds the calls made over the HTTP transport, including
+// the calls whose context has no deadline. Calls with a shorter deadline end
+// with it.
+const httpClientTimeout = 30 * time.Second
+
+var jsonMarshaler = protojson.MarshalOptions{EmitUnpopulated: true}
+var jsonUnmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// HttpClientConn calls the methods of a service over the HTTP transport. It
+// can be used in place of a gRPC connection by the generated clients, e.g.
+// NewAgentServiceClient(NewHttpClientConn("http://localhost:50051")).
+type HttpClientConn struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHttpClientConn(baseURL string) *HttpClientConn {
+	return &HttpClientConn{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: httpClientTimeout},
+	}
+}
+
+func (c *HttpClientConn) Invoke(ctx context.Context, method string, args any, reply any, _ ...grpc.CallOption) error {
+	body, err := jsonMarshaler.Marshal(args.(protobuf.Message))
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to encode request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+method, bytes.NewReader(body))
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", jsonContentType)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return status.FromContextError(ctxErr).Err()
+		}
+		if urlErr, ok := err.(*url.Error); ok && urlErr.Timeout() {
+			return status.Errorf(codes.DeadlineExceeded, "call to %s timed out: %v", method, err)
+		}
+		return status.Errorf(codes.Unavailable, "failed to call %s: %v", method, err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to read response of %s: %v", method, err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var st spb.Status
+		if err := jsonUnmarshaler.Unmarshal(resBody, &st); err != nil {
+			return status.Errorf(codeFromHttpStatus(res.StatusCode), "%s returned status %d", method, res.StatusCode)
+		}
+		return status.ErrorProto(&st)
+	}
+
+	if err := jsonUnmarshaler.Unmarshal(resBody, reply.(protobuf.Message)); err != nil {
+		return status.Errorf(codes.Internal, "failed to decode response of %s: %v", method, err)
+	}
+	return nil
+}
+
+// NewStream is not supported by the HTTP transport, since none of the services
+// declares a streaming method.
+func (c *HttpClientConn) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Error(codes.Unimplemented, "streams are not supported by the HTTP transport")
+}
+
+// RegisterHttpService registers the methods of a service implementation on mux,
+// the same way grpc.Server.RegisterService does for the gRPC transport, e.g.
+// RegisterHttpService(mux, &AgentService_ServiceDesc, agentService).
+func RegisterHttpService(mux *http.ServeMux, desc *grpc.ServiceDesc, impl any) {
+	for _, method := range desc.Methods {
+		path := fmt.Sprintf("/%s/%s", desc.ServiceName, method.MethodName)
+		mux.Handle("POST "+path, httpMethodHandler(impl, method))
+	}
+}
+
+func httpMethodHandler(impl any, method grpc.MethodDesc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeHttpError(w, status.Errorf(codes.InvalidArgument, "failed to read request: %v", err))
+			return
+		}
+
+		decode := func(v any) error {
+			if err := jsonUnmarshaler.Unmarshal(body, v.(protobuf.Message)); err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+			}
+			return nil
+		}
+
+		res, err := method.Handler(impl, r.Context(), decode, nil)
+		if err != nil {
+			writeHttpError(w, err)
+			return
+		}
+
+		resBody, err := jsonMarshaler.Marshal(res.(protobuf.Message))
+		if err != nil {
+			writeHttpError(w, status.Errorf(codes.Internal, "failed to encode response: %v", err))
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		_, _ = w.Write(resBody)
+	}
+}
+
+func writeHttpError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	body, marshalErr := jsonMarshaler.Marshal(st.Proto())
+	if marshalErr != nil {
+		body = []byte(`{"code":13,"message":"failed to encode error"}`)
+	}
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(httpStatusFromCode(st.Code()))
+	_, _ = w.Write(body)
+}
+
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// codeFromHttpStatus is used when an error response carries no status, e.g.
+// when it was answered by a proxy instead of the service.
+func codeFromHttpStatus(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return codes.Unimplemented
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
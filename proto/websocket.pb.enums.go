@@ -7,12 +7,15 @@ const (
 	WEBSOCKET_SCOPE_TYPE_UNSPECIFIED       = WebsocketScopeType_WEBSOCKET_SCOPE_TYPE_UNSPECIFIED
 	WEBSOCKET_SCOPE_TYPE_WORKFLOW_INSTANCE = WebsocketScopeType_WEBSOCKET_SCOPE_TYPE_WORKFLOW_INSTANCE
 	WEBSOCKET_SCOPE_TYPE_TASK_INSTANCE     = WebsocketScopeType_WEBSOCKET_SCOPE_TYPE_TASK_INSTANCE
+	WEBSOCKET_SCOPE_TYPE_AGENT             = WebsocketScopeType_WEBSOCKET_SCOPE_TYPE_AGENT
 )
 
 const (
 	WEBSOCKET_MESSAGE_TYPE_UNSPECIFIED             = WebsocketMessageType_WEBSOCKET_MESSAGE_TYPE_UNSPECIFIED
 	WEBSOCKET_MESSAGE_TYPE_WORKFLOW_INSTANCE_EVENT = WebsocketMessageType_WEBSOCKET_MESSAGE_TYPE_WORKFLOW_INSTANCE_EVENT
 	WEBSOCKET_MESSAGE_TYPE_CLIENT_REGISTERED       = WebsocketMessageType_WEBSOCKET_MESSAGE_TYPE_CLIENT_REGISTERED
+	WEBSOCKET_MESSAGE_TYPE_TASK_INSTANCE_EVENT     = WebsocketMessageType_WEBSOCKET_MESSAGE_TYPE_TASK_INSTANCE_EVENT
+	WEBSOCKET_MESSAGE_TYPE_AGENT_EVENT             = WebsocketMessageType_WEBSOCKET_MESSAGE_TYPE_AGENT_EVENT
 )
 
 const (
@@ -35,4 +38,12 @@ const (
 	TASK_INSTANCE_EVENT_TYPE_STARTED     = TaskInstanceEventType_TASK_INSTANCE_EVENT_TYPE_STARTED
 	TASK_INSTANCE_EVENT_TYPE_COMPLETED   = TaskInstanceEventType_TASK_INSTANCE_EVENT_TYPE_COMPLETED
 	TASK_INSTANCE_EVENT_TYPE_FAILED      = TaskInstanceEventType_TASK_INSTANCE_EVENT_TYPE_FAILED
+	TASK_INSTANCE_EVENT_TYPE_PROGRESS    = TaskInstanceEventType_TASK_INSTANCE_EVENT_TYPE_PROGRESS
+)
+
+const (
+	AGENT_EVENT_TYPE_UNSPECIFIED    = AgentEventType_AGENT_EVENT_TYPE_UNSPECIFIED
+	AGENT_EVENT_TYPE_REGISTERED     = AgentEventType_AGENT_EVENT_TYPE_REGISTERED
+	AGENT_EVENT_TYPE_UNREGISTERED   = AgentEventType_AGENT_EVENT_TYPE_UNREGISTERED
+	AGENT_EVENT_TYPE_STATUS_CHANGED = AgentEventType_AGENT_EVENT_TYPE_STATUS_CHANGED
 )
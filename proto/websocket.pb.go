@@ -9,6 +9,7 @@ package proto
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -27,6 +28,7 @@ const (
 	WebsocketScopeType_WEBSOCKET_SCOPE_TYPE_UNSPECIFIED       WebsocketScopeType = 0
 	WebsocketScopeType_WEBSOCKET_SCOPE_TYPE_WORKFLOW_INSTANCE WebsocketScopeType = 1
 	WebsocketScopeType_WEBSOCKET_SCOPE_TYPE_TASK_INSTANCE     WebsocketScopeType = 2
+	WebsocketScopeType_WEBSOCKET_SCOPE_TYPE_AGENT             WebsocketScopeType = 3
 )
 
 // Enum value maps for WebsocketScopeType.
@@ -35,11 +37,13 @@ var (
 		0: "WEBSOCKET_SCOPE_TYPE_UNSPECIFIED",
 		1: "WEBSOCKET_SCOPE_TYPE_WORKFLOW_INSTANCE",
 		2: "WEBSOCKET_SCOPE_TYPE_TASK_INSTANCE",
+		3: "WEBSOCKET_SCOPE_TYPE_AGENT",
 	}
 	WebsocketScopeType_value = map[string]int32{
 		"WEBSOCKET_SCOPE_TYPE_UNSPECIFIED":       0,
 		"WEBSOCKET_SCOPE_TYPE_WORKFLOW_INSTANCE": 1,
 		"WEBSOCKET_SCOPE_TYPE_TASK_INSTANCE":     2,
+		"WEBSOCKET_SCOPE_TYPE_AGENT":             3,
 	}
 )
 
@@ -76,6 +80,8 @@ const (
 	WebsocketMessageType_WEBSOCKET_MESSAGE_TYPE_UNSPECIFIED             WebsocketMessageType = 0
 	WebsocketMessageType_WEBSOCKET_MESSAGE_TYPE_WORKFLOW_INSTANCE_EVENT WebsocketMessageType = 1
 	WebsocketMessageType_WEBSOCKET_MESSAGE_TYPE_CLIENT_REGISTERED       WebsocketMessageType = 2
+	WebsocketMessageType_WEBSOCKET_MESSAGE_TYPE_TASK_INSTANCE_EVENT     WebsocketMessageType = 3
+	WebsocketMessageType_WEBSOCKET_MESSAGE_TYPE_AGENT_EVENT             WebsocketMessageType = 4
 )
 
 // Enum value maps for WebsocketMessageType.
@@ -84,11 +90,15 @@ var (
 		0: "WEBSOCKET_MESSAGE_TYPE_UNSPECIFIED",
 		1: "WEBSOCKET_MESSAGE_TYPE_WORKFLOW_INSTANCE_EVENT",
 		2: "WEBSOCKET_MESSAGE_TYPE_CLIENT_REGISTERED",
+		3: "WEBSOCKET_MESSAGE_TYPE_TASK_INSTANCE_EVENT",
+		4: "WEBSOCKET_MESSAGE_TYPE_AGENT_EVENT",
 	}
 	WebsocketMessageType_value = map[string]int32{
 		"WEBSOCKET_MESSAGE_TYPE_UNSPECIFIED":             0,
 		"WEBSOCKET_MESSAGE_TYPE_WORKFLOW_INSTANCE_EVENT": 1,
 		"WEBSOCKET_MESSAGE_TYPE_CLIENT_REGISTERED":       2,
+		"WEBSOCKET_MESSAGE_TYPE_TASK_INSTANCE_EVENT":     3,
+		"WEBSOCKET_MESSAGE_TYPE_AGENT_EVENT":             4,
 	}
 )
 
@@ -233,6 +243,7 @@ const (
 	TaskInstanceEventType_TASK_INSTANCE_EVENT_TYPE_STARTED     TaskInstanceEventType = 1
 	TaskInstanceEventType_TASK_INSTANCE_EVENT_TYPE_COMPLETED   TaskInstanceEventType = 2
 	TaskInstanceEventType_TASK_INSTANCE_EVENT_TYPE_FAILED      TaskInstanceEventType = 3
+	TaskInstanceEventType_TASK_INSTANCE_EVENT_TYPE_PROGRESS    TaskInstanceEventType = 4
 )
 
 // Enum value maps for TaskInstanceEventType.
@@ -242,12 +253,14 @@ var (
 		1: "TASK_INSTANCE_EVENT_TYPE_STARTED",
 		2: "TASK_INSTANCE_EVENT_TYPE_COMPLETED",
 		3: "TASK_INSTANCE_EVENT_TYPE_FAILED",
+		4: "TASK_INSTANCE_EVENT_TYPE_PROGRESS",
 	}
 	TaskInstanceEventType_value = map[string]int32{
 		"TASK_INSTANCE_EVENT_TYPE_UNSPECIFIED": 0,
 		"TASK_INSTANCE_EVENT_TYPE_STARTED":     1,
 		"TASK_INSTANCE_EVENT_TYPE_COMPLETED":   2,
 		"TASK_INSTANCE_EVENT_TYPE_FAILED":      3,
+		"TASK_INSTANCE_EVENT_TYPE_PROGRESS":    4,
 	}
 )
 
@@ -278,6 +291,58 @@ func (TaskInstanceEventType) EnumDescriptor() ([]byte, []int) {
 	return file_definition_websocket_proto_rawDescGZIP(), []int{4}
 }
 
+type AgentEventType int32
+
+const (
+	AgentEventType_AGENT_EVENT_TYPE_UNSPECIFIED    AgentEventType = 0
+	AgentEventType_AGENT_EVENT_TYPE_REGISTERED     AgentEventType = 1
+	AgentEventType_AGENT_EVENT_TYPE_UNREGISTERED   AgentEventType = 2
+	AgentEventType_AGENT_EVENT_TYPE_STATUS_CHANGED AgentEventType = 3
+)
+
+// Enum value maps for AgentEventType.
+var (
+	AgentEventType_name = map[int32]string{
+		0: "AGENT_EVENT_TYPE_UNSPECIFIED",
+		1: "AGENT_EVENT_TYPE_REGISTERED",
+		2: "AGENT_EVENT_TYPE_UNREGISTERED",
+		3: "AGENT_EVENT_TYPE_STATUS_CHANGED",
+	}
+	AgentEventType_value = map[string]int32{
+		"AGENT_EVENT_TYPE_UNSPECIFIED":    0,
+		"AGENT_EVENT_TYPE_REGISTERED":     1,
+		"AGENT_EVENT_TYPE_UNREGISTERED":   2,
+		"AGENT_EVENT_TYPE_STATUS_CHANGED": 3,
+	}
+)
+
+func (x AgentEventType) Enum() *AgentEventType {
+	p := new(AgentEventType)
+	*p = x
+	return p
+}
+
+func (x AgentEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AgentEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_definition_websocket_proto_enumTypes[5].Descriptor()
+}
+
+func (AgentEventType) Type() protoreflect.EnumType {
+	return &file_definition_websocket_proto_enumTypes[5]
+}
+
+func (x AgentEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AgentEventType.Descriptor instead.
+func (AgentEventType) EnumDescriptor() ([]byte, []int) {
+	return file_definition_websocket_proto_rawDescGZIP(), []int{5}
+}
+
 type WebsocketScope struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Type          WebsocketScopeType     `protobuf:"varint,1,opt,name=type,proto3,enum=websocket.WebsocketScopeType" json:"type,omitempty"`
@@ -339,6 +404,7 @@ type WebsocketMessage struct {
 	//	*WebsocketMessage_WorkflowInstanceEvent
 	//	*WebsocketMessage_TaskInstanceEvent
 	//	*WebsocketMessage_ClientRegisteredEvent
+	//	*WebsocketMessage_AgentEvent
 	Payload       isWebsocketMessage_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -422,6 +488,15 @@ func (x *WebsocketMessage) GetClientRegisteredEvent() *ClientRegisteredEvent {
 	return nil
 }
 
+func (x *WebsocketMessage) GetAgentEvent() *AgentEvent {
+	if x != nil {
+		if x, ok := x.Payload.(*WebsocketMessage_AgentEvent); ok {
+			return x.AgentEvent
+		}
+	}
+	return nil
+}
+
 type isWebsocketMessage_Payload interface {
 	isWebsocketMessage_Payload()
 }
@@ -438,12 +513,18 @@ type WebsocketMessage_ClientRegisteredEvent struct {
 	ClientRegisteredEvent *ClientRegisteredEvent `protobuf:"bytes,5,opt,name=client_registered_event,json=clientRegisteredEvent,proto3,oneof"`
 }
 
+type WebsocketMessage_AgentEvent struct {
+	AgentEvent *AgentEvent `protobuf:"bytes,6,opt,name=agent_event,json=agentEvent,proto3,oneof"`
+}
+
 func (*WebsocketMessage_WorkflowInstanceEvent) isWebsocketMessage_Payload() {}
 
 func (*WebsocketMessage_TaskInstanceEvent) isWebsocketMessage_Payload() {}
 
 func (*WebsocketMessage_ClientRegisteredEvent) isWebsocketMessage_Payload() {}
 
+func (*WebsocketMessage_AgentEvent) isWebsocketMessage_Payload() {}
+
 type WebsocketCommand struct {
 	state    protoimpl.MessageState `protogen:"open.v1"`
 	ClientId string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
@@ -905,6 +986,7 @@ type TaskInstanceEvent struct {
 	//	*TaskInstanceEvent_StartedDetails
 	//	*TaskInstanceEvent_CompletedDetails
 	//	*TaskInstanceEvent_FailedDetails
+	//	*TaskInstanceEvent_ProgressDetails
 	Details       isTaskInstanceEvent_Details `protobuf_oneof:"details"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -988,6 +1070,15 @@ func (x *TaskInstanceEvent) GetFailedDetails() *TaskInstanceFailedDetails {
 	return nil
 }
 
+func (x *TaskInstanceEvent) GetProgressDetails() *TaskInstanceProgressDetails {
+	if x != nil {
+		if x, ok := x.Details.(*TaskInstanceEvent_ProgressDetails); ok {
+			return x.ProgressDetails
+		}
+	}
+	return nil
+}
+
 type isTaskInstanceEvent_Details interface {
 	isTaskInstanceEvent_Details()
 }
@@ -1004,12 +1095,18 @@ type TaskInstanceEvent_FailedDetails struct {
 	FailedDetails *TaskInstanceFailedDetails `protobuf:"bytes,5,opt,name=failed_details,json=failedDetails,proto3,oneof"`
 }
 
+type TaskInstanceEvent_ProgressDetails struct {
+	ProgressDetails *TaskInstanceProgressDetails `protobuf:"bytes,6,opt,name=progress_details,json=progressDetails,proto3,oneof"`
+}
+
 func (*TaskInstanceEvent_StartedDetails) isTaskInstanceEvent_Details() {}
 
 func (*TaskInstanceEvent_CompletedDetails) isTaskInstanceEvent_Details() {}
 
 func (*TaskInstanceEvent_FailedDetails) isTaskInstanceEvent_Details() {}
 
+func (*TaskInstanceEvent_ProgressDetails) isTaskInstanceEvent_Details() {}
+
 type TaskInstanceStartedDetails struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -1118,6 +1215,126 @@ func (*TaskInstanceFailedDetails) Descriptor() ([]byte, []int) {
 	return file_definition_websocket_proto_rawDescGZIP(), []int{13}
 }
 
+type TaskInstanceProgressDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Progress      float32                `protobuf:"fixed32,1,opt,name=progress,proto3" json:"progress,omitempty"`
+	Message       *string                `protobuf:"bytes,2,opt,name=message,proto3,oneof" json:"message,omitempty"`
+	PartialOutput *structpb.Struct       `protobuf:"bytes,3,opt,name=partial_output,json=partialOutput,proto3" json:"partial_output,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskInstanceProgressDetails) Reset() {
+	*x = TaskInstanceProgressDetails{}
+	mi := &file_definition_websocket_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskInstanceProgressDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskInstanceProgressDetails) ProtoMessage() {}
+
+func (x *TaskInstanceProgressDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_definition_websocket_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskInstanceProgressDetails.ProtoReflect.Descriptor instead.
+func (*TaskInstanceProgressDetails) Descriptor() ([]byte, []int) {
+	return file_definition_websocket_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TaskInstanceProgressDetails) GetProgress() float32 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *TaskInstanceProgressDetails) GetMessage() string {
+	if x != nil && x.Message != nil {
+		return *x.Message
+	}
+	return ""
+}
+
+func (x *TaskInstanceProgressDetails) GetPartialOutput() *structpb.Struct {
+	if x != nil {
+		return x.PartialOutput
+	}
+	return nil
+}
+
+type AgentEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	EventType     AgentEventType         `protobuf:"varint,2,opt,name=event_type,json=eventType,proto3,enum=websocket.AgentEventType" json:"event_type,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentEvent) Reset() {
+	*x = AgentEvent{}
+	mi := &file_definition_websocket_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentEvent) ProtoMessage() {}
+
+func (x *AgentEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_definition_websocket_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentEvent.ProtoReflect.Descriptor instead.
+func (*AgentEvent) Descriptor() ([]byte, []int) {
+	return file_definition_websocket_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *AgentEvent) GetAgentName() string {
+	if x != nil {
+		return x.AgentName
+	}
+	return ""
+}
+
+func (x *AgentEvent) GetEventType() AgentEventType {
+	if x != nil {
+		return x.EventType
+	}
+	return AgentEventType_AGENT_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *AgentEvent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
 type ClientRegisteredEvent struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
@@ -1127,7 +1344,7 @@ type ClientRegisteredEvent struct {
 
 func (x *ClientRegisteredEvent) Reset() {
 	*x = ClientRegisteredEvent{}
-	mi := &file_definition_websocket_proto_msgTypes[14]
+	mi := &file_definition_websocket_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1139,7 +1356,7 @@ func (x *ClientRegisteredEvent) String() string {
 func (*ClientRegisteredEvent) ProtoMessage() {}
 
 func (x *ClientRegisteredEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_definition_websocket_proto_msgTypes[14]
+	mi := &file_definition_websocket_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1152,7 +1369,7 @@ func (x *ClientRegisteredEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientRegisteredEvent.ProtoReflect.Descriptor instead.
 func (*ClientRegisteredEvent) Descriptor() ([]byte, []int) {
-	return file_definition_websocket_proto_rawDescGZIP(), []int{14}
+	return file_definition_websocket_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *ClientRegisteredEvent) GetClientId() string {
@@ -1166,17 +1383,19 @@ var File_definition_websocket_proto protoreflect.FileDescriptor
 
 const file_definition_websocket_proto_rawDesc = "" +
 	"\n" +
-	"\x1adefinition/websocket.proto\x12\twebsocket\"_\n" +
+	"\x1adefinition/websocket.proto\x12\twebsocket\x1a\x1cgoogle/protobuf/struct.proto\"_\n" +
 	"\x0eWebsocketScope\x121\n" +
 	"\x04type\x18\x01 \x01(\x0e2\x1d.websocket.WebsocketScopeTypeR\x04type\x12\x13\n" +
 	"\x02id\x18\x02 \x01(\tH\x00R\x02id\x88\x01\x01B\x05\n" +
-	"\x03_id\"\x8b\x03\n" +
+	"\x03_id\"\xc5\x03\n" +
 	"\x10WebsocketMessage\x123\n" +
 	"\x04type\x18\x01 \x01(\x0e2\x1f.websocket.WebsocketMessageTypeR\x04type\x12/\n" +
 	"\x05scope\x18\x02 \x01(\v2\x19.websocket.WebsocketScopeR\x05scope\x12Z\n" +
 	"\x17workflow_instance_event\x18\x03 \x01(\v2 .websocket.WorkflowInstanceEventH\x00R\x15workflowInstanceEvent\x12N\n" +
 	"\x13task_instance_event\x18\x04 \x01(\v2\x1c.websocket.TaskInstanceEventH\x00R\x11taskInstanceEvent\x12Z\n" +
-	"\x17client_registered_event\x18\x05 \x01(\v2 .websocket.ClientRegisteredEventH\x00R\x15clientRegisteredEventB\t\n" +
+	"\x17client_registered_event\x18\x05 \x01(\v2 .websocket.ClientRegisteredEventH\x00R\x15clientRegisteredEvent\x128\n" +
+	"\vagent_event\x18\x06 \x01(\v2\x15.websocket.AgentEventH\x00R\n" +
+	"agentEventB\t\n" +
 	"\apayload\"\xc4\x01\n" +
 	"\x10WebsocketCommand\x12\x1b\n" +
 	"\tclient_id\x18\x01 \x01(\tR\bclientId\x123\n" +
@@ -1199,28 +1418,45 @@ const file_definition_websocket_proto_rawDesc = "" +
 	"\x1eWorkflowInstanceUpdatedDetails\"\"\n" +
 	" WorkflowInstanceCompletedDetails\"\x1f\n" +
 	"\x1dWorkflowInstanceFailedDetails\" \n" +
-	"\x1eWorkflowInstanceCreatedDetails\"\x82\x03\n" +
+	"\x1eWorkflowInstanceCreatedDetails\"\xd7\x03\n" +
 	"\x11TaskInstanceEvent\x12(\n" +
 	"\x10task_instance_id\x18\x01 \x01(\tR\x0etaskInstanceId\x12?\n" +
 	"\n" +
 	"event_type\x18\x02 \x01(\x0e2 .websocket.TaskInstanceEventTypeR\teventType\x12P\n" +
 	"\x0fstarted_details\x18\x03 \x01(\v2%.websocket.TaskInstanceStartedDetailsH\x00R\x0estartedDetails\x12V\n" +
 	"\x11completed_details\x18\x04 \x01(\v2'.websocket.TaskInstanceCompletedDetailsH\x00R\x10completedDetails\x12M\n" +
-	"\x0efailed_details\x18\x05 \x01(\v2$.websocket.TaskInstanceFailedDetailsH\x00R\rfailedDetailsB\t\n" +
+	"\x0efailed_details\x18\x05 \x01(\v2$.websocket.TaskInstanceFailedDetailsH\x00R\rfailedDetails\x12S\n" +
+	"\x10progress_details\x18\x06 \x01(\v2&.websocket.TaskInstanceProgressDetailsH\x00R\x0fprogressDetailsB\t\n" +
 	"\adetails\"\x1c\n" +
 	"\x1aTaskInstanceStartedDetails\"\x1e\n" +
 	"\x1cTaskInstanceCompletedDetails\"\x1b\n" +
-	"\x19TaskInstanceFailedDetails\"4\n" +
+	"\x19TaskInstanceFailedDetails\"\xa4\x01\n" +
+	"\x1bTaskInstanceProgressDetails\x12\x1a\n" +
+	"\bprogress\x18\x01 \x01(\x02R\bprogress\x12\x1d\n" +
+	"\amessage\x18\x02 \x01(\tH\x00R\amessage\x88\x01\x01\x12>\n" +
+	"\x0epartial_output\x18\x03 \x01(\v2\x17.google.protobuf.StructR\rpartialOutputB\n" +
+	"\n" +
+	"\b_message\"}\n" +
+	"\n" +
+	"AgentEvent\x12\x1d\n" +
+	"\n" +
+	"agent_name\x18\x01 \x01(\tR\tagentName\x128\n" +
+	"\n" +
+	"event_type\x18\x02 \x01(\x0e2\x19.websocket.AgentEventTypeR\teventType\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\"4\n" +
 	"\x15ClientRegisteredEvent\x12\x1b\n" +
-	"\tclient_id\x18\x01 \x01(\tR\bclientId*\x8e\x01\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId*\xae\x01\n" +
 	"\x12WebsocketScopeType\x12$\n" +
 	" WEBSOCKET_SCOPE_TYPE_UNSPECIFIED\x10\x00\x12*\n" +
 	"&WEBSOCKET_SCOPE_TYPE_WORKFLOW_INSTANCE\x10\x01\x12&\n" +
-	"\"WEBSOCKET_SCOPE_TYPE_TASK_INSTANCE\x10\x02*\xa0\x01\n" +
+	"\"WEBSOCKET_SCOPE_TYPE_TASK_INSTANCE\x10\x02\x12\x1e\n" +
+	"\x1aWEBSOCKET_SCOPE_TYPE_AGENT\x10\x03*\xf8\x01\n" +
 	"\x14WebsocketMessageType\x12&\n" +
 	"\"WEBSOCKET_MESSAGE_TYPE_UNSPECIFIED\x10\x00\x122\n" +
 	".WEBSOCKET_MESSAGE_TYPE_WORKFLOW_INSTANCE_EVENT\x10\x01\x12,\n" +
-	"(WEBSOCKET_MESSAGE_TYPE_CLIENT_REGISTERED\x10\x02*\x8c\x01\n" +
+	"(WEBSOCKET_MESSAGE_TYPE_CLIENT_REGISTERED\x10\x02\x12.\n" +
+	"*WEBSOCKET_MESSAGE_TYPE_TASK_INSTANCE_EVENT\x10\x03\x12&\n" +
+	"\"WEBSOCKET_MESSAGE_TYPE_AGENT_EVENT\x10\x04*\x8c\x01\n" +
 	"\x14WebsocketCommandType\x12&\n" +
 	"\"WEBSOCKET_COMMAND_TYPE_UNSPECIFIED\x10\x00\x12$\n" +
 	" WEBSOCKET_COMMAND_TYPE_SUBSCRIBE\x10\x01\x12&\n" +
@@ -1231,12 +1467,18 @@ const file_definition_websocket_proto_rawDesc = "" +
 	"$WORKFLOW_INSTANCE_EVENT_TYPE_UPDATED\x10\x02\x12*\n" +
 	"&WORKFLOW_INSTANCE_EVENT_TYPE_COMPLETED\x10\x03\x12'\n" +
 	"#WORKFLOW_INSTANCE_EVENT_TYPE_FAILED\x10\x04\x12(\n" +
-	"$WORKFLOW_INSTANCE_EVENT_TYPE_CREATED\x10\x05*\xb4\x01\n" +
+	"$WORKFLOW_INSTANCE_EVENT_TYPE_CREATED\x10\x05*\xdb\x01\n" +
 	"\x15TaskInstanceEventType\x12(\n" +
 	"$TASK_INSTANCE_EVENT_TYPE_UNSPECIFIED\x10\x00\x12$\n" +
 	" TASK_INSTANCE_EVENT_TYPE_STARTED\x10\x01\x12&\n" +
 	"\"TASK_INSTANCE_EVENT_TYPE_COMPLETED\x10\x02\x12#\n" +
-	"\x1fTASK_INSTANCE_EVENT_TYPE_FAILED\x10\x03B\tZ\a./protob\x06proto3"
+	"\x1fTASK_INSTANCE_EVENT_TYPE_FAILED\x10\x03\x12%\n" +
+	"!TASK_INSTANCE_EVENT_TYPE_PROGRESS\x10\x04*\x9b\x01\n" +
+	"\x0eAgentEventType\x12 \n" +
+	"\x1cAGENT_EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x1f\n" +
+	"\x1bAGENT_EVENT_TYPE_REGISTERED\x10\x01\x12!\n" +
+	"\x1dAGENT_EVENT_TYPE_UNREGISTERED\x10\x02\x12#\n" +
+	"\x1fAGENT_EVENT_TYPE_STATUS_CHANGED\x10\x03B\tZ\a./protob\x06proto3"
 
 var (
 	file_definition_websocket_proto_rawDescOnce sync.Once
@@ -1250,55 +1492,63 @@ func file_definition_websocket_proto_rawDescGZIP() []byte {
 	return file_definition_websocket_proto_rawDescData
 }
 
-var file_definition_websocket_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
-var file_definition_websocket_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_definition_websocket_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
+var file_definition_websocket_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_definition_websocket_proto_goTypes = []any{
 	(WebsocketScopeType)(0),                  // 0: websocket.WebsocketScopeType
 	(WebsocketMessageType)(0),                // 1: websocket.WebsocketMessageType
 	(WebsocketCommandType)(0),                // 2: websocket.WebsocketCommandType
 	(WorkflowInstanceEventType)(0),           // 3: websocket.WorkflowInstanceEventType
 	(TaskInstanceEventType)(0),               // 4: websocket.TaskInstanceEventType
-	(*WebsocketScope)(nil),                   // 5: websocket.WebsocketScope
-	(*WebsocketMessage)(nil),                 // 6: websocket.WebsocketMessage
-	(*WebsocketCommand)(nil),                 // 7: websocket.WebsocketCommand
-	(*WebsocketSubscribeCommand)(nil),        // 8: websocket.WebsocketSubscribeCommand
-	(*WorkflowInstanceEvent)(nil),            // 9: websocket.WorkflowInstanceEvent
-	(*WorkflowInstanceStartedDetails)(nil),   // 10: websocket.WorkflowInstanceStartedDetails
-	(*WorkflowInstanceUpdatedDetails)(nil),   // 11: websocket.WorkflowInstanceUpdatedDetails
-	(*WorkflowInstanceCompletedDetails)(nil), // 12: websocket.WorkflowInstanceCompletedDetails
-	(*WorkflowInstanceFailedDetails)(nil),    // 13: websocket.WorkflowInstanceFailedDetails
-	(*WorkflowInstanceCreatedDetails)(nil),   // 14: websocket.WorkflowInstanceCreatedDetails
-	(*TaskInstanceEvent)(nil),                // 15: websocket.TaskInstanceEvent
-	(*TaskInstanceStartedDetails)(nil),       // 16: websocket.TaskInstanceStartedDetails
-	(*TaskInstanceCompletedDetails)(nil),     // 17: websocket.TaskInstanceCompletedDetails
-	(*TaskInstanceFailedDetails)(nil),        // 18: websocket.TaskInstanceFailedDetails
-	(*ClientRegisteredEvent)(nil),            // 19: websocket.ClientRegisteredEvent
+	(AgentEventType)(0),                      // 5: websocket.AgentEventType
+	(*WebsocketScope)(nil),                   // 6: websocket.WebsocketScope
+	(*WebsocketMessage)(nil),                 // 7: websocket.WebsocketMessage
+	(*WebsocketCommand)(nil),                 // 8: websocket.WebsocketCommand
+	(*WebsocketSubscribeCommand)(nil),        // 9: websocket.WebsocketSubscribeCommand
+	(*WorkflowInstanceEvent)(nil),            // 10: websocket.WorkflowInstanceEvent
+	(*WorkflowInstanceStartedDetails)(nil),   // 11: websocket.WorkflowInstanceStartedDetails
+	(*WorkflowInstanceUpdatedDetails)(nil),   // 12: websocket.WorkflowInstanceUpdatedDetails
+	(*WorkflowInstanceCompletedDetails)(nil), // 13: websocket.WorkflowInstanceCompletedDetails
+	(*WorkflowInstanceFailedDetails)(nil),    // 14: websocket.WorkflowInstanceFailedDetails
+	(*WorkflowInstanceCreatedDetails)(nil),   // 15: websocket.WorkflowInstanceCreatedDetails
+	(*TaskInstanceEvent)(nil),                // 16: websocket.TaskInstanceEvent
+	(*TaskInstanceStartedDetails)(nil),       // 17: websocket.TaskInstanceStartedDetails
+	(*TaskInstanceCompletedDetails)(nil),     // 18: websocket.TaskInstanceCompletedDetails
+	(*TaskInstanceFailedDetails)(nil),        // 19: websocket.TaskInstanceFailedDetails
+	(*TaskInstanceProgressDetails)(nil),      // 20: websocket.TaskInstanceProgressDetails
+	(*AgentEvent)(nil),                       // 21: websocket.AgentEvent
+	(*ClientRegisteredEvent)(nil),            // 22: websocket.ClientRegisteredEvent
+	(*structpb.Struct)(nil),                  // 23: google.protobuf.Struct
 }
 var file_definition_websocket_proto_depIdxs = []int32{
 	0,  // 0: websocket.WebsocketScope.type:type_name -> websocket.WebsocketScopeType
 	1,  // 1: websocket.WebsocketMessage.type:type_name -> websocket.WebsocketMessageType
-	5,  // 2: websocket.WebsocketMessage.scope:type_name -> websocket.WebsocketScope
-	9,  // 3: websocket.WebsocketMessage.workflow_instance_event:type_name -> websocket.WorkflowInstanceEvent
-	15, // 4: websocket.WebsocketMessage.task_instance_event:type_name -> websocket.TaskInstanceEvent
-	19, // 5: websocket.WebsocketMessage.client_registered_event:type_name -> websocket.ClientRegisteredEvent
-	2,  // 6: websocket.WebsocketCommand.type:type_name -> websocket.WebsocketCommandType
-	8,  // 7: websocket.WebsocketCommand.subscribe_command:type_name -> websocket.WebsocketSubscribeCommand
-	5,  // 8: websocket.WebsocketSubscribeCommand.scopes:type_name -> websocket.WebsocketScope
-	3,  // 9: websocket.WorkflowInstanceEvent.event_type:type_name -> websocket.WorkflowInstanceEventType
-	10, // 10: websocket.WorkflowInstanceEvent.started_details:type_name -> websocket.WorkflowInstanceStartedDetails
-	11, // 11: websocket.WorkflowInstanceEvent.updated_details:type_name -> websocket.WorkflowInstanceUpdatedDetails
-	12, // 12: websocket.WorkflowInstanceEvent.completed_details:type_name -> websocket.WorkflowInstanceCompletedDetails
-	13, // 13: websocket.WorkflowInstanceEvent.failed_details:type_name -> websocket.WorkflowInstanceFailedDetails
-	14, // 14: websocket.WorkflowInstanceEvent.created_details:type_name -> websocket.WorkflowInstanceCreatedDetails
-	4,  // 15: websocket.TaskInstanceEvent.event_type:type_name -> websocket.TaskInstanceEventType
-	16, // 16: websocket.TaskInstanceEvent.started_details:type_name -> websocket.TaskInstanceStartedDetails
-	17, // 17: websocket.TaskInstanceEvent.completed_details:type_name -> websocket.TaskInstanceCompletedDetails
-	18, // 18: websocket.TaskInstanceEvent.failed_details:type_name -> websocket.TaskInstanceFailedDetails
-	19, // [19:19] is the sub-list for method output_type
-	19, // [19:19] is the sub-list for method input_type
-	19, // [19:19] is the sub-list for extension type_name
-	19, // [19:19] is the sub-list for extension extendee
-	0,  // [0:19] is the sub-list for field type_name
+	6,  // 2: websocket.WebsocketMessage.scope:type_name -> websocket.WebsocketScope
+	10, // 3: websocket.WebsocketMessage.workflow_instance_event:type_name -> websocket.WorkflowInstanceEvent
+	16, // 4: websocket.WebsocketMessage.task_instance_event:type_name -> websocket.TaskInstanceEvent
+	22, // 5: websocket.WebsocketMessage.client_registered_event:type_name -> websocket.ClientRegisteredEvent
+	21, // 6: websocket.WebsocketMessage.agent_event:type_name -> websocket.AgentEvent
+	2,  // 7: websocket.WebsocketCommand.type:type_name -> websocket.WebsocketCommandType
+	9,  // 8: websocket.WebsocketCommand.subscribe_command:type_name -> websocket.WebsocketSubscribeCommand
+	6,  // 9: websocket.WebsocketSubscribeCommand.scopes:type_name -> websocket.WebsocketScope
+	3,  // 10: websocket.WorkflowInstanceEvent.event_type:type_name -> websocket.WorkflowInstanceEventType
+	11, // 11: websocket.WorkflowInstanceEvent.started_details:type_name -> websocket.WorkflowInstanceStartedDetails
+	12, // 12: websocket.WorkflowInstanceEvent.updated_details:type_name -> websocket.WorkflowInstanceUpdatedDetails
+	13, // 13: websocket.WorkflowInstanceEvent.completed_details:type_name -> websocket.WorkflowInstanceCompletedDetails
+	14, // 14: websocket.WorkflowInstanceEvent.failed_details:type_name -> websocket.WorkflowInstanceFailedDetails
+	15, // 15: websocket.WorkflowInstanceEvent.created_details:type_name -> websocket.WorkflowInstanceCreatedDetails
+	4,  // 16: websocket.TaskInstanceEvent.event_type:type_name -> websocket.TaskInstanceEventType
+	17, // 17: websocket.TaskInstanceEvent.started_details:type_name -> websocket.TaskInstanceStartedDetails
+	18, // 18: websocket.TaskInstanceEvent.completed_details:type_name -> websocket.TaskInstanceCompletedDetails
+	19, // 19: websocket.TaskInstanceEvent.failed_details:type_name -> websocket.TaskInstanceFailedDetails
+	20, // 20: websocket.TaskInstanceEvent.progress_details:type_name -> websocket.TaskInstanceProgressDetails
+	23, // 21: websocket.TaskInstanceProgressDetails.partial_output:type_name -> google.protobuf.Struct
+	5,  // 22: websocket.AgentEvent.event_type:type_name -> websocket.AgentEventType
+	23, // [23:23] is the sub-list for method output_type
+	23, // [23:23] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
 }
 
 func init() { file_definition_websocket_proto_init() }
@@ -1311,6 +1561,7 @@ func file_definition_websocket_proto_init() {
 		(*WebsocketMessage_WorkflowInstanceEvent)(nil),
 		(*WebsocketMessage_TaskInstanceEvent)(nil),
 		(*WebsocketMessage_ClientRegisteredEvent)(nil),
+		(*WebsocketMessage_AgentEvent)(nil),
 	}
 	file_definition_websocket_proto_msgTypes[2].OneofWrappers = []any{
 		(*WebsocketCommand_SubscribeCommand)(nil),
@@ -1326,14 +1577,16 @@ func file_definition_websocket_proto_init() {
 		(*TaskInstanceEvent_StartedDetails)(nil),
 		(*TaskInstanceEvent_CompletedDetails)(nil),
 		(*TaskInstanceEvent_FailedDetails)(nil),
+		(*TaskInstanceEvent_ProgressDetails)(nil),
 	}
+	file_definition_websocket_proto_msgTypes[14].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_definition_websocket_proto_rawDesc), len(file_definition_websocket_proto_rawDesc)),
-			NumEnums:      5,
-			NumMessages:   15,
+			NumEnums:      6,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
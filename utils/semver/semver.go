@@ -1,6 +1,7 @@
 package semver
 
 import (
+	"cmp"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -82,3 +83,85 @@ func (v *Version) ReleaseDraft() {
 func InitialVersion() string {
 	return "0.1.0"
 }
+
+// Ensure returns the canonical form of a version string, or the initial
+// version if it is empty or invalid.
+func Ensure(s string) string {
+	v, err := Parse(s)
+	if err != nil {
+		return InitialVersion()
+	}
+	return v.String()
+}
+
+// BumpPatch returns the version string with its patch version incremented.
+func BumpPatch(s string) (string, error) {
+	return bump(s, (*Version).IncrementPatch)
+}
+
+// BumpMinor returns the version string with its minor version incremented.
+func BumpMinor(s string) (string, error) {
+	return bump(s, (*Version).IncrementMinor)
+}
+
+// BumpMajor returns the version string with its major version incremented.
+func BumpMajor(s string) (string, error) {
+	return bump(s, (*Version).IncrementMajor)
+}
+
+func bump(s string, increment func(*Version)) (string, error) {
+	v, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	increment(&v)
+	return v.String(), nil
+}
+
+// Compare returns -1, 0 or 1 depending on whether a precedes, equals or
+// follows b. A pre-release precedes its release, and build metadata is
+// ignored, as specified by semver.
+func Compare(a, b Version) int {
+	if c := cmp.Compare(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case a.PreRelease == b.PreRelease:
+		return 0
+	case a.PreRelease == "":
+		return 1
+	case b.PreRelease == "":
+		return -1
+	}
+
+	ai := strings.Split(a.PreRelease, ".")
+	bi := strings.Split(b.PreRelease, ".")
+	for i := 0; i < len(ai) && i < len(bi); i++ {
+		if c := compareIdentifiers(ai[i], bi[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(ai), len(bi))
+}
+
+// compareIdentifiers compares pre-release identifiers: numeric ones
+// numerically and before alphanumeric ones, which compare lexically.
+func compareIdentifiers(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return cmp.Compare(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
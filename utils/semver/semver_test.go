@@ -35,3 +35,20 @@ func TestBumps(t *testing.T) {
 		t.Fatalf("expected 2.0.0, got %s", s)
 	}
 }
+
+func TestCompare(t *testing.T) {
+	ordered := []string{"0.1.0-1", "0.1.0-2", "0.1.0-10", "0.1.0-alpha", "0.1.0", "0.1.1", "0.2.0", "1.0.0-1", "1.0.0+build", "10.0.0"}
+	for i := 1; i < len(ordered); i++ {
+		a, _ := Parse(ordered[i-1])
+		b, _ := Parse(ordered[i])
+		if Compare(a, b) != -1 || Compare(b, a) != 1 {
+			t.Fatalf("expected %s to precede %s", ordered[i-1], ordered[i])
+		}
+	}
+
+	a, _ := Parse("1.2.3+one")
+	b, _ := Parse("1.2.3+two")
+	if Compare(a, b) != 0 {
+		t.Fatalf("expected build metadata to be ignored")
+	}
+}